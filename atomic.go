@@ -0,0 +1,79 @@
+package ghdownloader
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+// tempFilePattern matches the temporary files the downloader writes next to
+// their final path: ".partial" downloads, ".json.tmp" state and lockfiles,
+// ".tmp-<pid>" links and ".import-*" bundle files.
+var tempFilePattern = regexp.MustCompile(`(\.partial|\.json\.tmp|\.tmp-\d+)$|^\.import-\d+$`)
+
+// writeFileAtomic writes dst with mode perm through "<dst>.partial", which
+// is synced to disk and renamed to dst only once write succeeds, so dst
+// never holds a truncated file.
+func writeFileAtomic(dst string, perm os.FileMode, write func(io.Writer) error) error {
+	return writeFileVerified(dst, perm, write, nil)
+}
+
+// writeFileVerified is like writeFileAtomic, but also only renames
+// "<dst>.partial" to dst once verify, if set, accepts it.
+func writeFileVerified(dst string, perm os.FileMode, write func(io.Writer) error, verify func(path string) error) error {
+	partialPath := dst + partialSuffix
+	out, err := os.OpenFile(partialPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	err = write(out)
+	if err == nil {
+		err = out.Sync()
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	// OpenFile doesn't change the mode of an existing file, and is subject
+	// to the umask.
+	if err == nil {
+		err = os.Chmod(partialPath, perm)
+	}
+	if err == nil && verify != nil {
+		err = verify(partialPath)
+	}
+	if err == nil {
+		err = os.Rename(partialPath, dst)
+	}
+	if err != nil {
+		os.Remove(partialPath)
+	}
+	return err
+}
+
+// Clean removes the partial downloads and other temporary files left in the
+// destination directory by interrupted or failed runs, returning their
+// paths. Partial downloads would otherwise be resumed by the next run. It
+// must not run while downloads into the same directory are in progress.
+func (d *Downloader) Clean() ([]string, error) {
+	var removed []string
+	err := filepath.WalkDir(d.destDir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && path == d.destDir {
+				return filepath.SkipAll
+			}
+			return err
+		}
+		if entry.IsDir() || !tempFilePattern.MatchString(entry.Name()) {
+			return nil
+		}
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+		d.infof("Removed '%s'", path)
+		removed = append(removed, path)
+		return nil
+	})
+	return removed, err
+}
@@ -0,0 +1,370 @@
+package ghdownloader
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	bundleManifestName  = "manifest.json"
+	bundleSignatureName = "manifest.json.sig"
+	bundleFilesPrefix   = "files/"
+)
+
+// BundleManifest lists the files packaged in an export bundle.
+type BundleManifest struct {
+	Created  time.Time       `json:"created"`
+	Files    []BundleFile    `json:"files"`
+	Releases []BundleRelease `json:"releases,omitempty"`
+}
+
+// BundleFile describes one file in an export bundle. Path is relative to the
+// download directory and always uses forward slashes.
+type BundleFile struct {
+	Path   string      `json:"path"`
+	Size   int64       `json:"size"`
+	Mode   fs.FileMode `json:"mode"`
+	SHA256 string      `json:"sha256"`
+}
+
+// BundleRelease records a release installed by Sync whose version directory
+// is in an export bundle, so the importing side knows what it holds. Dir is
+// relative to the download directory and always uses forward slashes.
+type BundleRelease struct {
+	Repo string `json:"repo"`
+	Tag  string `json:"tag"`
+	Dir  string `json:"dir"`
+}
+
+// ExportBundle writes a gzip-compressed tar bundle of the given files or
+// directories under destDir (everything, if paths is empty) to w, along with
+// a manifest of their sizes and SHA-256 checksums and of the releases Sync
+// installed into them. If key is non-nil the manifest is signed with it so
+// ImportBundle can verify the bundle.
+func (d *Downloader) ExportBundle(w io.Writer, paths []string, key ed25519.PrivateKey) (*BundleManifest, error) {
+	srcDir := d.destDir
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+
+	// First pass: checksum everything so the manifest can lead the bundle.
+	manifest := &BundleManifest{Created: time.Now().UTC()}
+	for _, p := range paths {
+		root := filepath.Join(srcDir, p)
+		err := filepath.WalkDir(root, func(filePath string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			// Only regular files are bundled; links such as "<repo>-current"
+			// are recreated by the next download on the other side.
+			if !entry.Type().IsRegular() {
+				return nil
+			}
+			rel, err := filepath.Rel(srcDir, filePath)
+			if err != nil {
+				return err
+			}
+			// The state travels as manifest releases instead.
+			if rel == stateFileName {
+				return nil
+			}
+			info, err := entry.Info()
+			if err != nil {
+				return err
+			}
+			sum, err := fileSHA256(filePath)
+			if err != nil {
+				return err
+			}
+			manifest.Files = append(manifest.Files, BundleFile{
+				Path:   filepath.ToSlash(rel),
+				Size:   info.Size(),
+				Mode:   info.Mode().Perm(),
+				SHA256: sum,
+			})
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to read '%s': %v", root, err)
+		}
+	}
+	releases, err := d.exportedReleases(paths)
+	if err != nil {
+		return nil, err
+	}
+	manifest.Releases = releases
+
+	manifestData, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	zw := gzip.NewWriter(w)
+	tw := tar.NewWriter(zw)
+	if err := writeTarFile(tw, bundleManifestName, manifestData, 0644); err != nil {
+		return nil, err
+	}
+	if key != nil {
+		sig := base64.StdEncoding.EncodeToString(ed25519.Sign(key, manifestData))
+		if err := writeTarFile(tw, bundleSignatureName, []byte(sig+"\n"), 0644); err != nil {
+			return nil, err
+		}
+	}
+
+	// Second pass: the file contents themselves.
+	for _, file := range manifest.Files {
+		if err := copyFileToTar(tw, filepath.Join(srcDir, filepath.FromSlash(file.Path)), file); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// ImportBundle validates a bundle written by ExportBundle and unpacks its
+// files into destDir, returning their paths. If key is non-nil the manifest
+// must carry a valid signature from the matching private key. Every file is
+// checked against the manifest checksums before it is moved into place, and
+// the releases it lists are recorded in the state file as installed by Sync
+// once all files are.
+func (d *Downloader) ImportBundle(r io.Reader, key ed25519.PublicKey) ([]string, error) {
+	destDir := d.destDir
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("not a bundle: %v", err)
+	}
+	defer zr.Close()
+	tr := tar.NewReader(zr)
+
+	// The manifest (and signature) lead the bundle and must be validated
+	// before any file is written.
+	var manifestData, sigData []byte
+	var hdr *tar.Header
+	for {
+		hdr, err = tr.Next()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, fmt.Errorf("failed to read bundle: %v", err)
+		}
+		if hdr.Name == bundleManifestName {
+			manifestData, err = io.ReadAll(tr)
+		} else if hdr.Name == bundleSignatureName {
+			sigData, err = io.ReadAll(tr)
+		} else {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read bundle: %v", err)
+		}
+	}
+	if manifestData == nil {
+		return nil, fmt.Errorf("bundle has no manifest")
+	}
+
+	if key != nil {
+		if sigData == nil {
+			return nil, fmt.Errorf("bundle is not signed")
+		}
+		sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(sigData)))
+		if err != nil || !ed25519.Verify(key, manifestData, sig) {
+			return nil, fmt.Errorf("bundle signature is invalid")
+		}
+	}
+
+	var manifest BundleManifest
+	if err := json.Unmarshal(manifestData, &manifest); err != nil {
+		return nil, fmt.Errorf("invalid bundle manifest: %v", err)
+	}
+	releases := make(map[string]managedState, len(manifest.Releases))
+	for _, release := range manifest.Releases {
+		if _, _, err := parseUserRepo(release.Repo); err != nil || release.Tag == "" {
+			return nil, fmt.Errorf("invalid release '%s@%s' in bundle manifest", release.Repo, release.Tag)
+		}
+		dir, err := safeJoin(destDir, release.Dir)
+		if err != nil {
+			return nil, err
+		}
+		releases[strings.ToLower(release.Repo)] = managedState{Tag: release.Tag, Dir: dir}
+	}
+	expected := make(map[string]BundleFile, len(manifest.Files))
+	for _, file := range manifest.Files {
+		expected[file.Path] = file
+	}
+
+	var paths []string
+	for ; err != io.EOF; hdr, err = tr.Next() {
+		if err != nil {
+			return paths, fmt.Errorf("failed to read bundle: %v", err)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		name := strings.TrimPrefix(hdr.Name, bundleFilesPrefix)
+		file, ok := expected[name]
+		if !ok || !strings.HasPrefix(hdr.Name, bundleFilesPrefix) {
+			return paths, fmt.Errorf("bundle contains unexpected file '%s'", hdr.Name)
+		}
+		delete(expected, name)
+
+		target, err := safeJoin(destDir, name)
+		if err != nil {
+			return paths, err
+		}
+		if err := extractBundleFile(tr, target, file); err != nil {
+			return paths, err
+		}
+		paths = append(paths, target)
+	}
+
+	if len(expected) > 0 {
+		return paths, fmt.Errorf("bundle is missing %d file(s) listed in its manifest", len(expected))
+	}
+	if len(releases) == 0 {
+		return paths, nil
+	}
+	err = d.updateState(func(s *syncState) {
+		if s.Managed == nil {
+			s.Managed = make(map[string]managedState)
+		}
+		for key, release := range releases {
+			s.Managed[key] = release
+		}
+	})
+	return paths, err
+}
+
+// exportedReleases returns the releases installed by Sync whose version
+// directory is one of, or inside one of, paths.
+func (d *Downloader) exportedReleases(paths []string) ([]BundleRelease, error) {
+	state, err := d.loadState()
+	if err != nil {
+		return nil, err
+	}
+	var releases []BundleRelease
+	for key, managed := range state.Managed {
+		rel, err := filepath.Rel(d.destDir, managed.Dir)
+		if err != nil || !filepath.IsLocal(rel) {
+			continue
+		}
+		rel = filepath.ToSlash(rel)
+		for _, p := range paths {
+			p = path.Clean(filepath.ToSlash(p))
+			if p == "." || rel == p || strings.HasPrefix(rel, p+"/") {
+				releases = append(releases, BundleRelease{Repo: key, Tag: managed.Tag, Dir: rel})
+				break
+			}
+		}
+	}
+	sort.Slice(releases, func(i, j int) bool { return releases[i].Repo < releases[j].Repo })
+	return releases, nil
+}
+
+// writeTarFile adds a file with the given contents to tw.
+func writeTarFile(tw *tar.Writer, name string, data []byte, mode int64) error {
+	hdr := &tar.Header{Name: name, Mode: mode, Size: int64(len(data)), ModTime: time.Now()}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err := tw.Write(data)
+	return err
+}
+
+// copyFileToTar adds the file at filePath to tw under the bundle files prefix.
+func copyFileToTar(tw *tar.Writer, filePath string, file BundleFile) error {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	hdr := &tar.Header{
+		Name:    bundleFilesPrefix + file.Path,
+		Mode:    int64(file.Mode),
+		Size:    file.Size,
+		ModTime: time.Now(),
+	}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	if _, err := io.Copy(tw, f); err != nil {
+		return fmt.Errorf("failed to add '%s' to bundle: %v", filePath, err)
+	}
+	return nil
+}
+
+// extractBundleFile writes r to a temporary file next to target, checks it
+// against file's size and checksum, and renames it into place.
+func extractBundleFile(r io.Reader, target string, file BundleFile) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(target), ".import-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	h := sha256.New()
+	n, err := io.Copy(io.MultiWriter(tmp, h), r)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write '%s': %v", target, err)
+	}
+	if n != file.Size || hex.EncodeToString(h.Sum(nil)) != file.SHA256 {
+		return fmt.Errorf("checksum mismatch for '%s'", file.Path)
+	}
+	if err := os.Chmod(tmp.Name(), file.Mode.Perm()); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), target)
+}
+
+// safeJoin joins a slash-separated relative name onto dir, rejecting names
+// that would escape it.
+func safeJoin(dir, name string) (string, error) {
+	clean := path.Clean("/" + name)[1:]
+	if clean == "" || clean != name {
+		return "", fmt.Errorf("illegal file path '%s'", name)
+	}
+	return filepath.Join(dir, filepath.FromSlash(clean)), nil
+}
+
+// fileSHA256 returns the hex-encoded SHA-256 of the file at filePath.
+func fileSHA256(filePath string) (string, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,312 @@
+package ghdownloader
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// bundleEntry is a file of a hand-built bundle.
+type bundleEntry struct {
+	name string
+	data string
+}
+
+// craftBundle builds a bundle with the given manifest files, signed with key
+// if it is non-nil, followed by entries.
+func craftBundle(t *testing.T, files []BundleFile, key ed25519.PrivateKey, entries []bundleEntry) []byte {
+	t.Helper()
+	manifestData, err := json.Marshal(&BundleManifest{Files: files})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(zw)
+	if err := writeTarFile(tw, bundleManifestName, manifestData, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if key != nil {
+		sig := base64.StdEncoding.EncodeToString(ed25519.Sign(key, manifestData))
+		if err := writeTarFile(tw, bundleSignatureName, []byte(sig), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, e := range entries {
+		if err := writeTarFile(tw, e.name, []byte(e.data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// bundleFile describes data as the bundle file at p.
+func bundleFile(p, data string) BundleFile {
+	sum := sha256.Sum256([]byte(data))
+	return BundleFile{Path: p, Size: int64(len(data)), Mode: 0644, SHA256: hex.EncodeToString(sum[:])}
+}
+
+func TestBundleRoundTrip(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := newTestDownloader(t)
+	src := d.destDir
+	for name, data := range map[string]string{"tool-v1.0.0/tool": "bin", "tool-v1.0.0/README.md": "docs", "other-v2/other": "x"} {
+		filePath := filepath.Join(src, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filePath, []byte(data), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink("tool-v1.0.0", filepath.Join(src, "tool-current")); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	manifest, err := d.ExportBundle(&buf, []string{"tool-v1.0.0", "tool-current"}, priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(manifest.Files) != 2 {
+		t.Errorf("bundled %d files, want 2: %+v", len(manifest.Files), manifest.Files)
+	}
+
+	other := newTestDownloader(t)
+	dest := other.destDir
+	paths, err := other.ImportBundle(bytes.NewReader(buf.Bytes()), pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) != 2 {
+		t.Errorf("imported %v, want 2 files", paths)
+	}
+	data, err := os.ReadFile(filepath.Join(dest, "tool-v1.0.0", "tool"))
+	if err != nil || string(data) != "bin" {
+		t.Errorf("imported tool = %q, %v", data, err)
+	}
+	if info, err := os.Stat(filepath.Join(dest, "tool-v1.0.0", "tool")); err != nil || info.Mode().Perm() != 0755 {
+		t.Errorf("imported tool lost its mode: %v, %v", info, err)
+	}
+}
+
+func TestImportBundleRejects(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, otherPriv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	good := bundleFile("tool-v1.0.0/tool", "bin")
+	tests := []struct {
+		name    string
+		bundle  []byte
+		key     ed25519.PublicKey
+		wantErr string
+	}{
+		{
+			name:    "unsigned",
+			bundle:  craftBundle(t, []BundleFile{good}, nil, []bundleEntry{{"files/tool-v1.0.0/tool", "bin"}}),
+			key:     pub,
+			wantErr: "not signed",
+		},
+		{
+			name:    "signed with another key",
+			bundle:  craftBundle(t, []BundleFile{good}, otherPriv, []bundleEntry{{"files/tool-v1.0.0/tool", "bin"}}),
+			key:     pub,
+			wantErr: "signature is invalid",
+		},
+		{
+			name:    "tampered file",
+			bundle:  craftBundle(t, []BundleFile{good}, priv, []bundleEntry{{"files/tool-v1.0.0/tool", "evil"}}),
+			key:     pub,
+			wantErr: "checksum mismatch",
+		},
+		{
+			name:    "file missing from the manifest",
+			bundle:  craftBundle(t, []BundleFile{good}, priv, []bundleEntry{{"files/tool-v1.0.0/tool", "bin"}, {"files/extra", "x"}}),
+			key:     pub,
+			wantErr: "unexpected file",
+		},
+		{
+			name:    "file outside the files directory",
+			bundle:  craftBundle(t, []BundleFile{bundleFile("evil", "x")}, priv, []bundleEntry{{"evil", "x"}}),
+			key:     pub,
+			wantErr: "unexpected file",
+		},
+		{
+			name:    "missing file",
+			bundle:  craftBundle(t, []BundleFile{good, bundleFile("other", "x")}, priv, []bundleEntry{{"files/tool-v1.0.0/tool", "bin"}}),
+			key:     pub,
+			wantErr: "missing 1 file",
+		},
+		{
+			name:    "parent directory",
+			bundle:  craftBundle(t, []BundleFile{bundleFile("../evil", "x")}, nil, []bundleEntry{{"files/../evil", "x"}}),
+			wantErr: "illegal file path",
+		},
+		{
+			name:    "absolute path",
+			bundle:  craftBundle(t, []BundleFile{bundleFile("/evil", "x")}, nil, []bundleEntry{{"files//evil", "x"}}),
+			wantErr: "illegal file path",
+		},
+		{
+			name:    "not gzip",
+			bundle:  craftBundle(t, nil, nil, nil)[:0],
+			wantErr: "not a bundle",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			dest := filepath.Join(root, "dest")
+			_, err := New("", dest).ImportBundle(bytes.NewReader(tt.bundle), tt.key)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("ImportBundle: %v, want an error containing %q", err, tt.wantErr)
+			}
+			if _, err := os.Lstat(filepath.Join(root, "evil")); err == nil {
+				t.Error("a file was written outside the destination directory")
+			}
+			if tt.wantErr == "checksum mismatch" {
+				if _, err := os.Stat(filepath.Join(dest, "tool-v1.0.0", "tool")); err == nil {
+					t.Error("a file failing its checksum was moved into place")
+				}
+			}
+		})
+	}
+}
+
+func TestBundleRecordsReleases(t *testing.T) {
+	d := newTestDownloader(t)
+	makeDirs(t, d.destDir, "tool-v1.0.0", "other-v2.0.0")
+	for _, dir := range []string{"tool-v1.0.0", "other-v2.0.0"} {
+		if err := os.WriteFile(filepath.Join(d.destDir, dir, "bin"), []byte(dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	err := d.updateState(func(s *syncState) {
+		s.Managed = map[string]managedState{
+			"owner/tool":  {Tag: "v1.0.0", Dir: filepath.Join(d.destDir, "tool-v1.0.0"), Installed: map[string]string{"/usr/local/bin/tool": "bin"}},
+			"owner/other": {Tag: "v2.0.0", Dir: filepath.Join(d.destDir, "other-v2.0.0")},
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	manifest, err := d.ExportBundle(&buf, []string{"tool-v1.0.0"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []BundleRelease{{Repo: "owner/tool", Tag: "v1.0.0", Dir: "tool-v1.0.0"}}
+	if len(manifest.Releases) != 1 || manifest.Releases[0] != want[0] {
+		t.Errorf("bundled releases %+v, want %+v", manifest.Releases, want)
+	}
+	if _, err := d.ExportBundle(io.Discard, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	other := newTestDownloader(t)
+	if _, err := other.ImportBundle(bytes.NewReader(buf.Bytes()), nil); err != nil {
+		t.Fatal(err)
+	}
+	state, err := other.loadState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	managed := state.Managed["owner/tool"]
+	if len(state.Managed) != 1 || managed.Tag != "v1.0.0" || managed.Dir != filepath.Join(other.destDir, "tool-v1.0.0") || managed.Installed != nil {
+		t.Errorf("recorded %+v, want owner/tool at v1.0.0 in the import directory", state.Managed)
+	}
+
+	// A whole-directory export carries the state as releases, not as a file.
+	buf.Reset()
+	manifest, err = d.ExportBundle(&buf, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(manifest.Releases) != 2 {
+		t.Errorf("bundled releases %+v, want both", manifest.Releases)
+	}
+	for _, file := range manifest.Files {
+		if file.Path == stateFileName {
+			t.Error("the state file was bundled")
+		}
+	}
+}
+
+func TestImportBundleRejectsInvalidReleases(t *testing.T) {
+	for _, release := range []BundleRelease{
+		{Repo: "tool", Tag: "v1", Dir: "tool-v1"},
+		{Repo: "owner/tool", Dir: "tool-v1"},
+		{Repo: "owner/tool", Tag: "v1", Dir: "../tool-v1"},
+	} {
+		manifestData, err := json.Marshal(&BundleManifest{Releases: []BundleRelease{release}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		tw := tar.NewWriter(zw)
+		if err := writeTarFile(tw, bundleManifestName, manifestData, 0644); err != nil {
+			t.Fatal(err)
+		}
+		tw.Close()
+		zw.Close()
+
+		d := newTestDownloader(t)
+		if _, err := d.ImportBundle(&buf, nil); err == nil {
+			t.Errorf("imported a bundle with release %+v", release)
+		}
+		if _, err := os.Stat(filepath.Join(d.destDir, stateFileName)); err == nil {
+			t.Errorf("release %+v was recorded", release)
+		}
+	}
+}
+
+func TestSafeJoin(t *testing.T) {
+	tests := []struct {
+		name string
+		ok   bool
+	}{
+		{"tool/bin", true},
+		{"bin", true},
+		{"", false},
+		{".", false},
+		{"..", false},
+		{"../bin", false},
+		{"tool/../../bin", false},
+		{"tool/../bin", false},
+		{"/bin", false},
+		{"tool//bin", false},
+		{"tool/", false},
+	}
+	for _, tt := range tests {
+		_, err := safeJoin("/dest", tt.name)
+		if (err == nil) != tt.ok {
+			t.Errorf("safeJoin(%q): %v, want ok %v", tt.name, err, tt.ok)
+		}
+	}
+}
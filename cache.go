@@ -0,0 +1,164 @@
+package ghdownloader
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// SetCacheDir caches GitHub API responses in dir, together with their ETag
+// and Last-Modified headers. Cached responses are revalidated with
+// conditional requests; GitHub answers those with "304 Not Modified" when
+// nothing changed, which doesn't count against the rate limit. Asset
+// downloads are never cached. An empty dir disables the cache.
+func (d *Downloader) SetCacheDir(dir string) {
+	d.cacheDir = dir
+}
+
+// DefaultCacheDir returns the "ghdownloader" directory in the user's cache
+// directory.
+func DefaultCacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "ghdownloader"), nil
+}
+
+// cacheEntry is a cached API response.
+type cacheEntry struct {
+	URL          string      `json:"url"`
+	ETag         string      `json:"etag,omitempty"`
+	LastModified string      `json:"last_modified,omitempty"`
+	Header       http.Header `json:"header"`
+	Body         []byte      `json:"body"`
+}
+
+// cacheTransport answers API requests from the cache directory when the
+// server reports the cached response is still current.
+type cacheTransport struct {
+	d    *Downloader
+	next http.RoundTripper
+}
+
+func (t *cacheTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !t.cacheable(req) {
+		return t.next.RoundTrip(req)
+	}
+
+	path := t.entryPath(req)
+	entry := readCacheEntry(path, req.URL.String())
+	if entry != nil {
+		// Don't modify the caller's request.
+		req = req.Clone(req.Context())
+		if entry.ETag != "" {
+			req.Header.Set("If-None-Match", entry.ETag)
+		}
+		if entry.LastModified != "" {
+			req.Header.Set("If-Modified-Since", entry.LastModified)
+		}
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified && entry != nil:
+		resp.Body.Close()
+		t.d.debugf("Using cached response for '%s'", redactURL(req.URL))
+		// Keep the fresh rate limit headers of the 304.
+		header := entry.Header.Clone()
+		for name, values := range resp.Header {
+			header[name] = values
+		}
+		resp.StatusCode, resp.Status = http.StatusOK, "200 OK"
+		resp.Header = header
+		resp.Body = io.NopCloser(bytes.NewReader(entry.Body))
+		resp.ContentLength = int64(len(entry.Body))
+		return resp, nil
+
+	case resp.StatusCode == http.StatusOK:
+		etag, modified := resp.Header.Get("ETag"), resp.Header.Get("Last-Modified")
+		if etag == "" && modified == "" {
+			return resp, nil
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+		entry := &cacheEntry{URL: req.URL.String(), ETag: etag, LastModified: modified, Header: resp.Header, Body: body}
+		if err := writeCacheEntry(path, entry); err != nil {
+			t.d.warnf("Failed to cache response for '%s': %v", redactURL(req.URL), err)
+		}
+	}
+	return resp, nil
+}
+
+// cacheable reports whether req is an API request whose response may be
+// cached.
+func (t *cacheTransport) cacheable(req *http.Request) bool {
+	if t.d.cacheDir == "" || req.Method != http.MethodGet || req.Header.Get("Range") != "" {
+		return false
+	}
+	if strings.Contains(req.Header.Get("Accept"), "application/octet-stream") {
+		return false
+	}
+	return strings.HasPrefix(req.URL.String(), t.d.client.BaseURL.String())
+}
+
+// entryPath returns the cache file of req. Responses may depend on who asks,
+// so the credentials are part of the key.
+func (t *cacheTransport) entryPath(req *http.Request) string {
+	sum := sha256.Sum256([]byte(strings.Join([]string{
+		req.URL.String(), req.Header.Get("Accept"), req.Header.Get("Authorization"),
+	}, "\n")))
+	return filepath.Join(t.d.cacheDir, hex.EncodeToString(sum[:])+".json")
+}
+
+// readCacheEntry returns the entry cached at path for rawURL, or nil if there
+// is none or it can't be read.
+func readCacheEntry(path, rawURL string) *cacheEntry {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil || entry.URL != rawURL {
+		return nil
+	}
+	return &entry
+}
+
+// writeCacheEntry atomically stores entry at path.
+func writeCacheEntry(path string, entry *cacheEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".cache-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
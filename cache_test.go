@@ -0,0 +1,81 @@
+package ghdownloader
+
+import (
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestCacheRevalidates(t *testing.T) {
+	g := newFakeGitHub(t)
+	g.addRelease("owner/tool", "v1.0.0", map[string]string{"tool": "bin"})
+	var conditional, notModified int
+	g.hook = func(w http.ResponseWriter, r *http.Request) bool {
+		if strings.Contains(r.URL.Path, "/releases/assets/") {
+			if r.Header.Get("If-None-Match") != "" {
+				t.Error("an asset download was revalidated")
+			}
+			return false
+		}
+		if r.Header.Get("If-None-Match") != "" {
+			conditional++
+		}
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return true
+		}
+		w.Header().Set("ETag", `"v1"`)
+		return false
+	}
+	cacheDir := t.TempDir()
+	download := func() []string {
+		t.Helper()
+		d := g.downloader(t)
+		d.SetCacheDir(cacheDir)
+		paths, err := d.DownloadLatestReleases([]string{"owner/tool"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return paths
+	}
+
+	download()
+	if conditional != 0 {
+		t.Errorf("sent %d conditional requests with an empty cache", conditional)
+	}
+	entries, err := os.ReadDir(cacheDir)
+	if err != nil || len(entries) == 0 {
+		t.Fatalf("cached %d responses, %v", len(entries), err)
+	}
+
+	// The second run gets its release from the cache.
+	paths := download()
+	if notModified == 0 {
+		t.Error("no cached response was revalidated")
+	}
+	if data, err := os.ReadFile(paths[0]); err != nil || string(data) != "bin" {
+		t.Errorf("downloaded %q, %v", data, err)
+	}
+	if after, _ := os.ReadDir(cacheDir); len(after) != len(entries) {
+		t.Errorf("cached %d responses, want %d", len(after), len(entries))
+	}
+}
+
+func TestCacheDisabled(t *testing.T) {
+	g := newFakeGitHub(t)
+	g.addRelease("owner/tool", "v1.0.0", map[string]string{"tool": "bin"})
+	g.hook = func(w http.ResponseWriter, r *http.Request) bool {
+		if r.Header.Get("If-None-Match") != "" {
+			t.Error("a conditional request was sent without a cache")
+		}
+		w.Header().Set("ETag", `"v1"`)
+		return false
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := g.downloader(t).DownloadLatestReleases([]string{"owner/tool"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,197 @@
+package ghdownloader
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/google/go-github/v68/github"
+)
+
+// bsdChecksumPattern matches BSD-style checksum lines such as
+// "SHA256 (tool.tar.gz) = <hex>".
+var bsdChecksumPattern = regexp.MustCompile(`^SHA(?:256|512) \((.+)\) = ([0-9a-fA-F]+)$`)
+
+// checksumSuffixes are the extensions of checksum files published for a
+// single asset, e.g. "tool.tar.gz.sha256".
+var checksumSuffixes = []string{".sha256", ".sha256sum", ".sha512", ".sha512sum"}
+
+// SetVerifyChecksums enables verifying each downloaded asset against the
+// checksums published in its release, either in a combined file such as
+// "checksums.txt" or "SHA256SUMS", or in a per-asset "<asset>.sha256" file.
+// Assets that don't match are deleted and reported as failed.
+func (d *Downloader) SetVerifyChecksums(verify bool) {
+	d.verifyChecksums = verify
+}
+
+// isChecksumAsset reports whether name looks like a checksum file. The
+// signatures and certificates of checksum files aren't checksum files.
+func isChecksumAsset(name string) bool {
+	if isSignatureAsset(name) {
+		return false
+	}
+	lower := strings.ToLower(name)
+	for _, suffix := range checksumSuffixes {
+		if strings.HasSuffix(lower, suffix) {
+			return true
+		}
+	}
+	return strings.Contains(lower, "checksums") ||
+		strings.Contains(lower, "sha256sums") || strings.Contains(lower, "sha512sums") ||
+		strings.Contains(lower, "shasums256")
+}
+
+// releaseChecksums lazily downloads and parses the checksum files of a
+// release, so releases without verification enabled cost nothing.
+type releaseChecksums struct {
+	ctx      context.Context
+	d        *Downloader
+	userRepo string
+	assets   []*github.ReleaseAsset
+
+	once sync.Once
+	sums map[string]string
+	err  error
+}
+
+// newReleaseChecksums returns the checksums published among the release
+// assets of userRepo.
+func (d *Downloader) newReleaseChecksums(ctx context.Context, userRepo string, assets []*github.ReleaseAsset) *releaseChecksums {
+	return &releaseChecksums{ctx: ctx, d: d, userRepo: userRepo, assets: assets}
+}
+
+// lookup returns the published hex digest for the asset named name, or "" if
+// the release publishes none.
+func (c *releaseChecksums) lookup(name string) (string, error) {
+	c.once.Do(c.load)
+	if c.err != nil {
+		return "", c.err
+	}
+	return c.sums[name], nil
+}
+
+// verify checks the file at filePath against the digest published for name.
+// A missing checksum is only a warning, since many releases publish none.
+func (c *releaseChecksums) verify(filePath, name string) error {
+	expected, err := c.lookup(name)
+	if err != nil {
+		return err
+	}
+	if expected == "" {
+		c.d.warnf("no checksum published for '%s'; not verified", name)
+		return nil
+	}
+	return verifyDigest(filePath, expected)
+}
+
+// load downloads and parses every checksum asset of the release.
+func (c *releaseChecksums) load() {
+	c.sums = make(map[string]string)
+	for _, asset := range c.assets {
+		if !isChecksumAsset(asset.GetName()) {
+			continue
+		}
+		data, err := c.d.fetchAssetBytes(c.ctx, c.userRepo, asset)
+		if err != nil {
+			c.err = fmt.Errorf("failed to download checksum file '%s': %v", asset.GetName(), err)
+			return
+		}
+
+		// A per-asset checksum file names its asset by its own name.
+		lowerName := strings.ToLower(asset.GetName())
+		for _, suffix := range checksumSuffixes {
+			if strings.HasSuffix(lowerName, suffix) {
+				if fields := strings.Fields(string(data)); len(fields) > 0 {
+					c.sums[asset.GetName()[:len(asset.GetName())-len(suffix)]] = fields[0]
+				}
+				break
+			}
+		}
+		for name, sum := range parseChecksums(data) {
+			c.sums[name] = sum
+		}
+	}
+}
+
+// parseChecksums parses sha256sum/goreleaser ("<hex>  <name>", "<hex> *<name>")
+// and BSD ("SHA256 (<name>) = <hex>") checksum lines.
+func parseChecksums(data []byte) map[string]string {
+	sums := make(map[string]string)
+	scanner := bufio.NewScanner(strings.NewReader(string(data)))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if m := bsdChecksumPattern.FindStringSubmatch(line); m != nil {
+			sums[m[1]] = strings.ToLower(m[2])
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 || digestHash(fields[0]) == nil {
+			continue
+		}
+		name := strings.TrimPrefix(strings.TrimPrefix(fields[1], "*"), "./")
+		sums[name] = strings.ToLower(fields[0])
+	}
+	return sums
+}
+
+// digestHash returns the hash matching the length of a hex digest, or nil
+// if it isn't a SHA-256 or SHA-512 digest.
+func digestHash(digest string) hash.Hash {
+	if _, err := hex.DecodeString(digest); err != nil {
+		return nil
+	}
+	switch len(digest) {
+	case sha256.Size * 2:
+		return sha256.New()
+	case sha512.Size * 2:
+		return sha512.New()
+	}
+	return nil
+}
+
+// verifyDigest checks the file at filePath against a hex SHA-256 or SHA-512
+// digest.
+func verifyDigest(filePath, expected string) error {
+	h := digestHash(expected)
+	if h == nil {
+		return fmt.Errorf("unsupported checksum '%s'", expected)
+	}
+	f, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if _, err := io.Copy(h, f); err != nil {
+		return fmt.Errorf("failed to read '%s': %v", filePath, err)
+	}
+	if actual := hex.EncodeToString(h.Sum(nil)); !strings.EqualFold(actual, expected) {
+		return fmt.Errorf("checksum mismatch for '%s': expected %s, got %s", filePath, strings.ToLower(expected), actual)
+	}
+	return nil
+}
+
+// fetchAssetBytes downloads a small asset, such as a checksum file, into
+// memory.
+func (d *Downloader) fetchAssetBytes(ctx context.Context, userRepo string, asset *github.ReleaseAsset) ([]byte, error) {
+	tmp, err := os.CreateTemp("", "ghdownloader-*")
+	if err != nil {
+		return nil, err
+	}
+	tmp.Close()
+	defer os.Remove(tmp.Name())
+	defer os.Remove(tmp.Name() + partialSuffix)
+
+	if err := d.fetchAsset(ctx, userRepo, asset, tmp.Name(), nil); err != nil {
+		return nil, err
+	}
+	return os.ReadFile(tmp.Name())
+}
@@ -0,0 +1,185 @@
+package ghdownloader
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/google/go-github/v68/github"
+)
+
+// checksumRelease returns a provider serving an asset named "tool" holding
+// data, with a checksums file listing digest for it, and the downloader
+// using it.
+func checksumRelease(t *testing.T, data []byte, digest string) (*Downloader, *github.ReleaseAsset, *releaseChecksums) {
+	t.Helper()
+	d := newTestDownloader(t)
+	d.SetVerifyChecksums(true)
+	p := &fakeProvider{}
+	d.SetProvider(p)
+	asset := p.newFakeAsset("tool", data)
+	sums := p.newFakeAsset("checksums.txt", []byte(digest+"  tool\n"))
+	return d, asset, d.newReleaseChecksums(context.Background(), "owner/tool", []*github.ReleaseAsset{asset, sums})
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func TestDownloadAssetChecksumMismatch(t *testing.T) {
+	d, asset, sums := checksumRelease(t, []byte("tampered"), sha256Hex([]byte("original")))
+	if _, _, err := d.downloadAsset(context.Background(), "owner/tool", asset, d.destDir, false, sums); err == nil {
+		t.Fatal("expected a checksum error")
+	}
+	// Neither the final path nor the partial download may be left behind.
+	if names := listDir(t, d.destDir); len(names) != 0 {
+		t.Errorf("left %q in the destination directory", names)
+	}
+}
+
+func TestDownloadAssetReverifiesExisting(t *testing.T) {
+	data := []byte("original")
+	d, asset, sums := checksumRelease(t, data, sha256Hex(data))
+	// A file of the right size that was never verified, as left by a run
+	// without verification.
+	path := filepath.Join(d.destDir, "tool")
+	if err := os.WriteFile(path, []byte("tampered"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	asset.Size = github.Int(len("tampered"))
+
+	got, skipped, err := d.downloadAsset(context.Background(), "owner/tool", asset, d.destDir, false, sums)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, _ := os.ReadFile(got)
+	if skipped || string(content) != "original" {
+		t.Errorf("skipped = %v, content = %q, want a fresh download", skipped, content)
+	}
+}
+
+func TestParseChecksums(t *testing.T) {
+	a := strings.Repeat("a", 64)
+	b := strings.Repeat("b", 64)
+	long := strings.Repeat("c", 128)
+	tests := []struct {
+		name string
+		data string
+		want map[string]string
+	}{
+		{
+			name: "sha256sum",
+			data: a + "  tool.tar.gz\n" + b + "  tool.zip\n",
+			want: map[string]string{"tool.tar.gz": a, "tool.zip": b},
+		},
+		{
+			name: "binary mode and leading dot",
+			data: a + " *tool.tar.gz\n" + b + "  ./tool.zip\n",
+			want: map[string]string{"tool.tar.gz": a, "tool.zip": b},
+		},
+		{
+			name: "BSD",
+			data: "SHA256 (tool.tar.gz) = " + strings.ToUpper(a) + "\nSHA512 (tool.zip) = " + long + "\n",
+			want: map[string]string{"tool.tar.gz": a, "tool.zip": long},
+		},
+		{
+			name: "CRLF and blank lines",
+			data: "\r\n" + a + "  tool.tar.gz\r\n\r\n",
+			want: map[string]string{"tool.tar.gz": a},
+		},
+		{
+			name: "not a digest",
+			data: "zz" + a[2:] + "  bad-hex\n" + a[:40] + "  sha1\nhello world\n" + a + "  two names\n",
+			want: map[string]string{},
+		},
+	}
+	for _, tt := range tests {
+		if got := parseChecksums([]byte(tt.data)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: parseChecksums = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestIsChecksumAsset(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"checksums.txt", true},
+		{"tool_1.0.0_checksums.txt", true},
+		{"SHA256SUMS", true},
+		{"sha512sums.txt", true},
+		{"tool.tar.gz.sha256", true},
+		{"tool.tar.gz.SHA256SUM", true},
+		{"tool.tar.gz.sha512", true},
+		{"tool.tar.gz", false},
+		{"tool.tar.gz.sig", false},
+		{"checksums.txt.sig", false},
+		{"checksums.txt.pem", false},
+		{"SHA256SUMS.asc", false},
+		{"checksums.txt.sigstore.json", false},
+	}
+	for _, tt := range tests {
+		if got := isChecksumAsset(tt.name); got != tt.want {
+			t.Errorf("isChecksumAsset(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestVerifyDigest(t *testing.T) {
+	data := []byte("tool")
+	filePath := filepath.Join(t.TempDir(), "tool")
+	if err := os.WriteFile(filePath, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	sum512 := sha512.Sum512(data)
+	tests := []struct {
+		name    string
+		digest  string
+		wantErr bool
+	}{
+		{name: "sha256", digest: sha256Hex(data)},
+		{name: "upper case", digest: strings.ToUpper(sha256Hex(data))},
+		{name: "sha512", digest: hex.EncodeToString(sum512[:])},
+		{name: "mismatch", digest: sha256Hex([]byte("other")), wantErr: true},
+		{name: "unsupported length", digest: sha256Hex(data)[:40], wantErr: true},
+		{name: "not hex", digest: strings.Repeat("z", 64), wantErr: true},
+	}
+	for _, tt := range tests {
+		if err := verifyDigest(filePath, tt.digest); (err != nil) != tt.wantErr {
+			t.Errorf("%s: verifyDigest: %v, want error %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestReleaseChecksumsPerAsset(t *testing.T) {
+	d := newTestDownloader(t)
+	p := &fakeProvider{}
+	d.SetProvider(p)
+	digest := sha256Hex([]byte("tool"))
+	assets := []*github.ReleaseAsset{
+		p.newFakeAsset("tool.tar.gz", []byte("tool")),
+		p.newFakeAsset("tool.tar.gz.sha256", []byte(digest+"\n")),
+		p.newFakeAsset("tool.zip.sha256sum", []byte(digest+"  tool.zip\n")),
+	}
+	sums := d.newReleaseChecksums(context.Background(), "owner/tool", assets)
+	for _, name := range []string{"tool.tar.gz", "tool.zip"} {
+		got, err := sums.lookup(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != digest {
+			t.Errorf("lookup(%q) = %q, want %q", name, got, digest)
+		}
+	}
+	if got, _ := sums.lookup("other"); got != "" {
+		t.Errorf("lookup of an asset without checksum = %q", got)
+	}
+}
@@ -1,38 +1,331 @@
 package main
 
 import (
+	"context"
+	"crypto/ed25519"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"log"
+	"log/slog"
+	"net/http"
 	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"regexp"
+	"strconv"
 	"strings"
+	"sync"
+	"syscall"
+	"time"
 
 	"github.com/dropsite-ai/ghdownloader"
 )
 
-// repoList implements flag.Value to allow multiple -repo flags.
-type repoList []string
+// stringList implements flag.Value to allow repeated flags such as -repo.
+type stringList []string
 
-func (r *repoList) String() string {
+func (r *stringList) String() string {
 	return strings.Join(*r, ",")
 }
 
-func (r *repoList) Set(value string) error {
+func (r *stringList) Set(value string) error {
 	*r = append(*r, value)
 	return nil
 }
 
+// byteSize implements flag.Value for sizes such as "512", "10K" or "1.5M".
+type byteSize int64
+
+func (b *byteSize) String() string {
+	return strconv.FormatInt(int64(*b), 10)
+}
+
+func (b *byteSize) Set(value string) error {
+	multiplier := 1.0
+	number := strings.TrimSuffix(strings.ToUpper(strings.TrimSpace(value)), "B")
+	switch {
+	case strings.HasSuffix(number, "K"):
+		multiplier = 1 << 10
+	case strings.HasSuffix(number, "M"):
+		multiplier = 1 << 20
+	case strings.HasSuffix(number, "G"):
+		multiplier = 1 << 30
+	}
+	number = strings.TrimRight(number, "KMG")
+	n, err := strconv.ParseFloat(number, 64)
+	if err != nil || n < 0 {
+		return fmt.Errorf("invalid size '%s'", value)
+	}
+	*b = byteSize(n * multiplier)
+	return nil
+}
+
+// progressBars renders one progress bar per downloading asset on stderr,
+// redrawing them in place at most every progressInterval.
+type progressBars struct {
+	mu    sync.Mutex
+	bars  []*progressBar
+	index map[string]*progressBar
+	drawn int
+	last  time.Time
+}
+
+type progressBar struct {
+	label             string
+	downloaded, total int64
+}
+
+const (
+	progressInterval = 100 * time.Millisecond
+	progressWidth    = 30
+)
+
+func newProgressBars() *progressBars {
+	return &progressBars{index: make(map[string]*progressBar)}
+}
+
+// Update implements ghdownloader.ProgressFunc.
+func (p *progressBars) Update(repo, asset string, downloaded, total int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	label := repo + " " + asset
+	bar, ok := p.index[label]
+	if !ok {
+		bar = &progressBar{label: label}
+		p.index[label] = bar
+		p.bars = append(p.bars, bar)
+	}
+	bar.downloaded, bar.total = downloaded, total
+
+	if ok && downloaded != total && time.Since(p.last) < progressInterval {
+		return
+	}
+	p.draw()
+}
+
+// draw moves the cursor back over the bars drawn last time and redraws them.
+func (p *progressBars) draw() {
+	var b strings.Builder
+	if p.drawn > 0 {
+		fmt.Fprintf(&b, "\x1b[%dA", p.drawn)
+	}
+	for _, bar := range p.bars {
+		b.WriteString("\r\x1b[2K")
+		b.WriteString(bar.String())
+		b.WriteString("\n")
+	}
+	fmt.Fprint(os.Stderr, b.String())
+	p.drawn = len(p.bars)
+	p.last = time.Now()
+}
+
+func (b *progressBar) String() string {
+	if b.total <= 0 {
+		return fmt.Sprintf("%s %s", b.label, formatBytes(b.downloaded))
+	}
+	filled := int(b.downloaded * progressWidth / b.total)
+	if filled > progressWidth {
+		filled = progressWidth
+	}
+	return fmt.Sprintf("%s [%s%s] %3d%% %s/%s", b.label,
+		strings.Repeat("=", filled), strings.Repeat(" ", progressWidth-filled),
+		b.downloaded*100/b.total, formatBytes(b.downloaded), formatBytes(b.total))
+}
+
+// formatBytes formats n as a human-readable size such as "12.3MiB".
+func formatBytes(n int64) string {
+	const unit = 1 << 10
+	if n < unit {
+		return fmt.Sprintf("%dB", n)
+	}
+	value, suffix := float64(n), ""
+	for _, s := range []string{"KiB", "MiB", "GiB", "TiB"} {
+		value, suffix = value/unit, s
+		if value < unit {
+			break
+		}
+	}
+	return fmt.Sprintf("%.1f%s", value, suffix)
+}
+
 func main() {
+	// Ctrl-C or SIGTERM stops in-flight downloads; they resume on the next run.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "install":
+			runInstall(ctx, os.Args[2:])
+			return
+		case "rollback":
+			runRollback(os.Args[2:])
+			return
+		case "sync":
+			runSync(ctx, os.Args[2:])
+			return
+		case "export":
+			runExport(os.Args[2:])
+			return
+		case "import":
+			runImport(os.Args[2:])
+			return
+		}
+	}
+
 	token := flag.String("token", "", "GitHub Personal Access Token. Defaults to GITHUB_TOKEN environment variable if not provided.")
-	destDir := flag.String("dest", "./downloads", "Destination directory for downloaded binaries")
-	var repos repoList
-	flag.Var(&repos, "repo", "Repository in 'owner/repo' format. Can be specified multiple times. (Required)")
+	destDir := flag.String("dest", "", "Destination directory for downloaded binaries (default: the config's 'dest', or ./downloads)")
+	configPath := flag.String("config", "", "YAML or JSON config file listing repositories with per-repository tag, match, extract and dest options (optional)")
+	provider := flag.String("provider", "github", "Where releases are fetched from: github, gitlab or gitea, optionally followed by '=URL' of a self-hosted instance, e.g. 'gitea=https://gitea.example.com'")
+	githubURL := flag.String("github-url", "", "URL of a GitHub Enterprise Server instance, e.g. 'https://github.example.com' (default: github.com)")
+	var repos stringList
+	flag.Var(&repos, "repo", "Repository in 'owner/repo' format, optionally pinned to a release as 'owner/repo@v1.2.3' or a version range as 'owner/repo@^1.4'. A glob such as 'owner/*' or 'owner/cli-*' selects repositories of a GitHub organization or user. GitLab and Gitea repositories can be given by URL, e.g. 'https://gitlab.com/group/tool'. Can be specified multiple times. (Required)")
+	var orgs stringList
+	flag.Var(&orgs, "org", "Download the latest release of every repository of this GitHub organization or user that has one, like '-repo owner/*'. Can be specified multiple times.")
+	topic := flag.String("topic", "", "With -org or '-repo owner/*', only download repositories tagged with this topic (optional)")
+	language := flag.String("language", "", "With -org or '-repo owner/*', only download repositories whose primary language this is (optional)")
 	match := flag.String("match", "", "Substring to filter assets by name (optional)")
+	matchRegex := flag.String("match-regex", "", "Regular expression asset names must match, e.g. 'linux_(amd64|x86_64)' (optional)")
+	matchGlob := flag.String("match-glob", "", "Glob pattern asset names must match, e.g. '*.tar.gz' (optional)")
+	var excludes stringList
+	flag.Var(&excludes, "exclude", "Glob pattern of asset names to skip, e.g. '*.sig' or '*checksums*'. Can be specified multiple times.")
+	warnNoAssets := flag.Bool("warn-no-assets", false, "Warn about repositories whose release has no assets matching the filters instead of failing")
+	platform := flag.String("platform", "", "Only download assets built for a platform: 'auto' for this machine, or 'os/arch' such as 'linux/amd64' or 'darwin/arm64' (optional)")
+	verify := flag.Bool("verify", false, "Verify downloaded assets against the release's checksum file (e.g. checksums.txt, SHA256SUMS), deleting any that don't match")
+	cosignKey := flag.String("cosign-key", "", "Verify assets with cosign against this public key file or KMS URI, failing on missing or invalid signatures (optional)")
+	cosignIdentity := flag.String("cosign-identity", "", "Verify assets with cosign keyless signatures made by this certificate identity, e.g. the release workflow URL; a value starting with '^' is a regular expression (optional)")
+	cosignIssuer := flag.String("cosign-issuer", ghdownloader.DefaultCosignIssuer, "OIDC issuer of keyless signatures checked with -cosign-identity")
+	gpgKey := flag.String("gpg-key", "", "Verify assets against their .asc/.sig GPG signatures with the public key in this file, failing on missing or invalid signatures (optional)")
+	hints := flag.Bool("hints", false, "Use the repository's .ghdownloader.yml hints file to pick the asset for this platform when -match is not set")
+	gunzip := flag.Bool("gunzip", false, "Decompress bare .gz assets (not .tar.gz) to the name without '.gz' and mark them executable")
+	extract := flag.Bool("extract", false, "Unpack .tar.gz, .tgz, .tar.bz2, .tar and .zip assets into the version directory and report the executables inside instead of the archive")
+	joinParts := flag.Bool("join-parts", false, "Reassemble split assets ('name.part1', 'name.part2', ... or 'name.001', 'name.002', ...) into a single file")
+	var stallRate byteSize
+	flag.Var(&stallRate, "stall-rate", "Minimum transfer rate per second, e.g. '10K'. Transfers slower than this for -stall-timeout are aborted and retried")
+	var limitRate byteSize
+	flag.Var(&limitRate, "limit-rate", "Maximum combined download rate of all asset transfers per second, e.g. '10M' (default unlimited)")
+	segments := flag.Int("segments", 0, "Download large assets over this many concurrent ranged requests (default a single stream)")
+	segmentMinSize := byteSize(ghdownloader.DefaultSegmentMinSize)
+	flag.Var(&segmentMinSize, "segment-min-size", "Smallest asset downloaded in -segments, e.g. '100M'")
+	maxHostConns := flag.Int("max-host-connections", 0, "Maximum number of asset transfers from a single host, such as GitHub's CDN, at once (default unlimited)")
+	stallTimeout := flag.Duration("stall-timeout", 0, "How long a transfer may stay below -stall-rate before it is retried, e.g. '60s' (default disabled)")
+	prerelease := flag.Bool("prerelease", false, "Pick the most recent non-draft release as the latest, even if it is a pre-release (e.g. for repositories that only publish nightly builds)")
+	source := flag.Bool("source", false, "Also download each release's source tarball as '<repo>-<tag>-src.tar.gz', so releases without assets can be downloaded too")
+	releases := flag.String("releases", "", "Download a range of releases of each -repo instead of the latest: 'all', 'last:N' or 'since:TAG' (optional)")
+	mirror := flag.Bool("mirror", false, "Mirror the assets of every release instead of only the latest. Later runs only fetch releases created since the last mirrored one")
+	preflight := flag.Bool("preflight", false, "Check that every repository exists and is accessible before downloading anything, suggesting corrections for typos")
+	verbose := flag.Bool("verbose", false, "Log every HTTP request (method, URL without query string, status). Credentials are never logged")
+	quiet := flag.Bool("quiet", false, "Only log warnings and errors")
+	jsonLogs := flag.Bool("json-logs", false, "Write log messages to stderr as JSON lines")
+	layout := flag.String("layout", "", "Template for where assets are saved in -dest, using {{.Owner}}, {{.Repo}}, {{.Tag}} and {{.Asset}} (default '"+ghdownloader.DefaultPathTemplate+"')")
+	current := flag.Bool("current", false, "Maintain a '<repo>-current' symlink in the destination directory pointing at the newest downloaded version")
+	blueGreen := flag.Bool("blue-green", false, "Download into a staging directory and atomically swap it in as '<repo>-current', keeping the previous version as '<repo>-previous'")
+	keep := flag.Int("keep", 0, "After a successful download, delete all but this many of the most recent '<repo>-<tag>' directories of each repository (default keep all)")
+	pruneDryRun := flag.Bool("prune-dry-run", false, "With -keep, only log the version directories that would be deleted")
+	smokeTest := flag.String("smoke-test", "", "Command run inside the staging directory before promoting a release in -blue-green mode (optional)")
+	archived := flag.String("archived", "warn", "Policy for archived repositories: warn, skip, or pin (keep the version already downloaded)")
+	concurrency := flag.Int("concurrency", ghdownloader.DefaultMaxConcurrentRepos, "Maximum number of repositories downloaded at once")
+	assetConcurrency := flag.Int("asset-concurrency", ghdownloader.DefaultMaxConcurrentAssets, "Maximum number of assets of a single release downloaded at once")
+	retries := flag.Int("retries", ghdownloader.DefaultRetries, "How many times to retry requests that fail with a network error, a 5xx status or a rate limit, and to resume downloads whose connection broke off (0 disables retries)")
+	timeout := flag.Duration("timeout", 0, "Time limit for each HTTP request, including transferring the response, e.g. 10m (default: none)")
+	insecure := flag.Bool("insecure-skip-verify", false, "Don't verify TLS certificates. Only for testing; prefer adding the proxy's CA to the system trust store")
+	cache := flag.Bool("cache", false, "Cache GitHub API responses in the user cache directory and revalidate them with conditional requests, which don't count against the rate limit when nothing changed")
+	cacheDir := flag.String("cache-dir", "", "Directory to cache GitHub API responses in; implies -cache (optional)")
+	progress := flag.Bool("progress", false, "Show a progress bar for each asset on stderr while downloading")
+	installDir := flag.String("install-dir", "", "Directory such as ~/bin to install the downloaded executables into, replacing previously installed versions (optional)")
+	installLink := flag.Bool("install-link", false, "Install symlinks to the versioned executables under -dest instead of copies")
+	installAs := flag.String("as", "", "Name to install the executable as in -install-dir; requires exactly one -repo (optional)")
+	checkUpdate := flag.Bool("check-update", false, "Report which repositories have a release newer than the version already downloaded, without downloading anything")
+	update := flag.Bool("update", false, "Only download repositories that have a release newer than the version already downloaded")
+	watch := flag.Bool("watch", false, "Keep running, checking for new releases every -interval and downloading them")
+	interval := flag.Duration("interval", 15*time.Minute, "How often -watch checks for new releases")
+	onUpdate := flag.String("on-update", "", "Command run after -watch downloads a new release, with GHDOWNLOADER_REPO, GHDOWNLOADER_TAG, GHDOWNLOADER_PREVIOUS_TAG and GHDOWNLOADER_FILES (newline-separated paths) set (optional)")
+	output := flag.String("output", "text", "Output format: text, or json for a structured result document on stdout (human messages then go to stderr)")
+	clean := flag.Bool("clean", false, "Remove partial downloads and other temporary files left in -dest by interrupted runs, then exit")
+	dryRun := flag.Bool("dry-run", false, "List the assets that would be downloaded, with their size, update time and whether they are already present, without downloading anything")
+	manifest := flag.String("manifest", "", "Write a lockfile (e.g. 'downloads.lock.json') recording the repository, tag, asset ID, URL, SHA-256, size and time of every downloaded file (optional)")
+	fromManifest := flag.String("from-manifest", "", "Download exactly the release assets recorded in a lockfile written by -manifest, verifying their SHA-256 digests, instead of the latest releases of -repo")
+	rewriteMoved := flag.Bool("rewrite-moved", false, "Update repositories that were renamed or transferred to their new location in the -config file or -from-manifest lockfile")
+	var probes stringList
+	flag.Var(&probes, "probe", "Version probe in 'owner/repo=command args' format, e.g. 'cli/cli=gh --version'. Skips the download if the installed version matches the latest tag. Can be specified multiple times.")
 
 	flag.Parse()
 
+	// Reject conflicting flags before any work is done.
+	set := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) {
+		set[f.Name] = f.Value.String() != "" && f.Value.String() != "false"
+	})
+	if err := checkFlagConflicts(set); err != nil {
+		log.Fatalf("Error: %v\n", err)
+	}
+
+	// In JSON mode stdout only carries the result document.
+	var human io.Writer = os.Stdout
+	switch *output {
+	case "text":
+	case "json":
+		human = os.Stderr
+	default:
+		log.Fatalf("Invalid -output value '%s': expected text or json\n", *output)
+	}
+	jsonOutput := *output == "json"
+
+	// Repositories from -repo are added to those listed in -config.
+	var config *ghdownloader.Config
+	if *configPath != "" {
+		var err error
+		config, err = ghdownloader.LoadConfig(*configPath)
+		if err != nil {
+			log.Fatalf("Error loading config: %v\n", err)
+		}
+		for _, spec := range repos {
+			userRepo, tag, _ := strings.Cut(spec, "@")
+			config.Repos = append(config.Repos, ghdownloader.RepoConfig{Repo: userRepo, Tag: tag})
+		}
+		repos = nil
+		for _, repo := range config.Repos {
+			repos = append(repos, repo.Repo)
+		}
+		if *destDir == "" {
+			*destDir = config.Dest
+		}
+	}
+	if *destDir == "" {
+		*destDir = "./downloads"
+	}
+
+	// Clean up after interrupted runs; no repositories are needed.
+	if *clean {
+		downloader := ghdownloader.New(*token, *destDir)
+		downloader.SetLogger(newLogger(*quiet, *verbose, *jsonLogs))
+		removed, err := downloader.Clean()
+		if jsonOutput {
+			writeOutput(outputDocument{Removed: removed}, err)
+			return
+		}
+		if err != nil {
+			log.Fatalf("Error cleaning '%s': %v\n", *destDir, err)
+		}
+		if !*quiet {
+			fmt.Printf("Removed %d temporary file(s).\n", len(removed))
+		}
+		return
+	}
+
 	// Validate that at least one repository is provided.
-	if len(repos) == 0 {
+	if len(repos) == 0 && len(orgs) == 0 && *fromManifest == "" {
+		if jsonOutput {
+			writeOutput(outputDocument{}, fmt.Errorf("at least one repository is required"))
+			return
+		}
 		fmt.Println("Error: At least one repository is required.")
 		flag.Usage()
 		os.Exit(1)
@@ -40,18 +333,927 @@ func main() {
 
 	// Create a new downloader.
 	downloader := ghdownloader.New(*token, *destDir)
+	if *githubURL != "" {
+		if err := downloader.SetBaseURL(*githubURL, ""); err != nil {
+			log.Fatalf("Invalid -github-url value: %v\n", err)
+		}
+	}
+	kind, providerURL, _ := strings.Cut(*provider, "=")
+	switch kind {
+	case "github":
+		if providerURL != "" {
+			log.Fatalf("Invalid -provider value '%s': use -github-url for GitHub Enterprise Server\n", *provider)
+		}
+	case "gitlab", "gitea":
+		// -token belongs to the selected provider.
+		newProvider := downloader.NewGitLabProvider
+		if kind == "gitea" {
+			newProvider = downloader.NewGiteaProvider
+		}
+		p, err := newProvider(providerURL, *token)
+		if err != nil {
+			log.Fatalf("Invalid -provider value '%s': %v\n", *provider, err)
+		}
+		downloader.SetProvider(p)
+	default:
+		log.Fatalf("Invalid -provider value '%s': expected github, gitlab or gitea\n", *provider)
+	}
+	// Repositories given as URLs pick their provider from the host.
+	for i, spec := range repos {
+		if strings.Contains(spec, "://") {
+			userRepo, err := downloader.RegisterRepoURL(spec)
+			if err != nil {
+				log.Fatalf("Invalid -repo value: %v\n", err)
+			}
+			repos[i] = userRepo
+		}
+	}
+	if config != nil {
+		if err := downloader.RegisterConfigURLs(config); err != nil {
+			log.Fatalf("Invalid repo in -config: %v\n", err)
+		}
+	}
 	downloader.SetMatchFilter(*match)
+	if *matchRegex != "" {
+		re, err := regexp.Compile(*matchRegex)
+		if err != nil {
+			log.Fatalf("Invalid -match-regex value '%s': %v\n", *matchRegex, err)
+		}
+		downloader.SetMatchRegexp(re)
+	}
+	if err := downloader.SetMatchGlob(*matchGlob); err != nil {
+		log.Fatalf("Invalid -match-glob value: %v\n", err)
+	}
+	if err := downloader.SetExcludeFilter(excludes...); err != nil {
+		log.Fatalf("Invalid -exclude value: %v\n", err)
+	}
+	if *platform != "" {
+		goos, goarch, err := ghdownloader.ParsePlatform(*platform)
+		if err != nil {
+			log.Fatalf("Invalid -platform value '%s': %v\n", *platform, err)
+		}
+		downloader.SetPlatformFilter(goos, goarch)
+	}
+	downloader.SetVerbose(*verbose)
+	downloader.SetLogger(newLogger(*quiet, *verbose, *jsonLogs))
+	downloader.SetVerifyChecksums(*verify)
+	switch {
+	case *cosignKey != "" && *cosignIdentity != "":
+		log.Fatalf("Error: -cosign-key and -cosign-identity can't be combined\n")
+	case *cosignKey != "":
+		if _, err := os.Stat(*cosignKey); err != nil && !strings.Contains(*cosignKey, "://") {
+			log.Fatalf("Invalid -cosign-key value: %v\n", err)
+		}
+		downloader.SetVerifySignatures(*cosignKey)
+	case *cosignIdentity != "":
+		downloader.SetVerifySignatures(*cosignIdentity)
+		downloader.SetCosignIssuer(*cosignIssuer)
+	}
+	downloader.SetGPGKey(*gpgKey)
+	downloader.SetUseAssetHints(*hints)
+	downloader.SetDecompressGzip(*gunzip)
+	downloader.SetJoinParts(*joinParts)
+	downloader.SetExtract(*extract)
+	downloader.SetMaxConcurrentRepos(*concurrency)
+	downloader.SetMaxConcurrentAssets(*assetConcurrency)
+	downloader.SetRetries(*retries)
+	if *timeout > 0 || *insecure {
+		// Cloning the default transport keeps HTTPS_PROXY support.
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		if *insecure {
+			log.Println("Warning: TLS certificate verification is disabled.")
+			transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+		}
+		downloader.SetHTTPClient(&http.Client{Transport: transport, Timeout: *timeout})
+	}
+	if *cache && *cacheDir == "" {
+		dir, err := ghdownloader.DefaultCacheDir()
+		if err != nil {
+			log.Fatalf("Error: cannot determine the cache directory: %v\n", err)
+		}
+		*cacheDir = dir
+	}
+	downloader.SetCacheDir(*cacheDir)
+	downloader.SetIncludePrereleases(*prerelease)
+	downloader.SetDownloadSource(*source)
+	downloader.SetStallWatchdog(int64(stallRate), *stallTimeout)
+	downloader.SetRateLimit(int64(limitRate))
+	downloader.SetMaxHostConnections(*maxHostConns)
+	downloader.SetSegmentedDownloads(*segments, int64(segmentMinSize))
+	if *installDir != "" {
+		*installDir = expandHome(*installDir)
+		downloader.SetInstallDir(*installDir, *installLink)
+	}
+	if *installAs != "" {
+		if *installDir == "" || len(repos) != 1 {
+			log.Fatalf("Error: -as requires -install-dir and exactly one repository\n")
+		}
+		userRepo, _, _ := strings.Cut(repos[0], "@")
+		downloader.SetInstallName(userRepo, *installAs)
+	}
+	if err := downloader.SetPathTemplate(*layout); err != nil {
+		log.Fatalf("Error: %v\n", err)
+	}
+	downloader.SetUpdateCurrentLink(*current)
+	downloader.SetBlueGreen(*blueGreen)
+	downloader.SetSmokeTest(strings.Fields(*smokeTest)...)
+	if *pruneDryRun && *keep <= 0 {
+		log.Fatalf("Error: -prune-dry-run requires -keep\n")
+	}
+	downloader.SetKeepVersions(*keep)
+	downloader.SetPruneDryRun(*pruneDryRun)
+	if *progress {
+		downloader.SetProgressFunc(newProgressBars().Update)
+	}
+	switch *archived {
+	case "warn":
+		downloader.SetArchivedPolicy(ghdownloader.ArchivedWarn)
+	case "skip":
+		downloader.SetArchivedPolicy(ghdownloader.ArchivedSkip)
+	case "pin":
+		downloader.SetArchivedPolicy(ghdownloader.ArchivedPin)
+	default:
+		log.Fatalf("Invalid -archived value '%s': expected warn, skip, or pin\n", *archived)
+	}
+	for _, probe := range probes {
+		userRepo, command, ok := strings.Cut(probe, "=")
+		if !ok || len(strings.Fields(command)) == 0 {
+			log.Fatalf("Invalid -probe value '%s': expected 'owner/repo=command args'\n", probe)
+		}
+		downloader.SetVersionProbe(userRepo, strings.Fields(command)...)
+	}
+
+	// Expand organizations and repository globs into their repositories.
+	filter := ghdownloader.RepoFilter{Topic: *topic, Language: *language}
+	for _, org := range orgs {
+		repos = append(repos, org+"/*")
+		if config != nil {
+			config.Repos = append(config.Repos, ghdownloader.RepoConfig{Repo: org + "/*"})
+		}
+	}
+	if config != nil {
+		var expanded []ghdownloader.RepoConfig
+		for _, repo := range config.Repos {
+			userRepos, err := expandRepo(ctx, downloader, repo.Repo, filter)
+			if err != nil {
+				log.Fatalf("Error: %v\n", err)
+			}
+			for _, userRepo := range userRepos {
+				repo.Repo = userRepo
+				expanded = append(expanded, repo)
+			}
+		}
+		config.Repos = expanded
+		repos = nil
+		for _, repo := range config.Repos {
+			repos = append(repos, repo.Repo)
+		}
+	} else {
+		var expanded []string
+		for _, spec := range repos {
+			userRepo, tag, hasTag := strings.Cut(spec, "@")
+			userRepos, err := expandRepo(ctx, downloader, userRepo, filter)
+			if err != nil {
+				log.Fatalf("Error: %v\n", err)
+			}
+			for _, userRepo := range userRepos {
+				if hasTag {
+					userRepo += "@" + tag
+				}
+				expanded = append(expanded, userRepo)
+			}
+		}
+		repos = expanded
+	}
+	if len(repos) == 0 && *fromManifest == "" {
+		log.Fatalf("Error: no repositories match.\n")
+	}
+
+	// Fail up front if any repository is missing or inaccessible.
+	if *preflight {
+		if err := downloader.PreflightContext(ctx, repos); err != nil {
+			log.Fatalf("Error: %v\n", err)
+		}
+	}
+
+	var releaseRange ghdownloader.ReleaseRange
+	if *releases != "" {
+		var err error
+		releaseRange, err = ghdownloader.ParseReleaseRange(*releases)
+		if err != nil {
+			log.Fatalf("Invalid -releases value '%s': %v\n", *releases, err)
+		}
+	}
+
+	// Only show what would be downloaded.
+	if *dryRun {
+		specs := repos
+		if config != nil {
+			specs = downloader.ApplyConfig(config)
+		}
+		assets, err := downloader.ListLatestReleaseAssetsContext(ctx, specs)
+		if err != nil && *warnNoAssets {
+			err = warnNoAssetErrors(human, "list", err)
+		}
+		if jsonOutput {
+			doc := outputDocument{Assets: []assetOutput{}}
+			for _, asset := range assets {
+				doc.Assets = append(doc.Assets, assetOutput{
+					Repo: asset.Owner + "/" + asset.Repo, Tag: asset.Tag, Name: asset.Name, Path: asset.Path,
+					Size: asset.Size, UpdatedAt: asset.UpdatedAt, Exists: asset.Exists,
+				})
+			}
+			writeOutput(doc, err)
+			return
+		}
+		for _, asset := range assets {
+			status := "download"
+			if asset.Exists {
+				status = "present"
+			}
+			fmt.Printf("%-8s %s/%s@%s %s (%s, updated %s)\n", status, asset.Owner, asset.Repo, asset.Tag,
+				asset.Name, formatBytes(asset.Size), asset.UpdatedAt.Format("2006-01-02"))
+		}
+		if err != nil {
+			log.Fatalf("Error listing releases: %v\n", err)
+		}
+		return
+	}
+
+	// Compare the latest releases with what is already downloaded.
+	if *checkUpdate || *update {
+		specs := repos
+		if config != nil {
+			specs = downloader.ApplyConfig(config)
+			config = nil
+		}
+		statuses, err := downloader.CheckForUpdatesContext(ctx, specs)
+		if *checkUpdate && jsonOutput {
+			doc := outputDocument{Updates: []updateOutput{}}
+			for _, status := range statuses {
+				doc.Updates = append(doc.Updates, updateOutput{
+					Repo: status.Repo, Current: status.Current, Latest: status.Latest,
+					UpdateAvailable: status.UpdateAvailable, Error: errorString(status.Err),
+				})
+			}
+			writeOutput(doc, err)
+			return
+		}
+		if err != nil {
+			log.Fatalf("Error checking for updates: %v\n", err)
+		}
+		repos = nil
+		for i, status := range statuses {
+			switch {
+			case status.UpdateAvailable:
+				repos = append(repos, specs[i])
+				if status.Current == "" {
+					fmt.Fprintf(human, "%-10s %s %s\n", "new", status.Repo, status.Latest)
+				} else {
+					fmt.Fprintf(human, "%-10s %s %s -> %s\n", "update", status.Repo, status.Current, status.Latest)
+				}
+			case !*quiet:
+				fmt.Fprintf(human, "%-10s %s %s\n", "current", status.Repo, status.Current)
+			}
+		}
+		if *checkUpdate || len(repos) == 0 {
+			if jsonOutput {
+				writeOutput(outputDocument{Repos: []repoOutput{}}, nil)
+			}
+			return
+		}
+	}
+
+	if *watch {
+		specs := repos
+		if config != nil {
+			specs = downloader.ApplyConfig(config)
+		}
+		hook := strings.Fields(*onUpdate)
+		err := downloader.Watch(ctx, specs, *interval, func(event ghdownloader.WatchEvent) {
+			if event.Err != nil || len(hook) == 0 {
+				return
+			}
+			if err := runHook(ctx, hook, event); err != nil {
+				log.Printf("Error running -on-update for %s: %v\n", event.Repo, err)
+			}
+		})
+		if err != nil && ctx.Err() == nil {
+			log.Fatalf("Error watching for releases: %v\n", err)
+		}
+		return
+	}
 
 	// Download the latest releases.
-	fmt.Println("Starting download...")
-	binPaths, err := downloader.DownloadLatestReleases(repos)
+	if !*quiet {
+		fmt.Fprintln(human, "Starting download...")
+	}
+	var binPaths []string
+	var results []ghdownloader.DownloadResult
+	var lock *ghdownloader.Lockfile
+	var err error
+	switch {
+	case *fromManifest != "":
+		var lockErr error
+		lock, lockErr = ghdownloader.LoadLockfile(*fromManifest)
+		if lockErr != nil {
+			log.Fatalf("Error reading -from-manifest: %v\n", lockErr)
+		}
+		binPaths, err = downloader.DownloadFromLockfileContext(ctx, lock)
+	case config != nil:
+		binPaths, err = downloader.DownloadFromConfigContext(ctx, config)
+	case *mirror:
+		binPaths, err = downloader.MirrorReleasesContext(ctx, repos)
+	case *releases != "":
+		for _, userRepo := range repos {
+			// GitLab owners may be nested groups.
+			slash := strings.LastIndex(userRepo, "/")
+			owner, repo := userRepo[:max(slash, 0)], userRepo[slash+1:]
+			if slash <= 0 || repo == "" || strings.Contains(userRepo, "@") {
+				log.Fatalf("Invalid -repo value '%s' for -releases: expected 'owner/repo'\n", userRepo)
+			}
+			// Each call only returns its own files.
+			paths, repoErr := downloader.DownloadReleasesContext(ctx, owner, repo, releaseRange)
+			binPaths = append(binPaths, paths...)
+			results = append(results, downloader.Results()...)
+			if repoErr != nil && *warnNoAssets {
+				repoErr = warnNoAssetErrors(human, "download", repoErr)
+			}
+			if repoErr != nil {
+				err = repoErr
+				break
+			}
+		}
+	default:
+		binPaths, err = downloader.DownloadLatestReleasesContext(ctx, repos)
+	}
+	if results == nil {
+		results = downloader.Results()
+	}
+	if err != nil && *warnNoAssets {
+		action := "download"
+		if *mirror {
+			action = "mirror"
+		}
+		err = warnNoAssetErrors(human, action, err)
+	}
+	moved := downloader.MovedRepos()
+	if *rewriteMoved && len(moved) > 0 {
+		if rewriteErr := rewriteMovedRepos(human, *configPath, *fromManifest, lock, moved); rewriteErr != nil && err == nil {
+			err = rewriteErr
+		}
+	} else {
+		for from, to := range moved {
+			fmt.Fprintf(human, "Note: '%s' has moved; update '-repo %s' to '-repo %s'.\n", from, from, to)
+		}
+	}
+	if err == nil && *manifest != "" {
+		if writeErr := downloader.LockfileFor(results).Write(*manifest); writeErr != nil {
+			err = fmt.Errorf("failed to write -manifest: %v", writeErr)
+		}
+	}
+	if jsonOutput {
+		writeOutput(outputDocument{Repos: repoOutputs(results), Moved: moved}, err)
+		return
+	}
 	if err != nil {
 		log.Fatalf("Error downloading releases: %v\n", err)
 	}
 
-	fmt.Println("Download completed successfully.")
+	if !*quiet {
+		fmt.Println("Download completed successfully.")
+		fmt.Println("Downloaded binaries:")
+	}
+	for _, path := range binPaths {
+		fmt.Println(path)
+	}
+}
+
+// warnNoAssetErrors prints the failures in err of repositories without
+// matching assets to w as warnings, returning an error for the other failed
+// repositories, or nil if there are none. Errors that aren't about single
+// repositories are returned as is.
+func warnNoAssetErrors(w io.Writer, action string, err error) error {
+	repoErrs := ghdownloader.RepoErrors(err)
+	var rest []error
+	for _, repoErr := range repoErrs {
+		if onlyNoAssets(repoErr.Err) {
+			fmt.Fprintf(w, "Warning: %v\n", repoErr)
+			continue
+		}
+		rest = append(rest, fmt.Errorf("failed to %s %w", action, repoErr))
+	}
+	switch {
+	case len(rest) == len(repoErrs):
+		return err
+	case len(rest) == 0:
+		return nil
+	}
+	return fmt.Errorf("errors occurred:\n%w", errors.Join(rest...))
+}
+
+// onlyNoAssets reports whether err, and every error it joins, is about a
+// release without matching assets.
+func onlyNoAssets(err error) bool {
+	if err == ghdownloader.ErrNoAssets {
+		return true
+	}
+	switch u := err.(type) {
+	case interface{ Unwrap() []error }:
+		for _, next := range u.Unwrap() {
+			if !onlyNoAssets(next) {
+				return false
+			}
+		}
+		return true
+	case interface{ Unwrap() error }:
+		return onlyNoAssets(u.Unwrap())
+	}
+	return false
+}
+
+// rewriteMovedRepos updates moved repositories to their new location in the
+// config file at configPath and the lockfile lock read from lockPath, where
+// given, and tells w about repositories given otherwise.
+func rewriteMovedRepos(w io.Writer, configPath, lockPath string, lock *ghdownloader.Lockfile, moved map[string]string) error {
+	if configPath != "" {
+		changed, err := ghdownloader.RewriteConfigRepos(configPath, moved)
+		if err != nil {
+			return err
+		}
+		if changed {
+			fmt.Fprintf(w, "Updated moved repositories in '%s'.\n", configPath)
+		}
+	}
+	if lock != nil && lock.RenameRepos(moved) {
+		if err := lock.Write(lockPath); err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "Updated moved repositories in '%s'.\n", lockPath)
+	}
+	if configPath == "" && lock == nil {
+		for from, to := range moved {
+			fmt.Fprintf(w, "Note: '%s' has moved; update '-repo %s' to '-repo %s'.\n", from, from, to)
+		}
+	}
+	return nil
+}
+
+// flagConflicts lists the flags that can't be combined with each flag.
+var flagConflicts = []struct {
+	flag string
+	with []string
+}{
+	{"mirror", []string{"config"}},
+	{"releases", []string{"config"}},
+	{"dry-run", []string{"mirror", "releases", "from-manifest", "check-update", "update", "watch"}},
+	{"check-update", []string{"mirror", "releases", "from-manifest"}},
+	{"update", []string{"mirror", "releases", "from-manifest"}},
+	{"watch", []string{"mirror", "releases", "from-manifest", "check-update", "update"}},
+}
+
+// checkFlagConflicts returns an error for the first pair of set flags that
+// can't be combined.
+func checkFlagConflicts(set map[string]bool) error {
+	for _, conflict := range flagConflicts {
+		if !set[conflict.flag] {
+			continue
+		}
+		for _, other := range conflict.with {
+			if set[other] {
+				return fmt.Errorf("-%s can't be combined with -%s", conflict.flag, other)
+			}
+		}
+	}
+	return nil
+}
+
+// outputDocument is the result document written by -output json.
+type outputDocument struct {
+	Success bool              `json:"success"`
+	Error   string            `json:"error,omitempty"`
+	Repos   []repoOutput      `json:"repos,omitempty"`
+	Assets  []assetOutput     `json:"assets,omitempty"`
+	Updates []updateOutput    `json:"updates,omitempty"`
+	Moved   map[string]string `json:"moved,omitempty"`
+	Removed []string          `json:"removed,omitempty"`
+}
+
+// repoOutput describes the files downloaded for a repository.
+type repoOutput struct {
+	Repo    string       `json:"repo"`
+	Tag     string       `json:"tag,omitempty"`
+	Skipped bool         `json:"skipped,omitempty"`
+	Error   string       `json:"error,omitempty"`
+	Files   []fileOutput `json:"files"`
+}
+
+// fileOutput describes a single downloaded or extracted file.
+type fileOutput struct {
+	Asset   string `json:"asset"`
+	Path    string `json:"path,omitempty"`
+	Size    int64  `json:"size,omitempty"`
+	SHA256  string `json:"sha256,omitempty"`
+	Skipped bool   `json:"skipped,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// assetOutput describes an asset listed by -dry-run.
+type assetOutput struct {
+	Repo      string    `json:"repo"`
+	Tag       string    `json:"tag"`
+	Name      string    `json:"name"`
+	Path      string    `json:"path"`
+	Size      int64     `json:"size"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Exists    bool      `json:"exists"`
+}
+
+// updateOutput describes a repository checked by -check-update.
+type updateOutput struct {
+	Repo            string `json:"repo"`
+	Current         string `json:"current,omitempty"`
+	Latest          string `json:"latest,omitempty"`
+	UpdateAvailable bool   `json:"update_available"`
+	Error           string `json:"error,omitempty"`
+}
+
+// repoOutputs groups results by repository, in the order repositories first
+// appear.
+func repoOutputs(results []ghdownloader.DownloadResult) []repoOutput {
+	repos := []repoOutput{}
+	index := make(map[string]int)
+	for _, r := range results {
+		name := r.Owner + "/" + r.Repo
+		i, ok := index[name]
+		if !ok {
+			i = len(repos)
+			index[name] = i
+			repos = append(repos, repoOutput{Repo: name, Files: []fileOutput{}})
+		}
+		repo := &repos[i]
+		if r.Tag != "" {
+			repo.Tag = r.Tag
+		}
+		if r.AssetName == "" {
+			repo.Skipped = r.Skipped
+			repo.Error = errorString(r.Err)
+			continue
+		}
+		repo.Files = append(repo.Files, fileOutput{
+			Asset: r.AssetName, Path: r.Path, Size: r.Size, SHA256: r.SHA256,
+			Skipped: r.Skipped, Error: errorString(r.Err),
+		})
+	}
+	return repos
+}
+
+// writeOutput writes doc to stdout, recording err, and exits with status 1
+// if err is set.
+func writeOutput(doc outputDocument, err error) {
+	doc.Success = err == nil
+	doc.Error = errorString(err)
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if encErr := enc.Encode(doc); encErr != nil {
+		log.Fatalf("Error writing output: %v\n", encErr)
+	}
+	if err != nil {
+		os.Exit(1)
+	}
+}
+
+// errorString returns the message of err, or "" if it is nil.
+func errorString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+// runHook runs the -on-update command for a release downloaded by -watch,
+// passing its details in the environment.
+func runHook(ctx context.Context, hook []string, event ghdownloader.WatchEvent) error {
+	var files []string
+	for _, r := range event.Results {
+		if r.Err == nil && r.Path != "" {
+			files = append(files, r.Path)
+		}
+	}
+	cmd := exec.CommandContext(ctx, hook[0], hook[1:]...)
+	cmd.Env = append(os.Environ(),
+		"GHDOWNLOADER_REPO="+event.Repo,
+		"GHDOWNLOADER_TAG="+event.Tag,
+		"GHDOWNLOADER_PREVIOUS_TAG="+event.Previous,
+		"GHDOWNLOADER_FILES="+strings.Join(files, "\n"),
+	)
+	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+	return cmd.Run()
+}
+
+// newLogger returns the logger for the downloader's messages on stderr.
+func newLogger(quiet, verbose, jsonLogs bool) *slog.Logger {
+	level := slog.LevelInfo
+	switch {
+	case verbose:
+		level = slog.LevelDebug
+	case quiet:
+		level = slog.LevelWarn
+	}
+	if jsonLogs {
+		return slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{Level: level}))
+	}
+	return slog.New(ghdownloader.NewPlainHandler(os.Stderr, level))
+}
+
+// runInstall implements "ghdownloader install <tool>...", which looks tools up
+// in the registry and downloads their latest release for this platform.
+func runInstall(ctx context.Context, args []string) {
+	fs := flag.NewFlagSet("install", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s install [flags] <tool>...\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	token := fs.String("token", "", "GitHub Personal Access Token. Defaults to GITHUB_TOKEN environment variable if not provided.")
+	destDir := fs.String("dest", "./downloads", "Destination directory for downloaded binaries")
+	registryPath := fs.String("registry", "", "Additional registry file (YAML or JSON) merged over the built-in registry")
+	list := fs.Bool("list", false, "List the tools available in the registry and exit")
+	platform := fs.String("platform", "auto", "Platform to install for: 'auto' for this machine, or 'os/arch' such as 'linux/amd64' or 'darwin/arm64'")
+	installDir := fs.String("install-dir", "~/.local/bin", "Directory to install the executables of the tools into, replacing previously installed versions; empty to only download them")
+	installLink := fs.Bool("install-link", false, "Install symlinks to the versioned executables under -dest instead of copies")
+	fs.Parse(args)
+
+	registry, err := ghdownloader.DefaultRegistry()
+	if err != nil {
+		log.Fatalf("Error loading registry: %v\n", err)
+	}
+	if *registryPath != "" {
+		extra, err := ghdownloader.LoadRegistry(*registryPath)
+		if err != nil {
+			log.Fatalf("Error loading registry: %v\n", err)
+		}
+		registry.Merge(extra)
+	}
+
+	if *list {
+		for _, name := range registry.Names() {
+			fmt.Printf("%s\t%s\n", name, registry[name].Repo)
+		}
+		return
+	}
+
+	if fs.NArg() == 0 {
+		fmt.Println("Error: At least one tool name is required.")
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	downloader := ghdownloader.New(*token, *destDir)
+	goos, goarch, err := ghdownloader.ParsePlatform(*platform)
+	if err != nil {
+		log.Fatalf("Invalid -platform value '%s': %v\n", *platform, err)
+	}
+	downloader.SetPlatformFilter(goos, goarch)
+	if *installDir != "" {
+		*installDir = expandHome(*installDir)
+		downloader.SetInstallDir(*installDir, *installLink)
+	}
+	fmt.Println("Starting install...")
+	binPaths, err := downloader.InstallContext(ctx, fs.Args(), registry)
+	if err != nil {
+		log.Fatalf("Error installing tools: %v\n", err)
+	}
+
+	fmt.Println("Install completed successfully.")
+	if *installDir != "" {
+		fmt.Printf("Installed into '%s'.\n", *installDir)
+	}
 	fmt.Println("Downloaded binaries:")
 	for _, path := range binPaths {
 		fmt.Println(path)
 	}
 }
+
+// expandHome expands a leading "~/" in path to the home directory.
+func expandHome(path string) string {
+	if home, err := os.UserHomeDir(); err == nil && strings.HasPrefix(path, "~/") {
+		return filepath.Join(home, path[2:])
+	}
+	return path
+}
+
+// expandRepo returns the repositories of a GitHub organization or user that
+// userRepo selects with a glob, such as "owner/*", matching filter. Other
+// repositories are returned as they are.
+func expandRepo(ctx context.Context, downloader *ghdownloader.Downloader, userRepo string, filter ghdownloader.RepoFilter) ([]string, error) {
+	i := strings.LastIndex(userRepo, "/")
+	name := userRepo[i+1:]
+	if i < 0 || !strings.ContainsAny(name, "*?[") || strings.Contains(userRepo, "://") {
+		return []string{userRepo}, nil
+	}
+	if name != "*" {
+		filter.Name = name
+	}
+	userRepos, err := downloader.ExpandOwnerContext(ctx, userRepo[:i], filter)
+	if err != nil {
+		return nil, err
+	}
+	if len(userRepos) == 0 {
+		log.Printf("Warning: no repositories match '%s'.\n", userRepo)
+	}
+	return userRepos, nil
+}
+
+// runRollback implements "ghdownloader rollback <repo>...", which makes the
+// previous blue/green version of each repository live again.
+func runRollback(args []string) {
+	fs := flag.NewFlagSet("rollback", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s rollback [flags] <owner/repo>...\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	destDir := fs.String("dest", "./downloads", "Destination directory for downloaded binaries")
+	layout := fs.String("layout", "", "Path template the releases were downloaded with (default '"+ghdownloader.DefaultPathTemplate+"')")
+	fs.Parse(args)
+
+	if fs.NArg() == 0 {
+		fmt.Println("Error: At least one repository is required.")
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	downloader := ghdownloader.New("", *destDir)
+	if err := downloader.SetPathTemplate(*layout); err != nil {
+		log.Fatalf("Error: %v\n", err)
+	}
+	for _, userRepo := range fs.Args() {
+		if err := downloader.Rollback(userRepo); err != nil {
+			log.Fatalf("Error rolling back %s: %v\n", userRepo, err)
+		}
+	}
+}
+
+// runExport implements "ghdownloader export", which packages downloads into a
+// signed bundle for transfer to an offline machine.
+func runExport(args []string) {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s export [flags] [path...]\n", os.Args[0])
+		fmt.Fprintln(fs.Output(), "Paths are relative to -dest; everything is exported if none are given.")
+		fs.PrintDefaults()
+	}
+	destDir := fs.String("dest", "./downloads", "Destination directory for downloaded binaries")
+	output := fs.String("o", "bundle.tar.gz", "Bundle file to write")
+	keyPath := fs.String("key", "", "Private key file used to sign the bundle (optional)")
+	generateKey := fs.String("generate-key", "", "Generate a signing key pair at this path (and '<path>.pub') and exit")
+	fs.Parse(args)
+
+	if *generateKey != "" {
+		pub, priv, err := ed25519.GenerateKey(nil)
+		if err != nil {
+			log.Fatalf("Error generating key: %v\n", err)
+		}
+		if err := os.WriteFile(*generateKey, []byte(base64.StdEncoding.EncodeToString(priv)+"\n"), 0600); err != nil {
+			log.Fatalf("Error writing key: %v\n", err)
+		}
+		if err := os.WriteFile(*generateKey+".pub", []byte(base64.StdEncoding.EncodeToString(pub)+"\n"), 0644); err != nil {
+			log.Fatalf("Error writing key: %v\n", err)
+		}
+		fmt.Printf("Wrote signing key to '%s' and public key to '%s.pub'\n", *generateKey, *generateKey)
+		return
+	}
+
+	var key ed25519.PrivateKey
+	if *keyPath != "" {
+		data, err := readKeyFile(*keyPath, ed25519.PrivateKeySize)
+		if err != nil {
+			log.Fatalf("Error reading key: %v\n", err)
+		}
+		key = ed25519.PrivateKey(data)
+	}
+
+	f, err := os.Create(*output)
+	if err != nil {
+		log.Fatalf("Error creating bundle: %v\n", err)
+	}
+	manifest, err := ghdownloader.New("", *destDir).ExportBundle(f, fs.Args(), key)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(*output)
+		log.Fatalf("Error exporting bundle: %v\n", err)
+	}
+	fmt.Printf("Exported %d file(s) to '%s'\n", len(manifest.Files), *output)
+}
+
+// runImport implements "ghdownloader import", which validates a bundle and
+// unpacks it into the destination directory.
+func runImport(args []string) {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s import [flags] <bundle>\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	destDir := fs.String("dest", "./downloads", "Destination directory for downloaded binaries")
+	pubKeyPath := fs.String("pubkey", "", "Public key file the bundle must be signed with")
+	insecure := fs.Bool("insecure-skip-verify", false, "Import the bundle without verifying its signature when no -pubkey is given")
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fmt.Println("Error: Exactly one bundle is required.")
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	var key ed25519.PublicKey
+	if *pubKeyPath != "" {
+		data, err := readKeyFile(*pubKeyPath, ed25519.PublicKeySize)
+		if err != nil {
+			log.Fatalf("Error reading key: %v\n", err)
+		}
+		key = ed25519.PublicKey(data)
+	} else if *insecure {
+		fmt.Println("Warning: no -pubkey given; the bundle signature will not be verified.")
+	} else {
+		log.Fatalf("Error: -pubkey is required to verify the bundle (use -insecure-skip-verify to import it unverified)\n")
+	}
+
+	f, err := os.Open(fs.Arg(0))
+	if err != nil {
+		log.Fatalf("Error opening bundle: %v\n", err)
+	}
+	defer f.Close()
+
+	paths, err := ghdownloader.New("", *destDir).ImportBundle(f, key)
+	if err != nil {
+		log.Fatalf("Error importing bundle: %v\n", err)
+	}
+	fmt.Println("Import completed successfully.")
+	fmt.Println("Imported files:")
+	for _, path := range paths {
+		fmt.Println(path)
+	}
+}
+
+// readKeyFile reads a base64-encoded key of the given size.
+func readKeyFile(path string, size int) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil || len(key) != size {
+		return nil, fmt.Errorf("'%s' is not a valid key file", path)
+	}
+	return key, nil
+}
+
+// runSync implements "ghdownloader sync <manifest>", which converges the
+// destination directory to the packages declared in a manifest.
+func runSync(ctx context.Context, args []string) {
+	fs := flag.NewFlagSet("sync", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s sync [flags] <manifest.yaml>\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	token := fs.String("token", "", "GitHub Personal Access Token. Defaults to GITHUB_TOKEN environment variable if not provided.")
+	destDir := fs.String("dest", "", "Destination directory for downloaded binaries (default: the manifest's 'dest', or ./downloads)")
+	check := fs.Bool("check", false, "Only report drift from the manifest; don't download or remove anything")
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fmt.Println("Error: Exactly one manifest is required.")
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	manifest, err := ghdownloader.LoadManifest(fs.Arg(0))
+	if err != nil {
+		log.Fatalf("Error loading manifest: %v\n", err)
+	}
+	if *destDir == "" {
+		*destDir = manifest.Dest
+	}
+	if *destDir == "" {
+		*destDir = "./downloads"
+	}
+
+	downloader := ghdownloader.New(*token, *destDir)
+	changes, err := downloader.SyncContext(ctx, manifest, !*check)
+	for _, change := range changes {
+		switch {
+		case change.Err != nil:
+			fmt.Printf("%-10s %s: %v\n", change.Action, change.Repo, change.Err)
+		case change.Action == ghdownloader.SyncUpdated:
+			fmt.Printf("%-10s %s %s -> %s\n", change.Action, change.Repo, change.From, change.To)
+		case change.Action == ghdownloader.SyncPruned:
+			fmt.Printf("%-10s %s %s\n", change.Action, change.Repo, change.From)
+		default:
+			fmt.Printf("%-10s %s %s\n", change.Action, change.Repo, change.To)
+		}
+	}
+	if err != nil {
+		log.Fatalf("Error syncing manifest: %v\n", err)
+	}
+}
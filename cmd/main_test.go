@@ -0,0 +1,81 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/dropsite-ai/ghdownloader"
+)
+
+func TestWarnNoAssetErrors(t *testing.T) {
+	noAssets := &ghdownloader.RepoError{Owner: "owner", Repo: "lib", Err: fmt.Errorf("%w in the release", ghdownloader.ErrNoAssets)}
+	notFound := &ghdownloader.RepoError{Owner: "owner", Repo: "typo", Err: fmt.Errorf("%w: GET ...", ghdownloader.ErrNotFound)}
+	// A -releases error where only one release lacks assets.
+	mixed := &ghdownloader.RepoError{Owner: "owner", Repo: "old", Err: errors.Join(
+		fmt.Errorf("release 'v1': %w", ghdownloader.ErrNoAssets),
+		errors.New("release 'v2': some assets failed to download"),
+	)}
+	join := func(repoErrs ...*ghdownloader.RepoError) error {
+		var errs []error
+		for _, repoErr := range repoErrs {
+			errs = append(errs, fmt.Errorf("failed to download %w", repoErr))
+		}
+		return fmt.Errorf("errors occurred:\n%w", errors.Join(errs...))
+	}
+
+	tests := []struct {
+		name  string
+		err   error
+		repos []string
+	}{
+		{"only missing assets", join(noAssets), nil},
+		{"other failures kept", join(noAssets, notFound), []string{"typo"}},
+		{"partly missing assets", join(mixed), []string{"old"}},
+		{"not per repository", errors.New("failed to create destination directory"), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := warnNoAssetErrors(io.Discard, "download", tt.err)
+			var repos []string
+			for _, repoErr := range ghdownloader.RepoErrors(err) {
+				repos = append(repos, repoErr.Repo)
+			}
+			if strings.Join(repos, ",") != strings.Join(tt.repos, ",") {
+				t.Errorf("left errors for %q, want %q", repos, tt.repos)
+			}
+			if tt.name == "not per repository" && err != tt.err {
+				t.Errorf("err = %v, want it unchanged", err)
+			}
+		})
+	}
+}
+
+func TestCheckFlagConflicts(t *testing.T) {
+	tests := []struct {
+		set     []string
+		wantErr string
+	}{
+		{set: []string{"repo", "update"}},
+		{set: []string{"repo", "watch", "interval"}},
+		{set: []string{"config", "dry-run"}},
+		{set: []string{"update", "watch"}, wantErr: "-watch can't be combined with -update"},
+		{set: []string{"check-update", "watch"}, wantErr: "-watch can't be combined with -check-update"},
+		{set: []string{"mirror", "config"}, wantErr: "-mirror can't be combined with -config"},
+		{set: []string{"config", "releases"}, wantErr: "-releases can't be combined with -config"},
+		{set: []string{"dry-run", "releases"}, wantErr: "-dry-run can't be combined with -releases"},
+		{set: []string{"update", "from-manifest"}, wantErr: "-update can't be combined with -from-manifest"},
+	}
+	for _, tt := range tests {
+		set := make(map[string]bool)
+		for _, name := range tt.set {
+			set[name] = true
+		}
+		err := checkFlagConflicts(set)
+		if tt.wantErr == "" && err != nil || tt.wantErr != "" && (err == nil || err.Error() != tt.wantErr) {
+			t.Errorf("checkFlagConflicts(%v) = %v, want %q", tt.set, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package ghdownloader
+
+// Default concurrency limits, chosen to keep well within GitHub's secondary
+// rate limits while still saturating a typical connection.
+const (
+	DefaultMaxConcurrentRepos  = 8
+	DefaultMaxConcurrentAssets = 4
+)
+
+// SetMaxConcurrentRepos limits how many repositories are processed at once.
+// Values below 1 are treated as 1.
+func (d *Downloader) SetMaxConcurrentRepos(n int) {
+	d.maxConcurrentRepos = max(n, 1)
+}
+
+// SetMaxConcurrentAssets limits how many assets of a single release are
+// downloaded at once. Values below 1 are treated as 1, which downloads
+// assets one after another.
+func (d *Downloader) SetMaxConcurrentAssets(n int) {
+	d.maxConcurrentAssets = max(n, 1)
+}
+
+// semaphore limits the number of goroutines doing work at once.
+type semaphore chan struct{}
+
+func newSemaphore(n int) semaphore {
+	return make(semaphore, max(n, 1))
+}
+
+func (s semaphore) acquire() { s <- struct{}{} }
+func (s semaphore) release() { <-s }
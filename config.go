@@ -0,0 +1,215 @@
+package ghdownloader
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config lists the repositories to download along with per-repository
+// options, as an alternative to one -repo flag per repository.
+type Config struct {
+	// Dest is the download directory. It is only used by callers that
+	// construct the Downloader from the config.
+	Dest  string       `yaml:"dest,omitempty"`
+	Repos []RepoConfig `yaml:"repos"`
+}
+
+// RepoConfig configures the download of one repository. Unset options fall
+// back to the Downloader's global settings.
+type RepoConfig struct {
+	// Repo is "owner/repo", or the URL of a GitLab or Gitea repository as
+	// accepted by RegisterRepoURL.
+	Repo string `yaml:"repo"`
+	// Tag pins the release to download, or is a version constraint such
+	// as "^1.4"; empty or "latest" downloads the latest release.
+	Tag string `yaml:"tag,omitempty"`
+	// Match is a substring asset names must contain.
+	Match   string `yaml:"match,omitempty"`
+	Extract *bool  `yaml:"extract,omitempty"`
+	// Dest is a subdirectory of the download directory to save the
+	// repository's version directories and links in.
+	Dest string `yaml:"dest,omitempty"`
+}
+
+// LoadConfig reads a config from a YAML (or JSON) file.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config '%s': %v", path, err)
+	}
+	var config Config
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse config '%s': %v", path, err)
+	}
+	for _, repo := range config.Repos {
+		if err := validateRepo(repo.Repo); err != nil {
+			return nil, fmt.Errorf("invalid repo '%s' in config '%s': %v", repo.Repo, path, err)
+		}
+		if repo.Dest != "" && !filepath.IsLocal(repo.Dest) {
+			return nil, fmt.Errorf("invalid dest '%s' for '%s' in config '%s': must be a relative path inside the download directory", repo.Dest, repo.Repo, path)
+		}
+	}
+	return &config, nil
+}
+
+// SetRepoDestDir saves the version directories and links of a single
+// "owner/repo" in subdir, relative to the download directory.
+func (d *Downloader) SetRepoDestDir(userRepo, subdir string) {
+	d.repoDest[strings.ToLower(userRepo)] = subdir
+}
+
+// DownloadFromConfig applies the per-repository options of config and
+// downloads each repository's release as DownloadLatestReleases does.
+func (d *Downloader) DownloadFromConfig(config *Config) ([]string, error) {
+	return d.DownloadFromConfigContext(context.Background(), config)
+}
+
+// DownloadFromConfigContext is like DownloadFromConfig but honors
+// cancellation of ctx.
+func (d *Downloader) DownloadFromConfigContext(ctx context.Context, config *Config) ([]string, error) {
+	if err := d.RegisterConfigURLs(config); err != nil {
+		return nil, err
+	}
+	return d.DownloadLatestReleasesContext(ctx, d.ApplyConfig(config))
+}
+
+// RegisterConfigURLs sets up the providers of the repositories config lists
+// by URL, as RegisterRepoURL does, and replaces their Repo with the
+// "owner/repo" to download them with.
+func (d *Downloader) RegisterConfigURLs(config *Config) error {
+	for i, repo := range config.Repos {
+		if !strings.Contains(repo.Repo, "://") {
+			continue
+		}
+		spec, err := d.RegisterRepoURL(repo.Repo)
+		if err != nil {
+			return err
+		}
+		userRepo, tag, _ := strings.Cut(spec, "@")
+		config.Repos[i].Repo = userRepo
+		if config.Repos[i].Tag == "" {
+			config.Repos[i].Tag = tag
+		}
+	}
+	return nil
+}
+
+// ApplyConfig registers the per-repository options of config and returns
+// the repositories it lists as "owner/repo" or "owner/repo@tag" specs, for
+// use with DownloadLatestReleases or ListLatestReleaseAssets. Repositories
+// given by URL must be registered with RegisterConfigURLs first.
+func (d *Downloader) ApplyConfig(config *Config) []string {
+	specs := make([]string, len(config.Repos))
+	for i, repo := range config.Repos {
+		specs[i] = repo.Repo
+		if repo.Tag != "" && repo.Tag != "latest" {
+			specs[i] += "@" + repo.Tag
+		}
+		if repo.Match != "" {
+			d.SetRepoMatchFilter(repo.Repo, repo.Match)
+		}
+		if repo.Extract != nil {
+			d.SetRepoExtract(repo.Repo, *repo.Extract)
+		}
+		if repo.Dest != "" {
+			d.SetRepoDestDir(repo.Repo, repo.Dest)
+		}
+	}
+	return specs
+}
+
+// RewriteConfigRepos updates the repositories of the config file at path
+// that moved to their new location, as reported by MovedRepos, leaving the
+// rest of the file, comments and formatting included, as it is. The file is
+// replaced atomically, and only if anything changed, which it reports.
+func RewriteConfigRepos(path string, moved map[string]string) (bool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false, fmt.Errorf("failed to read config '%s': %v", path, err)
+	}
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return false, fmt.Errorf("failed to parse config '%s': %v", path, err)
+	}
+	to := make(map[string]string, len(moved))
+	for from, repo := range moved {
+		to[strings.ToLower(from)] = repo
+	}
+
+	// Values are replaced where they appear in the file, which holds for
+	// JSON as well as YAML, from the end so earlier positions stay valid.
+	lines := strings.SplitAfter(string(data), "\n")
+	nodes := configRepoNodes(&root)
+	changed := false
+	for i := len(nodes) - 1; i >= 0; i-- {
+		node := nodes[i]
+		repo, ok := to[strings.ToLower(node.Value)]
+		if !ok || node.Line < 1 || node.Line > len(lines) {
+			continue
+		}
+		line := lines[node.Line-1]
+		start := node.Column - 1
+		if node.Style&(yaml.DoubleQuotedStyle|yaml.SingleQuotedStyle) != 0 {
+			start++
+		}
+		if start < 0 || !strings.HasPrefix(line[min(start, len(line)):], node.Value) {
+			return false, fmt.Errorf("failed to rewrite config '%s': unexpected formatting of '%s'", path, node.Value)
+		}
+		lines[node.Line-1] = line[:start] + repo + line[start+len(node.Value):]
+		changed = true
+	}
+	if !changed {
+		return false, nil
+	}
+
+	perm := os.FileMode(0644)
+	if info, err := os.Stat(path); err == nil {
+		perm = info.Mode().Perm()
+	}
+	err = writeFileAtomic(path, perm, func(w io.Writer) error {
+		_, err := io.WriteString(w, strings.Join(lines, ""))
+		return err
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to write config '%s': %v", path, err)
+	}
+	return true, nil
+}
+
+// configRepoNodes returns the "repo" value nodes of the repositories listed
+// in a parsed config document.
+func configRepoNodes(root *yaml.Node) []*yaml.Node {
+	if root.Kind != yaml.DocumentNode || len(root.Content) == 0 {
+		return nil
+	}
+	repos := mappingValue(root.Content[0], "repos")
+	if repos == nil || repos.Kind != yaml.SequenceNode {
+		return nil
+	}
+	var nodes []*yaml.Node
+	for _, entry := range repos.Content {
+		if repo := mappingValue(entry, "repo"); repo != nil && repo.Kind == yaml.ScalarNode {
+			nodes = append(nodes, repo)
+		}
+	}
+	return nodes
+}
+
+// mappingValue returns the value of key in the mapping node, or nil.
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
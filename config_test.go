@@ -0,0 +1,186 @@
+package ghdownloader
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeConfig writes data to a config file in a temporary directory and
+// returns its path.
+func writeConfig(t *testing.T, data string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr string
+	}{
+		{name: "owner/repo", data: "repos:\n  - repo: owner/tool\n    dest: tools\n"},
+		{name: "URL", data: "repos:\n  - repo: https://gitlab.com/group/tool\n"},
+		{name: "missing owner", data: "repos:\n  - repo: tool\n", wantErr: "invalid repo 'tool'"},
+		{name: "invalid URL", data: "repos:\n  - repo: ftp://gitlab.com/group/tool\n", wantErr: "invalid repository URL"},
+		{name: "dest outside", data: "repos:\n  - repo: owner/tool\n    dest: ../tools\n", wantErr: "invalid dest"},
+		{name: "not YAML", data: "repos: [", wantErr: "failed to parse"},
+	}
+	for _, tt := range tests {
+		_, err := LoadConfig(writeConfig(t, tt.data))
+		if tt.wantErr == "" {
+			if err != nil {
+				t.Errorf("%s: %v", tt.name, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: %v, want an error containing %q", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestRegisterConfigURLs(t *testing.T) {
+	config, err := LoadConfig(writeConfig(t, `repos:
+  - repo: https://gitlab.com/group/tool@v1.2.0
+  - repo: https://gitea.com/owner/cli
+    tag: ^2.0
+  - repo: owner/other
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := newTestDownloader(t)
+	if err := d.RegisterConfigURLs(config); err != nil {
+		t.Fatal(err)
+	}
+	specs := d.ApplyConfig(config)
+	want := []string{"group/tool@v1.2.0", "owner/cli@^2.0", "owner/other"}
+	if strings.Join(specs, " ") != strings.Join(want, " ") {
+		t.Errorf("specs = %v, want %v", specs, want)
+	}
+	if _, ok := d.provider("group", "tool").(*gitlabProvider); !ok {
+		t.Error("group/tool isn't served by GitLab")
+	}
+	if _, ok := d.provider("owner", "cli").(*giteaProvider); !ok {
+		t.Error("owner/cli isn't served by Gitea")
+	}
+}
+
+func TestDownloadFromConfigRepoOptions(t *testing.T) {
+	tarball, err := os.ReadFile(writeTarGz(t, t.TempDir(), "archive.tar.gz", []archiveEntry{{name: "bin", mode: 0755, data: "bin"}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := newFakeGitHub(t)
+	g.addRelease("owner/tool", "v1.0.0", map[string]string{"tool-linux": "v1", "tool-darwin": "v1"})
+	g.addRelease("owner/tool", "v2.0.0", map[string]string{"tool-linux": "v2", "tool-darwin": "v2"})
+	g.addRelease("owner/packed", "v1.0.0", map[string]string{"packed-linux.tar.gz": string(tarball)})
+	g.addRelease("owner/kept", "v1.0.0", map[string]string{"kept-linux.tar.gz": string(tarball)})
+	config, err := LoadConfig(writeConfig(t, `repos:
+  - repo: owner/tool
+    tag: v1.0.0
+    match: darwin
+    dest: tools
+  - repo: owner/packed
+  - repo: owner/kept
+    extract: false
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d := g.downloader(t)
+	d.SetMatchFilter("linux")
+	d.SetExtract(true)
+	if _, err := d.DownloadFromConfig(config); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string][]string{
+		filepath.Join("tools", "tool-v1.0.0"): {"tool-darwin"},
+		"packed-v1.0.0":                       {"bin", "packed-linux.tar.gz"},
+		"kept-v1.0.0":                         {"kept-linux.tar.gz"},
+	}
+	for dir, names := range want {
+		if got := listDir(t, filepath.Join(d.destDir, dir)); strings.Join(got, " ") != strings.Join(names, " ") {
+			t.Errorf("%s holds %q, want %q", dir, got, names)
+		}
+	}
+}
+
+func TestRewriteConfigRepos(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{
+			name: "YAML",
+			data: "# tools\nrepos:\n  - repo: old/tool # the CLI\n    tag: v1\n  - repo: \"Old/Lib\"\n  - repo: owner/other\n",
+			want: "# tools\nrepos:\n  - repo: new/tool # the CLI\n    tag: v1\n  - repo: \"new/lib\"\n  - repo: owner/other\n",
+		},
+		{
+			name: "JSON",
+			data: `{"repos": [{"repo": "old/tool"}, {"repo": "old/lib", "tag": "v2"}]}`,
+			want: `{"repos": [{"repo": "new/tool"}, {"repo": "new/lib", "tag": "v2"}]}`,
+		},
+	}
+	moved := map[string]string{"old/tool": "new/tool", "old/lib": "new/lib"}
+	for _, tt := range tests {
+		path := writeConfig(t, tt.data)
+		changed, err := RewriteConfigRepos(path, moved)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		data, _ := os.ReadFile(path)
+		if !changed || string(data) != tt.want {
+			t.Errorf("%s: rewrote to %q (changed %v), want %q", tt.name, data, changed, tt.want)
+		}
+		if _, err := LoadConfig(path); err != nil {
+			t.Errorf("%s: rewritten config: %v", tt.name, err)
+		}
+	}
+
+	path := writeConfig(t, "repos:\n  - repo: owner/other\n")
+	if changed, err := RewriteConfigRepos(path, moved); changed || err != nil {
+		t.Errorf("config without moved repositories: changed %v, %v", changed, err)
+	}
+	if _, err := os.Stat(path + partialSuffix); err == nil {
+		t.Error("a partial config was left behind")
+	}
+}
+
+func TestDownloadFromConfigFollowsMovedRepos(t *testing.T) {
+	g := newFakeGitHub(t)
+	g.addRelease("new/tool", "v1.0.0", map[string]string{"tool": "bin"})
+	g.repos["old/tool"] = g.repos["new/tool"]
+	path := writeConfig(t, "repos:\n  - repo: old/tool\n")
+	config, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d := g.downloader(t)
+	paths, err := d.DownloadFromConfig(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) != 1 || paths[0] != filepath.Join(d.destDir, "tool-v1.0.0", "tool") {
+		t.Errorf("downloaded %v", paths)
+	}
+	moved := d.MovedRepos()
+	if moved["old/tool"] != "new/tool" {
+		t.Fatalf("MovedRepos() = %v", moved)
+	}
+	if _, err := RewriteConfigRepos(path, moved); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "repos:\n  - repo: new/tool\n" {
+		t.Errorf("rewrote config to %q", data)
+	}
+}
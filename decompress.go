@@ -0,0 +1,59 @@
+package ghdownloader
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// SetDecompressGzip enables decompressing bare gzip assets ("tool.gz", but not
+// "tool.tar.gz") to the name without the ".gz" suffix, marked executable.
+func (d *Downloader) SetDecompressGzip(decompress bool) {
+	d.decompressGzip = decompress
+}
+
+// isBareGzip reports whether name is a gzip-compressed single file rather
+// than a compressed tarball.
+func isBareGzip(name string) bool {
+	lower := strings.ToLower(name)
+	return strings.HasSuffix(lower, ".gz") && !strings.HasSuffix(lower, ".tar.gz")
+}
+
+// savedPath returns where an asset named name is kept in dir, which differs
+// from its download path for bare gzip assets that are decompressed.
+func (d *Downloader) savedPath(dir, name string) string {
+	filePath := filepath.Join(dir, name)
+	if d.decompressGzip && isBareGzip(name) {
+		return strings.TrimSuffix(filePath, filepath.Ext(filePath))
+	}
+	return filePath
+}
+
+// gunzipFile decompresses src to dst and removes src on success.
+func gunzipFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("failed to open '%s': %v", src, err)
+	}
+	defer in.Close()
+
+	zr, err := gzip.NewReader(in)
+	if err != nil {
+		return fmt.Errorf("failed to read gzip header of '%s': %v", src, err)
+	}
+	defer zr.Close()
+
+	err = writeFileAtomic(dst, 0755, func(out io.Writer) error {
+		_, err := io.Copy(out, zr)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to decompress '%s': %v", src, err)
+	}
+
+	in.Close()
+	return os.Remove(src)
+}
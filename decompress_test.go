@@ -0,0 +1,90 @@
+package ghdownloader
+
+import (
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"testing"
+)
+
+// gzipData returns data compressed with gzip.
+func gzipData(t *testing.T, data string) string {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write([]byte(data)); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func TestDecompressGzip(t *testing.T) {
+	g := newFakeGitHub(t)
+	g.addRelease("owner/tool", "v1.0.0", map[string]string{"tool.gz": gzipData(t, "bin"), "tool.tar.gz": "tarball"})
+	d := g.downloader(t)
+	d.SetDecompressGzip(true)
+
+	paths, err := d.DownloadLatestReleases([]string{"owner/tool"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := filepath.Join(d.destDir, "tool-v1.0.0")
+	if names := listDir(t, dir); !slices.Equal(names, []string{"tool", "tool.tar.gz"}) {
+		t.Fatalf("saved %q, want the decompressed tool and the untouched tarball", names)
+	}
+	if !slices.Contains(paths, filepath.Join(dir, "tool")) {
+		t.Errorf("reported %v, want the decompressed path", paths)
+	}
+	tool := filepath.Join(dir, "tool")
+	if data, err := os.ReadFile(tool); err != nil || string(data) != "bin" {
+		t.Errorf("decompressed %q, %v", data, err)
+	}
+	if info, err := os.Stat(tool); err != nil || (runtime.GOOS != "windows" && info.Mode().Perm()&0111 == 0) {
+		t.Errorf("decompressed tool isn't executable: %v, %v", info, err)
+	}
+
+	// The decompressed file counts as the downloaded asset.
+	served := len(g.served())
+	if _, err := d.DownloadLatestReleases([]string{"owner/tool"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range g.served()[served:] {
+		if filepath.Base(filepath.Dir(path)) == "assets" {
+			t.Errorf("downloaded '%s' again", path)
+		}
+	}
+}
+
+func TestDecompressGzipDisabled(t *testing.T) {
+	g := newFakeGitHub(t)
+	g.addRelease("owner/tool", "v1.0.0", map[string]string{"tool.gz": gzipData(t, "bin")})
+	d := g.downloader(t)
+	if _, err := d.DownloadLatestReleases([]string{"owner/tool"}); err != nil {
+		t.Fatal(err)
+	}
+	if names := listDir(t, filepath.Join(d.destDir, "tool-v1.0.0")); !slices.Equal(names, []string{"tool.gz"}) {
+		t.Errorf("saved %q, want the compressed asset", names)
+	}
+}
+
+func TestDecompressGzipCorrupt(t *testing.T) {
+	g := newFakeGitHub(t)
+	g.addRelease("owner/tool", "v1.0.0", map[string]string{"tool.gz": "not gzip"})
+	d := g.downloader(t)
+	d.SetDecompressGzip(true)
+	if _, err := d.DownloadLatestReleases([]string{"owner/tool"}); err != nil {
+		t.Fatal(err)
+	}
+	if results := d.Results(); len(results) != 1 || results[0].Err == nil {
+		t.Errorf("results = %+v, want a failed decompression", results)
+	}
+	if _, err := os.Stat(filepath.Join(d.destDir, "tool-v1.0.0", "tool")); err == nil {
+		t.Error("a corrupt asset was decompressed")
+	}
+}
@@ -0,0 +1,139 @@
+package ghdownloader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AssetInfo describes a release asset that would be downloaded.
+type AssetInfo struct {
+	Owner     string
+	Repo      string
+	Tag       string
+	Name      string
+	Size      int64
+	UpdatedAt time.Time
+	// Path is where the asset would be saved.
+	Path string
+	// Exists is set when the file is already present, so its download
+	// would be skipped.
+	Exists bool
+}
+
+// ListLatestReleaseAssets returns the assets DownloadLatestReleases would
+// download for userRepos, in order, without downloading anything.
+func (d *Downloader) ListLatestReleaseAssets(userRepos []string) ([]AssetInfo, error) {
+	return d.ListLatestReleaseAssetsContext(context.Background(), userRepos)
+}
+
+// ListLatestReleaseAssetsContext is like ListLatestReleaseAssets but honors
+// cancellation of ctx.
+func (d *Downloader) ListLatestReleaseAssetsContext(ctx context.Context, userRepos []string) ([]AssetInfo, error) {
+	ctx = d.rateLimitContext(ctx)
+
+	listed := make([][]AssetInfo, len(userRepos))
+	errs := make([]*RepoError, len(userRepos))
+	sem := newSemaphore(d.maxConcurrentRepos)
+	var wg sync.WaitGroup
+	for i, userRepo := range userRepos {
+		owner, repo, tag, err := parseRepoSpec(userRepo)
+		if err != nil {
+			return nil, fmt.Errorf("invalid user/repo format '%s': %v", userRepo, err)
+		}
+
+		wg.Add(1)
+		go func(i int, owner, repo, tag string) {
+			defer wg.Done()
+			sem.acquire()
+			defer sem.release()
+			var err error
+			if listed[i], err = d.listReleaseAssets(ctx, owner, repo, tag); err != nil {
+				errs[i] = d.repoError(owner, repo, err)
+			}
+		}(i, owner, repo, tag)
+	}
+	wg.Wait()
+
+	var assets []AssetInfo
+	var failed []*RepoError
+	for i := range userRepos {
+		assets = append(assets, listed[i]...)
+		if errs[i] != nil {
+			failed = append(failed, errs[i])
+		}
+	}
+	return assets, joinRepoErrors("list", failed)
+}
+
+// listReleaseAssets returns the assets downloadRelease would download for
+// owner/repo at tag.
+func (d *Downloader) listReleaseAssets(ctx context.Context, owner, repo, tag string) ([]AssetInfo, error) {
+	key := strings.ToLower(owner + "/" + repo)
+	owner, repo, _, err := d.resolveRepo(ctx, owner, repo)
+	if err != nil {
+		return nil, err
+	}
+	release, err := d.fetchRelease(ctx, owner, repo, tag)
+	if err != nil {
+		// Repositories found by ExpandOwner would be skipped.
+		if d.isExpanded(key) && (errors.Is(err, ErrNotFound) || errors.Is(err, errNoReleases)) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	// Releases without a tag are always downloaded again.
+	tag = release.GetTagName()
+	force := tag == ""
+	if force {
+		tag = "latest"
+	}
+	versionDir, err := d.versionDir(key, owner, repo, tag)
+	if err != nil {
+		return nil, err
+	}
+	info := func(name, path string, size int64, updated time.Time) AssetInfo {
+		_, statErr := os.Stat(path)
+		return AssetInfo{Owner: owner, Repo: repo, Tag: tag, Name: name, Size: size, UpdatedAt: updated,
+			Path: path, Exists: !force && statErr == nil}
+	}
+
+	selection := d.selectAssets(ctx, key, owner, repo, release)
+	// Repositories found by ExpandOwner would be skipped.
+	if selection.empty() && !d.isExpanded(key) {
+		return nil, fmt.Errorf("%w matching the filters in release '%s'", ErrNoAssets, tag)
+	}
+	var assets []AssetInfo
+	if selection.source {
+		name := sourceName(repo, tag)
+		assets = append(assets, info(name, filepath.Join(versionDir, name), 0, release.GetPublishedAt().Time))
+	}
+	names := make([]string, 0, len(selection.parts))
+	for name := range selection.parts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		parts := selection.parts[name]
+		var size int64
+		var updated time.Time
+		for _, part := range parts {
+			size += int64(part.asset.GetSize())
+			if t := part.asset.GetUpdatedAt().Time; t.After(updated) {
+				updated = t
+			}
+		}
+		assets = append(assets, info(name, filepath.Join(versionDir, name), size, updated))
+	}
+	for _, asset := range selection.assets {
+		assets = append(assets, info(asset.GetName(), d.savedPath(versionDir, asset.GetName()), int64(asset.GetSize()), asset.GetUpdatedAt().Time))
+	}
+	return assets, nil
+}
@@ -0,0 +1,89 @@
+package ghdownloader
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestListLatestReleaseAssets(t *testing.T) {
+	g := newFakeGitHub(t)
+	g.addRelease("owner/tool", "v1.0.0", map[string]string{"tool-linux": "linux", "tool-darwin": "darwin", "tool.gz": "gz"})
+	g.addRelease("owner/other", "v2.0.0", map[string]string{"other-linux": "other"})
+	d := g.downloader(t)
+	d.SetMatchFilter("linux")
+
+	assets, err := d.ListLatestReleaseAssets([]string{"owner/tool", "owner/other"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []AssetInfo{
+		{Owner: "owner", Repo: "tool", Tag: "v1.0.0", Name: "tool-linux", Size: 5, Path: filepath.Join(d.destDir, "tool-v1.0.0", "tool-linux")},
+		{Owner: "owner", Repo: "other", Tag: "v2.0.0", Name: "other-linux", Size: 5, Path: filepath.Join(d.destDir, "other-v2.0.0", "other-linux")},
+	}
+	if len(assets) != len(want) {
+		t.Fatalf("listed %+v, want %+v", assets, want)
+	}
+	for i, asset := range assets {
+		asset.UpdatedAt = want[i].UpdatedAt
+		if asset != want[i] {
+			t.Errorf("listed %+v, want %+v", asset, want[i])
+		}
+	}
+	for _, path := range g.served() {
+		if strings.Contains(path, "/releases/assets/") {
+			t.Errorf("the dry run downloaded '%s'", path)
+		}
+	}
+	if names := listDir(t, d.destDir); len(names) != 0 {
+		t.Errorf("the dry run created %q", names)
+	}
+
+	// Assets already downloaded would be skipped.
+	if _, err := d.DownloadLatestReleases([]string{"owner/tool"}); err != nil {
+		t.Fatal(err)
+	}
+	assets, err = d.ListLatestReleaseAssets([]string{"owner/tool", "owner/other"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(assets) != 2 || !assets[0].Exists || assets[1].Exists {
+		t.Errorf("listed %+v, want only tool-linux to exist", assets)
+	}
+
+	// Decompressed assets are listed where they would be saved.
+	d.SetMatchFilter("")
+	d.SetDecompressGzip(true)
+	assets, err = d.ListLatestReleaseAssets([]string{"owner/tool"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var found bool
+	for _, asset := range assets {
+		if asset.Name == "tool.gz" {
+			found = asset.Path == filepath.Join(d.destDir, "tool-v1.0.0", "tool")
+		}
+	}
+	if !found {
+		t.Errorf("listed %+v, want tool.gz saved as tool", assets)
+	}
+}
+
+func TestListLatestReleaseAssetsErrors(t *testing.T) {
+	g := newFakeGitHub(t)
+	g.addRelease("owner/tool", "v1.0.0", map[string]string{"tool-linux": "linux"})
+	d := g.downloader(t)
+	d.SetMatchFilter("darwin")
+
+	assets, err := d.ListLatestReleaseAssets([]string{"owner/tool", "owner/missing"})
+	if len(assets) != 0 {
+		t.Errorf("listed %+v", assets)
+	}
+	if !errors.Is(err, ErrNoAssets) || !errors.Is(err, ErrNotFound) {
+		t.Errorf("ListLatestReleaseAssets: %v, want errors for both repositories", err)
+	}
+	if _, err := d.ListLatestReleaseAssets([]string{"tool"}); err == nil {
+		t.Error("expected an error for a repository without an owner")
+	}
+}
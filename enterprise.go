@@ -0,0 +1,55 @@
+package ghdownloader
+
+import (
+	"fmt"
+	"net/url"
+)
+
+// Options configures a Downloader created with NewWithOptions.
+type Options struct {
+	// Token is a GitHub token; it defaults to the GITHUB_TOKEN environment
+	// variable.
+	Token string
+	// DestDir is the directory where binaries will be saved.
+	DestDir string
+	// BaseURL is the URL of a GitHub Enterprise Server instance, such as
+	// "https://github.example.com" (the "/api/v3/" suffix is added if
+	// missing). Empty means github.com.
+	BaseURL string
+	// UploadURL is the upload URL of the instance; it defaults to BaseURL.
+	UploadURL string
+}
+
+// NewWithOptions creates a new Downloader configured by opts.
+func NewWithOptions(opts Options) (*Downloader, error) {
+	d := New(opts.Token, opts.DestDir)
+	if opts.BaseURL != "" {
+		if err := d.SetBaseURL(opts.BaseURL, opts.UploadURL); err != nil {
+			return nil, err
+		}
+	}
+	return d, nil
+}
+
+// SetBaseURL points the downloader at a GitHub Enterprise Server instance.
+// apiURL may be the instance URL or its API URL; uploadURL defaults to
+// apiURL. Asset downloads use the asset URLs the instance reports, so they
+// follow it automatically.
+func (d *Downloader) SetBaseURL(apiURL, uploadURL string) error {
+	if uploadURL == "" {
+		uploadURL = apiURL
+	}
+	for _, raw := range []string{apiURL, uploadURL} {
+		u, err := url.Parse(raw)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid GitHub Enterprise URL '%s': expected 'https://host'", raw)
+		}
+	}
+
+	client, err := d.client.WithEnterpriseURLs(apiURL, uploadURL)
+	if err != nil {
+		return fmt.Errorf("invalid GitHub Enterprise URL '%s': %v", apiURL, err)
+	}
+	d.client = client
+	return nil
+}
@@ -0,0 +1,104 @@
+package ghdownloader
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/google/go-github/v68/github"
+)
+
+var (
+	// ErrNoAssets is wrapped by errors for releases that have no assets, or
+	// none matching the filters.
+	ErrNoAssets = errors.New("no assets found")
+	// ErrRateLimited is wrapped by errors for requests refused because a
+	// rate limit was exceeded, once retries are exhausted.
+	ErrRateLimited = errors.New("rate limited")
+)
+
+// RepoError is the error of a single repository. Calls that handle several
+// repositories return an error joining one RepoError per failed repository,
+// so errors.As finds the first of them and errors.Is matches the errors of
+// any; RepoErrors lists them all.
+type RepoError struct {
+	Owner string
+	Repo  string
+	Err   error
+}
+
+func (e *RepoError) Error() string {
+	return e.Owner + "/" + e.Repo + ": " + e.Err.Error()
+}
+
+func (e *RepoError) Unwrap() error {
+	return e.Err
+}
+
+// RepoErrors returns every RepoError in the tree of err, in order.
+func RepoErrors(err error) []*RepoError {
+	var repoErrs []*RepoError
+	var walk func(error)
+	walk = func(err error) {
+		if repoErr, ok := err.(*RepoError); ok {
+			repoErrs = append(repoErrs, repoErr)
+			return
+		}
+		switch u := err.(type) {
+		case interface{ Unwrap() error }:
+			if next := u.Unwrap(); next != nil {
+				walk(next)
+			}
+		case interface{ Unwrap() []error }:
+			for _, next := range u.Unwrap() {
+				walk(next)
+			}
+		}
+	}
+	if err != nil {
+		walk(err)
+	}
+	return repoErrs
+}
+
+// repoError returns err of owner/repo as a RepoError, with credentials
+// scrubbed and rate limit errors of the GitHub client matching
+// ErrRateLimited.
+func (d *Downloader) repoError(owner, repo string, err error) *RepoError {
+	var rateErr *github.RateLimitError
+	var abuseErr *github.AbuseRateLimitError
+	if errors.As(err, &rateErr) || errors.As(err, &abuseErr) {
+		err = &rateLimitError{err: err}
+	}
+	return &RepoError{Owner: owner, Repo: repo, Err: d.redactError(err)}
+}
+
+// rateLimitError marks a rate limit error of the GitHub client as matching
+// ErrRateLimited, without changing its message.
+type rateLimitError struct {
+	err error
+}
+
+func (e *rateLimitError) Error() string { return e.err.Error() }
+
+func (e *rateLimitError) Unwrap() error { return e.err }
+
+func (e *rateLimitError) Is(target error) bool { return target == ErrRateLimited }
+
+// joinRepoErrors combines the errors of the repositories handled by one call,
+// sorted by repository and each described as "failed to <action>
+// owner/repo: ...". It returns nil if there are none.
+func joinRepoErrors(action string, repoErrs []*RepoError) error {
+	if len(repoErrs) == 0 {
+		return nil
+	}
+	sort.Slice(repoErrs, func(i, j int) bool {
+		return strings.ToLower(repoErrs[i].Owner+"/"+repoErrs[i].Repo) < strings.ToLower(repoErrs[j].Owner+"/"+repoErrs[j].Repo)
+	})
+	errs := make([]error, len(repoErrs))
+	for i, repoErr := range repoErrs {
+		errs[i] = fmt.Errorf("failed to %s %w", action, repoErr)
+	}
+	return fmt.Errorf("errors occurred:\n%w", errors.Join(errs...))
+}
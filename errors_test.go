@@ -0,0 +1,88 @@
+package ghdownloader
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/google/go-github/v68/github"
+)
+
+func TestRepoErrors(t *testing.T) {
+	calls := map[string]func(d *Downloader, repos []string) error{
+		"DownloadLatestReleases": func(d *Downloader, repos []string) error {
+			_, err := d.DownloadLatestReleases(repos)
+			return err
+		},
+		"ListLatestReleaseAssets": func(d *Downloader, repos []string) error {
+			_, err := d.ListLatestReleaseAssets(repos)
+			return err
+		},
+		"CheckForUpdates": func(d *Downloader, repos []string) error {
+			_, err := d.CheckForUpdates(repos)
+			return err
+		},
+	}
+	for name, call := range calls {
+		t.Run(name, func(t *testing.T) {
+			d := newTestDownloader(t)
+			p := &fakeProvider{}
+			d.SetProvider(p)
+			asset := p.newFakeAsset("tool.tar.gz", []byte("data"))
+			p.releases = map[string]*github.RepositoryRelease{
+				"owner/tool": {TagName: github.String("v1.0.0"), Assets: []*github.ReleaseAsset{asset}},
+			}
+			d.SetMatchFilter("windows")
+
+			err := call(d, []string{"owner/tool", "owner/missing"})
+			repoErrs := RepoErrors(err)
+			// CheckForUpdates doesn't look at assets.
+			wantRepos := []string{"missing", "tool"}
+			if name == "CheckForUpdates" {
+				wantRepos = []string{"missing"}
+			}
+			if len(repoErrs) != len(wantRepos) {
+				t.Fatalf("RepoErrors = %v, want %d errors", repoErrs, len(wantRepos))
+			}
+			for i, repoErr := range repoErrs {
+				if repoErr.Owner != "owner" || repoErr.Repo != wantRepos[i] {
+					t.Errorf("error %d is for %s/%s, want owner/%s", i, repoErr.Owner, repoErr.Repo, wantRepos[i])
+				}
+			}
+			if !errors.Is(repoErrs[0], ErrNotFound) || errors.Is(repoErrs[0], ErrNoAssets) {
+				t.Errorf("error for owner/missing = %v, want ErrNotFound", repoErrs[0])
+			}
+			if len(repoErrs) > 1 && (!errors.Is(repoErrs[1], ErrNoAssets) || errors.Is(repoErrs[1], ErrNotFound)) {
+				t.Errorf("error for owner/tool = %v, want ErrNoAssets", repoErrs[1])
+			}
+			if !strings.HasPrefix(err.Error(), "errors occurred:\n") {
+				t.Errorf("message = %q", err)
+			}
+		})
+	}
+}
+
+func TestRepoErrorRateLimited(t *testing.T) {
+	d := newTestDownloader(t)
+	u, _ := url.Parse("https://api.github.com/repos/owner/tool")
+	resp := &http.Response{StatusCode: http.StatusForbidden, Request: &http.Request{Method: "GET", URL: u}}
+	err := d.repoError("owner", "tool", &github.AbuseRateLimitError{Response: resp, Message: "secondary rate limit"})
+	if !errors.Is(err, ErrRateLimited) {
+		t.Errorf("%v doesn't match ErrRateLimited", err)
+	}
+	if err := d.repoError("owner", "tool", context.Canceled); errors.Is(err, ErrRateLimited) {
+		t.Errorf("%v matches ErrRateLimited", err)
+	}
+}
+
+func TestRepoErrorsNil(t *testing.T) {
+	if repoErrs := RepoErrors(nil); repoErrs != nil {
+		t.Errorf("RepoErrors(nil) = %v", repoErrs)
+	}
+	if repoErrs := RepoErrors(errors.New("plain")); repoErrs != nil {
+		t.Errorf("RepoErrors(plain) = %v", repoErrs)
+	}
+}
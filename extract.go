@@ -0,0 +1,238 @@
+package ghdownloader
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/bzip2"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// SetExtract enables unpacking downloaded archives (.tar.gz, .tgz, .tar.bz2,
+// .tbz2, .tar and .zip) into the version directory. The archive is kept, and
+// the executables it contained are returned in place of its path.
+func (d *Downloader) SetExtract(extract bool) {
+	d.extract = extract
+}
+
+// SetRepoExtract enables or disables unpacking archives for a single
+// "owner/repo", overriding SetExtract.
+func (d *Downloader) SetRepoExtract(userRepo string, extract bool) {
+	d.repoExtract[strings.ToLower(userRepo)] = extract
+}
+
+// isArchive reports whether name is an archive format that can be extracted.
+func isArchive(name string) bool {
+	return archiveFormat(name) != ""
+}
+
+// archiveFormat returns "zip", "tar", "tar.gz" or "tar.bz2" for a supported
+// archive name, and "" otherwise.
+func archiveFormat(name string) string {
+	lower := strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(lower, ".zip"):
+		return "zip"
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		return "tar.gz"
+	case strings.HasSuffix(lower, ".tar.bz2"), strings.HasSuffix(lower, ".tbz2"):
+		return "tar.bz2"
+	case strings.HasSuffix(lower, ".tar"):
+		return "tar"
+	}
+	return ""
+}
+
+// unpack returns the paths a downloaded asset contributes to the results:
+// the files extracted from it if it is an archive and extraction is enabled,
+// or the asset itself. key is the "owner/repo" the asset was downloaded for.
+func (d *Downloader) unpack(key, assetPath, dir string) ([]string, error) {
+	extract, ok := d.repoExtract[key]
+	if !ok {
+		extract = d.extract
+	}
+	if !extract || !isArchive(assetPath) {
+		return []string{assetPath}, nil
+	}
+	return d.extractAsset(assetPath, dir)
+}
+
+// extractAsset unpacks the archive at archivePath into dir and returns the
+// executables it contained, or every extracted file if none is executable.
+func (d *Downloader) extractAsset(archivePath, dir string) ([]string, error) {
+	var files []string
+	var err error
+	if archiveFormat(archivePath) == "zip" {
+		files, err = extractZip(archivePath, dir)
+	} else {
+		files, err = extractTar(archivePath, dir)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract '%s': %v", archivePath, err)
+	}
+	d.infof("Extracted %d file(s) from '%s'", len(files), archivePath)
+
+	var binaries []string
+	for _, file := range files {
+		info, err := os.Lstat(file)
+		if err != nil {
+			continue
+		}
+		if info.Mode().IsRegular() && (info.Mode()&0111 != 0 || strings.HasSuffix(strings.ToLower(file), ".exe")) {
+			binaries = append(binaries, file)
+		}
+	}
+	if len(binaries) == 0 {
+		return files, nil
+	}
+	return binaries, nil
+}
+
+// extractTar unpacks a tarball, optionally gzip or bzip2 compressed, into dir.
+func extractTar(archivePath, dir string) ([]string, error) {
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	switch archiveFormat(archivePath) {
+	case "tar.gz":
+		zr, err := gzip.NewReader(f)
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		r = zr
+	case "tar.bz2":
+		r = bzip2.NewReader(f)
+	}
+
+	var files []string
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return files, nil
+		}
+		if err != nil {
+			return files, err
+		}
+
+		target, err := archiveTarget(dir, hdr.Name)
+		if err != nil {
+			return files, err
+		}
+		if target == "" {
+			continue
+		}
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return files, err
+			}
+		case tar.TypeReg:
+			if err := writeArchiveFile(target, tr, hdr.FileInfo().Mode()); err != nil {
+				return files, err
+			}
+			files = append(files, target)
+		case tar.TypeSymlink:
+			// Links may only point down the tree, so neither they nor
+			// files written through them can escape dir.
+			if path.IsAbs(hdr.Linkname) || hasDotDot(hdr.Linkname) {
+				return files, fmt.Errorf("illegal link target '%s' for '%s'", hdr.Linkname, hdr.Name)
+			}
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return files, err
+			}
+			os.Remove(target)
+			if err := os.Symlink(hdr.Linkname, target); err != nil {
+				return files, err
+			}
+			files = append(files, target)
+		}
+	}
+}
+
+// extractZip unpacks a zip archive into dir.
+func extractZip(archivePath, dir string) ([]string, error) {
+	zr, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+
+	var files []string
+	for _, zf := range zr.File {
+		target, err := archiveTarget(dir, zf.Name)
+		if err != nil {
+			return files, err
+		}
+		if target == "" {
+			continue
+		}
+		if zf.FileInfo().IsDir() {
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return files, err
+			}
+			continue
+		}
+		if !zf.Mode().IsRegular() {
+			continue
+		}
+
+		rc, err := zf.Open()
+		if err != nil {
+			return files, err
+		}
+		err = writeArchiveFile(target, rc, zf.Mode())
+		rc.Close()
+		if err != nil {
+			return files, err
+		}
+		files = append(files, target)
+	}
+	return files, nil
+}
+
+// archiveTarget returns where the archive entry name is extracted within dir,
+// rejecting entries that would escape it ("zip slip"). It returns "" for the
+// archive root itself.
+func archiveTarget(dir, name string) (string, error) {
+	name = strings.TrimSuffix(strings.TrimPrefix(name, "./"), "/")
+	if name == "" || name == "." {
+		return "", nil
+	}
+	return safeJoin(dir, name)
+}
+
+// hasDotDot reports whether the slash-separated path p has a ".." element.
+func hasDotDot(p string) bool {
+	for _, elem := range strings.Split(p, "/") {
+		if elem == ".." {
+			return true
+		}
+	}
+	return false
+}
+
+// writeArchiveFile writes r to target, keeping the permission bits of mode.
+func writeArchiveFile(target string, r io.Reader, mode os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	perm := mode.Perm()
+	if perm == 0 {
+		perm = 0644
+	}
+	return writeFileAtomic(target, perm, func(out io.Writer) error {
+		_, err := io.Copy(out, r)
+		return err
+	})
+}
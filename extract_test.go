@@ -0,0 +1,206 @@
+package ghdownloader
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// archiveEntry is a file, directory or symbolic link of a test archive.
+type archiveEntry struct {
+	name string
+	link string
+	dir  bool
+	mode int64
+	data string
+}
+
+// writeTarGz writes entries to a .tar.gz named name in dir and returns its
+// path.
+func writeTarGz(t *testing.T, dir, name string, entries []archiveEntry) string {
+	t.Helper()
+	archivePath := filepath.Join(dir, name)
+	f, err := os.Create(archivePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	zw := gzip.NewWriter(f)
+	tw := tar.NewWriter(zw)
+	for _, e := range entries {
+		hdr := &tar.Header{Name: e.name, Mode: e.mode, Typeflag: tar.TypeReg, Size: int64(len(e.data))}
+		switch {
+		case e.dir:
+			hdr.Typeflag, hdr.Mode, hdr.Size = tar.TypeDir, 0755, 0
+		case e.link != "":
+			hdr.Typeflag, hdr.Linkname, hdr.Size = tar.TypeSymlink, e.link, 0
+		}
+		if hdr.Mode == 0 {
+			hdr.Mode = 0644
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(e.data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return archivePath
+}
+
+// writeZip writes the regular files of entries to a .zip named name in dir
+// and returns its path.
+func writeZip(t *testing.T, dir, name string, entries []archiveEntry) string {
+	t.Helper()
+	archivePath := filepath.Join(dir, name)
+	f, err := os.Create(archivePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	zw := zip.NewWriter(f)
+	for _, e := range entries {
+		w, err := zw.Create(e.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(e.data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return archivePath
+}
+
+func TestExtractTarGuards(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []archiveEntry
+		wantErr bool
+		want    []string
+	}{
+		{
+			name:    "plain files",
+			entries: []archiveEntry{{name: "./tool/", dir: true}, {name: "tool/bin", data: "x"}},
+			want:    []string{"tool/bin"},
+		},
+		{
+			name:    "parent directory",
+			entries: []archiveEntry{{name: "../evil", data: "x"}},
+			wantErr: true,
+		},
+		{
+			name:    "nested parent directory",
+			entries: []archiveEntry{{name: "tool/../../evil", data: "x"}},
+			wantErr: true,
+		},
+		{
+			name:    "absolute path",
+			entries: []archiveEntry{{name: "/evil", data: "x"}},
+			wantErr: true,
+		},
+		{
+			name:    "absolute link",
+			entries: []archiveEntry{{name: "link", link: "/etc"}},
+			wantErr: true,
+		},
+		{
+			name:    "link to a parent directory",
+			entries: []archiveEntry{{name: "tool/link", link: "../.."}},
+			wantErr: true,
+		},
+		{
+			name:    "write through an escaping link",
+			entries: []archiveEntry{{name: "link", link: "../outside"}, {name: "link/evil", data: "x"}},
+			wantErr: true,
+		},
+		{
+			name:    "link down the tree",
+			entries: []archiveEntry{{name: "tool/bin", data: "x"}, {name: "bin", link: "tool/bin"}},
+			want:    []string{"tool/bin", "bin"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			dir := filepath.Join(root, "out")
+			archivePath := writeTarGz(t, root, "tool.tar.gz", tt.entries)
+
+			files, err := extractTar(archivePath, dir)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("extractTar: %v, want error %v", err, tt.wantErr)
+			}
+			for _, name := range []string{"evil", "outside"} {
+				if _, err := os.Lstat(filepath.Join(root, name)); err == nil {
+					t.Errorf("'%s' was written outside the extraction directory", name)
+				}
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(files) != len(tt.want) {
+				t.Fatalf("extracted %v, want %v", files, tt.want)
+			}
+			for i, name := range tt.want {
+				if files[i] != filepath.Join(dir, filepath.FromSlash(name)) {
+					t.Errorf("extracted %s, want %s", files[i], name)
+				}
+			}
+		})
+	}
+}
+
+func TestExtractZipGuards(t *testing.T) {
+	tests := []struct {
+		name    string
+		entry   string
+		wantErr bool
+	}{
+		{name: "plain file", entry: "tool/bin"},
+		{name: "leading dot", entry: "./bin"},
+		{name: "parent directory", entry: "../evil", wantErr: true},
+		{name: "nested parent directory", entry: "tool/../../evil", wantErr: true},
+		{name: "absolute path", entry: "/evil", wantErr: true},
+		{name: "unclean path", entry: "tool//bin", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			archivePath := writeZip(t, root, "tool.zip", []archiveEntry{{name: tt.entry, data: "x"}})
+
+			_, err := extractZip(archivePath, filepath.Join(root, "out"))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("extractZip: %v, want error %v", err, tt.wantErr)
+			}
+			if _, err := os.Lstat(filepath.Join(root, "evil")); err == nil {
+				t.Error("a file was written outside the extraction directory")
+			}
+		})
+	}
+}
+
+func TestExtractAssetPrefersExecutables(t *testing.T) {
+	d := newTestDownloader(t)
+	archivePath := writeTarGz(t, d.destDir, "tool.tar.gz", []archiveEntry{
+		{name: "README.md", data: "docs"},
+		{name: "tool", mode: 0755, data: "bin"},
+	})
+	files, err := d.extractAsset(archivePath, filepath.Join(d.destDir, "out"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || filepath.Base(files[0]) != "tool" {
+		t.Errorf("extractAsset returned %v, want only the executable", files)
+	}
+}
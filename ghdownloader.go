@@ -2,13 +2,19 @@ package ghdownloader
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"os"
 	"path/filepath"
+	"regexp"
+	"slices"
 	"strings"
 	"sync"
+	"text/template"
+	"time"
 
 	"github.com/google/go-github/v68/github"
 	"golang.org/x/oauth2"
@@ -19,37 +25,131 @@ type Downloader struct {
 	client      *github.Client
 	destDir     string
 	token       string
-	wg          sync.WaitGroup
 	mu          sync.Mutex
-	binPaths    []string
+	lastResults []DownloadResult
 	assetsMap   map[string][]*github.ReleaseAsset
 	matchFilter string
+
+	matchRegexp  *regexp.Regexp
+	matchGlob    string
+	excludeGlobs []string
+
+	archivedPolicy ArchivedPolicy
+	useHints       bool
+	repoHints      map[string]*AssetHints
+	repoMatch      map[string]string
+	repoExtract    map[string]bool
+	repoDest       map[string]string
+	pathTemplate   *template.Template
+	installNames   map[string]string
+	repoInstall    map[string]installTarget
+	installed      map[string]map[string]string
+
+	defaultProvider ReleaseProvider
+	repoProviders   map[string]ReleaseProvider
+	expandedRepos   map[string]bool
+
+	updateCurrentLink bool
+	blueGreen         bool
+	smokeTest         []string
+	installDir        string
+	installSymlink    bool
+	keepVersions      int
+	pruneDryRun       bool
+	decompressGzip    bool
+	joinParts         bool
+	extract           bool
+	platformOS        string
+	platformArch      string
+	verifyChecksums   bool
+	cosignKey         string
+	cosignIdentity    string
+	cosignIssuer      string
+	gpgKey            string
+	stallMinRate      int64
+	stallWindow       time.Duration
+	rateLimiter       *tokenBucket
+	segments          int
+	segmentMinSize    int64
+
+	hostMu       sync.Mutex
+	maxHostConns int
+	hostSlots    map[string]semaphore
+
+	stateMu sync.Mutex
+
+	verbose    bool
+	transport  http.RoundTripper
+	httpClient *http.Client
+	cacheDir   string
+	logger     *slog.Logger
+
+	defaultLevel slog.LevelVar
+
+	versionProbes map[string][]string
+	movedRepos    map[string]string
+	repoMovedFunc func(from, to string)
+
+	progress ProgressFunc
+	hooks    Hooks
+
+	maxConcurrentRepos  int
+	maxConcurrentAssets int
+	retries             int
+
+	includePrereleases bool
+	downloadSource     bool
 }
 
 // New creates a new Downloader.
 // destDir is the directory where binaries will be saved.
 func New(token, destDir string) *Downloader {
-	var client *github.Client
 	if token == "" {
 		token = os.Getenv("GITHUB_TOKEN")
 	}
+
+	d := &Downloader{
+		destDir:   destDir,
+		token:     token,
+		assetsMap: make(map[string][]*github.ReleaseAsset),
+
+		versionProbes: make(map[string][]string),
+		movedRepos:    make(map[string]string),
+		repoHints:     make(map[string]*AssetHints),
+		repoMatch:     make(map[string]string),
+		repoExtract:   make(map[string]bool),
+		repoDest:      make(map[string]string),
+		pathTemplate:  defaultPathTemplate,
+		installNames:  make(map[string]string),
+		repoInstall:   make(map[string]installTarget),
+		installed:     make(map[string]map[string]string),
+		repoProviders: make(map[string]ReleaseProvider),
+		expandedRepos: make(map[string]bool),
+
+		maxConcurrentRepos:  DefaultMaxConcurrentRepos,
+		maxConcurrentAssets: DefaultMaxConcurrentAssets,
+		retries:             DefaultRetries,
+	}
+	d.logger = defaultLogger(&d.defaultLevel)
+
+	// All requests, API and asset transfers alike, go through the logging
+	// transport so verbose mode sees them, and are retried when they fail
+	// transiently. API requests are additionally answered from the cache
+	// directory, if set, when they haven't changed. The client set with
+	// SetHTTPClient is consulted on every request.
+	d.transport = &retryTransport{d: d, next: &loggingTransport{d: d, next: &hostLimitTransport{d: d, next: &baseTransport{d: d}}}}
+	httpClient := &http.Client{Transport: &cacheTransport{d: d, next: d.transport}}
 	if token == "" {
-		client = github.NewClient(nil)
+		d.client = github.NewClient(httpClient)
 	} else {
-		ctx := context.Background()
+		ctx := context.WithValue(context.Background(), oauth2.HTTPClient, httpClient)
 		ts := oauth2.StaticTokenSource(
 			&oauth2.Token{AccessToken: token},
 		)
 		tc := oauth2.NewClient(ctx, ts)
-		client = github.NewClient(tc)
-	}
-
-	return &Downloader{
-		client:    client,
-		destDir:   destDir,
-		token:     token,
-		assetsMap: make(map[string][]*github.ReleaseAsset),
+		d.client = github.NewClient(tc)
 	}
+	return d
 }
 
 // SetMatchFilter sets the match filter for asset names.
@@ -57,186 +157,788 @@ func (d *Downloader) SetMatchFilter(match string) {
 	d.matchFilter = match
 }
 
+// SetRepoMatchFilter sets the match filter for asset names of a single
+// "owner/repo", overriding the global match filter.
+func (d *Downloader) SetRepoMatchFilter(userRepo, match string) {
+	d.repoMatch[strings.ToLower(userRepo)] = match
+}
+
+// SetRepoAssetHints sets the asset naming scheme for a single "owner/repo",
+// used instead of any hints file published by the repository.
+func (d *Downloader) SetRepoAssetHints(userRepo string, hints *AssetHints) {
+	d.repoHints[strings.ToLower(userRepo)] = hints
+}
+
 // DownloadLatestReleases downloads the latest release binaries for the given user/repos.
+// A repo may be pinned to a specific release as "owner/repo@tag", or to the
+// highest release matching a version constraint as "owner/repo@^1.4".
 func (d *Downloader) DownloadLatestReleases(userRepos []string) ([]string, error) {
+	return d.DownloadLatestReleasesContext(context.Background(), userRepos)
+}
+
+// DownloadLatestReleasesContext is like DownloadLatestReleases but stops
+// in-flight API calls and transfers when ctx is cancelled.
+func (d *Downloader) DownloadLatestReleasesContext(ctx context.Context, userRepos []string) ([]string, error) {
+	results, err := d.withRun(ctx, func(ctx context.Context) error {
+		return d.downloadLatestReleases(ctx, userRepos)
+	})
+	return resultPaths(results), err
+}
+
+// downloadLatestReleases implements DownloadLatestReleasesContext, recording
+// results in the run of ctx.
+func (d *Downloader) downloadLatestReleases(ctx context.Context, userRepos []string) error {
+	ctx = d.rateLimitContext(ctx)
+
 	// Make sure the top-level destination directory exists.
 	if err := os.MkdirAll(d.destDir, 0755); err != nil {
-		return nil, fmt.Errorf("failed to create destination directory: %v", err)
+		return fmt.Errorf("failed to create destination directory: %v", err)
 	}
 
-	errChan := make(chan error, len(userRepos))
+	errChan := make(chan *RepoError, len(userRepos))
+	sem := newSemaphore(d.maxConcurrentRepos)
 
+	var wg sync.WaitGroup
+	seen := make(map[string]bool)
 	for _, userRepo := range userRepos {
-		owner, repo, err := parseUserRepo(userRepo)
+		owner, repo, tag, err := parseRepoSpec(userRepo)
 		if err != nil {
-			return nil, fmt.Errorf("invalid user/repo format '%s': %v", userRepo, err)
+			return fmt.Errorf("invalid user/repo format '%s': %v", userRepo, err)
 		}
-
-		d.wg.Add(1)
-		go func(owner, repo string) {
-			defer d.wg.Done()
-			if err := d.downloadLatestRelease(owner, repo); err != nil {
-				errChan <- fmt.Errorf("failed to download %s/%s: %v", owner, repo, err)
+		// Downloading the same release twice at once would race.
+		if seen[strings.ToLower(userRepo)] {
+			continue
+		}
+		seen[strings.ToLower(userRepo)] = true
+
+		wg.Add(1)
+		go func(owner, repo, tag string) {
+			defer wg.Done()
+			sem.acquire()
+			defer sem.release()
+			if _, _, err := d.downloadRelease(ctx, owner, repo, tag); err != nil {
+				d.record(ctx, DownloadResult{Owner: owner, Repo: repo, Tag: tag, Err: err})
+				errChan <- d.repoError(owner, repo, err)
 			}
-		}(owner, repo)
+		}(owner, repo, tag)
 	}
 
-	d.wg.Wait()
+	wg.Wait()
 	close(errChan)
 
 	// Collect errors
-	var errs []string
+	var errs []*RepoError
 	for err := range errChan {
-		errs = append(errs, err.Error())
+		errs = append(errs, err)
 	}
+	return joinRepoErrors("download", errs)
+}
+
+// DownloadRelease downloads the binaries of the release of owner/repo tagged
+// tag. The tag matches with or without a leading "v", so "1.2.3" finds a
+// release tagged "v1.2.3" and vice versa.
+func (d *Downloader) DownloadRelease(owner, repo, tag string) ([]string, error) {
+	return d.DownloadReleaseContext(context.Background(), owner, repo, tag)
+}
 
-	if len(errs) > 0 {
-		return d.binPaths, fmt.Errorf("errors occurred:\n%s", strings.Join(errs, "\n"))
+// DownloadReleaseContext is like DownloadRelease but honors cancellation of ctx.
+func (d *Downloader) DownloadReleaseContext(ctx context.Context, owner, repo, tag string) ([]string, error) {
+	if tag == "" {
+		return nil, fmt.Errorf("no tag given for %s/%s", owner, repo)
 	}
-	return d.binPaths, nil
+	return d.DownloadLatestReleasesContext(ctx, []string{owner + "/" + repo + "@" + tag})
 }
 
-// parseUserRepo splits "owner/repo" into owner and repo.
+// parseUserRepo splits "owner/repo" into owner and repo. The owner may be a
+// nested group path, as in "group/subgroup/repo" on GitLab.
 func parseUserRepo(userRepo string) (string, string, error) {
 	parts := strings.Split(userRepo, "/")
-	if len(parts) != 2 {
+	if len(parts) < 2 || slices.Contains(parts, "") {
 		return "", "", fmt.Errorf("expected format 'owner/repo'")
 	}
-	return parts[0], parts[1], nil
+	owner := strings.Join(parts[:len(parts)-1], "/")
+	return owner, parts[len(parts)-1], nil
+}
+
+// parseRepoSpec splits "owner/repo" or "owner/repo@tag" into owner, repo and
+// tag, which is empty when not given.
+func parseRepoSpec(spec string) (string, string, string, error) {
+	userRepo, tag, hasTag := strings.Cut(spec, "@")
+	if hasTag && tag == "" {
+		return "", "", "", fmt.Errorf("expected format 'owner/repo@tag'")
+	}
+	owner, repo, err := parseUserRepo(userRepo)
+	if err != nil {
+		return "", "", "", err
+	}
+	return owner, repo, tag, nil
 }
 
-// downloadLatestRelease fetches the latest release and downloads its assets.
-func (d *Downloader) downloadLatestRelease(owner, repo string) error {
-	release, _, err := d.client.Repositories.GetLatestRelease(context.Background(), owner, repo)
+// downloadRelease fetches the release tagged tag (or the latest release if tag
+// is empty) and downloads its assets. It returns the release tag and the
+// version directory holding the assets, both empty if the repository was
+// skipped.
+func (d *Downloader) downloadRelease(ctx context.Context, owner, repo, tag string) (string, string, error) {
+	// Follow renamed or transferred repositories to their new location.
+	// Per-repository settings are keyed by the name the caller asked for.
+	requestedOwner, requestedRepo, pinned := owner, repo, tag != ""
+	key := strings.ToLower(owner + "/" + repo)
+	owner, repo, archived, err := d.resolveRepo(ctx, owner, repo)
 	if err != nil {
-		return fmt.Errorf("error fetching latest release: %v", err)
+		return "", "", err
 	}
 
-	// Optionally skip if the latest release is a draft or pre-release:
-	if release.GetDraft() || release.GetPrerelease() {
-		return fmt.Errorf("latest release is draft or pre-release")
+	// Apply the archived repository policy before looking at releases.
+	if archived {
+		switch d.archivedPolicy {
+		case ArchivedSkip:
+			d.warnf("repository '%s/%s' is archived. Skipping.", owner, repo)
+			d.record(ctx, DownloadResult{Owner: owner, Repo: repo, Skipped: true})
+			return "", "", nil
+		case ArchivedPin:
+			pinnedTag, files, err := d.pinnedFiles(key, owner, repo)
+			if err != nil {
+				return "", "", fmt.Errorf("failed to read pinned version of archived repository: %v", err)
+			}
+			if len(files) > 0 {
+				d.warnf("repository '%s/%s' is archived. Keeping the version already downloaded.", owner, repo)
+				dir := filepath.Dir(files[0])
+				d.record(ctx, existingResults(owner, repo, pinnedTag, files)...)
+				return pinnedTag, dir, nil
+			}
+			d.warnf("repository '%s/%s' is archived. Downloading its final release.", owner, repo)
+		default:
+			d.warnf("repository '%s/%s' is archived and will receive no further releases.", owner, repo)
+		}
+	}
+
+	release, err := d.fetchRelease(ctx, owner, repo, tag)
+	if err != nil {
+		// Repositories found by ExpandOwner need not publish releases.
+		if d.isExpanded(key) && (errors.Is(err, ErrNotFound) || errors.Is(err, errNoReleases)) {
+			d.infof("Repository '%s/%s' has no releases. Skipping.", owner, repo)
+			d.record(ctx, DownloadResult{Owner: owner, Repo: repo, Skipped: true})
+			return "", "", nil
+		}
+		return "", "", err
 	}
 
-	if len(release.Assets) == 0 {
-		return fmt.Errorf("no assets found in the latest release")
+	if len(release.Assets) == 0 && !d.downloadSource {
+		if d.isExpanded(key) {
+			d.infof("Release '%s' of %s/%s has no assets. Skipping.", release.GetTagName(), owner, repo)
+			d.record(ctx, DownloadResult{Owner: owner, Repo: repo, Tag: release.GetTagName(), Skipped: true})
+			return "", "", nil
+		}
+		return "", "", fmt.Errorf("%w in the release", ErrNoAssets)
 	}
 
 	// If tag is empty, we'll call it "latest" and force re-download
-	tag := release.GetTagName()
+	tag = release.GetTagName()
 	forceDownload := false
 	if tag == "" {
 		tag = "latest"
 		forceDownload = true
 	}
 
-	// Build directory name as "<repoName>-<tag>"
-	dirName := fmt.Sprintf("%s-%s", repo, tag)
-	versionDir := filepath.Join(d.destDir, dirName)
-	if err := os.MkdirAll(versionDir, 0755); err != nil {
-		return fmt.Errorf("failed to create version directory '%s': %v", versionDir, err)
+	// Skip entirely if the installed binary already reports this version.
+	if installed, ok := d.installedVersion(ctx, requestedOwner, requestedRepo); ok && versionsMatch(installed, tag) {
+		d.infof("Installed version of %s/%s (%s) matches release '%s'. Skipping download.",
+			owner, repo, installed, tag)
+		d.record(ctx, DownloadResult{Owner: owner, Repo: repo, Tag: tag, Skipped: true})
+		return "", "", nil
+	}
+
+	// Build the directory path from the path template, "<repoName>-<tag>"
+	// by default.
+	versionDir, err := d.versionDir(key, owner, repo, tag)
+	if err != nil {
+		return "", "", err
+	}
+
+	// In blue/green mode, a version that is already live needs no work.
+	if d.blueGreen {
+		if files, ok := d.liveFiles(key, owner, repo, versionDir); ok {
+			d.infof("Release '%s' of %s/%s is already live. Skipping download.", tag, owner, repo)
+			d.record(ctx, existingResults(owner, repo, tag, files)...)
+			return tag, versionDir, nil
+		}
+	}
+
+	selection := d.selectAssets(ctx, key, owner, repo, release)
+	if selection.empty() {
+		if d.isExpanded(key) {
+			d.infof("No assets of release '%s' of %s/%s match the filters. Skipping.", tag, owner, repo)
+			d.record(ctx, DownloadResult{Owner: owner, Repo: repo, Tag: tag, Skipped: true})
+			return "", "", nil
+		}
+		return "", "", fmt.Errorf("%w matching the filters in release '%s'", ErrNoAssets, tag)
+	}
+
+	// In blue/green mode, assets go to a staging directory until promoted.
+	downloadDir := versionDir
+	if d.blueGreen {
+		if !d.versionedLayout() {
+			return "", "", fmt.Errorf("blue/green installs need the tag in the directory of the path template")
+		}
+		downloadDir = stagingDir(versionDir)
+		if err := os.RemoveAll(downloadDir); err != nil {
+			return "", "", fmt.Errorf("failed to clear staging directory '%s': %v", downloadDir, err)
+		}
+	}
+	if err := os.MkdirAll(downloadDir, 0755); err != nil {
+		return "", "", fmt.Errorf("failed to create version directory '%s': %v", downloadDir, err)
+	}
+
+	results, failed := d.downloadReleaseAssets(ctx, key, owner, repo, release, selection, downloadDir, forceDownload)
+
+	if d.blueGreen {
+		if failed {
+			os.RemoveAll(downloadDir)
+			return "", "", fmt.Errorf("not promoting release '%s': some assets failed to download", tag)
+		}
+		if err := d.promote(ctx, key, owner, repo, downloadDir, versionDir); err != nil {
+			return "", "", err
+		}
+		for i := range results {
+			if rel, err := filepath.Rel(downloadDir, results[i].Path); err == nil && results[i].Path != "" {
+				results[i].Path = filepath.Join(versionDir, rel)
+			}
+		}
+	}
+	d.record(ctx, results...)
+
+	// Only move the "current" link once every asset of the latest release is
+	// in place; pinned older versions must not replace it.
+	if d.updateCurrentLink && !d.blueGreen && !failed && !pinned {
+		if err := d.updateCurrent(key, owner, repo, versionDir); err != nil {
+			return "", "", err
+		}
+	}
+
+	// Install the executables once the whole release is in place.
+	if d.installTargetFor(key).dir != "" && !failed {
+		installed, err := d.installResults(key, repo, results)
+		if err != nil {
+			return "", "", err
+		}
+		d.mu.Lock()
+		d.installed[key] = installed
+		d.mu.Unlock()
+	}
+
+	// Prune older versions once the new one is complete.
+	if d.keepVersions > 0 && !failed {
+		if _, err := d.prune(key, owner, repo, d.keepVersions, versionDir); err != nil {
+			return "", "", err
+		}
+	}
+	return tag, versionDir, nil
+}
+
+// fetchReleaseByTag fetches the release of owner/repo tagged tag, retrying
+// with the leading "v" added or removed if there is no such tag.
+func (d *Downloader) fetchReleaseByTag(ctx context.Context, owner, repo, tag string) (*github.RepositoryRelease, error) {
+	p := d.provider(owner, repo)
+	release, err := p.GetByTag(ctx, owner, repo, tag)
+	if err == nil {
+		return release, nil
+	}
+	if !errors.Is(err, ErrNotFound) {
+		return nil, fmt.Errorf("error fetching release '%s': %w", tag, err)
+	}
+
+	alternate := "v" + tag
+	if strings.HasPrefix(tag, "v") {
+		alternate = strings.TrimPrefix(tag, "v")
+	}
+	release, altErr := p.GetByTag(ctx, owner, repo, alternate)
+	if altErr != nil {
+		return nil, fmt.Errorf("error fetching release '%s': %w", tag, err)
+	}
+	return release, nil
+}
+
+// fetchRelease fetches the release of owner/repo tagged tag, the highest
+// release matching tag if it is a version constraint, or the latest
+// published release if tag is empty.
+func (d *Downloader) fetchRelease(ctx context.Context, owner, repo, tag string) (*github.RepositoryRelease, error) {
+	if isVersionConstraint(tag) {
+		return d.resolveRelease(ctx, owner, repo, tag)
+	}
+	if tag != "" {
+		return d.fetchReleaseByTag(ctx, owner, repo, tag)
+	}
+	if d.includePrereleases {
+		return d.fetchNewestRelease(ctx, owner, repo)
+	}
+
+	release, err := d.provider(owner, repo).GetLatest(ctx, owner, repo)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching latest release: %w", err)
+	}
+
+	// Optionally skip if the latest release is a draft or pre-release:
+	if release.GetDraft() || release.GetPrerelease() {
+		return nil, fmt.Errorf("latest release is draft or pre-release")
+	}
+	return release, nil
+}
+
+// SetIncludePrereleases makes the latest release the most recent one that
+// isn't a draft, even if it is a pre-release. This suits repositories that
+// only publish pre-releases, such as nightly builds.
+func (d *Downloader) SetIncludePrereleases(include bool) {
+	d.includePrereleases = include
+}
+
+// fetchNewestRelease lists the releases of owner/repo and returns the most
+// recent one that isn't a draft, pre-releases included.
+func (d *Downloader) fetchNewestRelease(ctx context.Context, owner, repo string) (*github.RepositoryRelease, error) {
+	p := d.provider(owner, repo)
+	for page := 1; ; {
+		releases, next, err := p.ListReleases(ctx, owner, repo, page, 30)
+		if err != nil {
+			return nil, fmt.Errorf("error listing releases: %w", err)
+		}
+		// Releases are listed newest first.
+		for _, release := range releases {
+			if !release.GetDraft() {
+				return release, nil
+			}
+		}
+		if next == 0 {
+			return nil, errNoReleases
+		}
+		page = next
+	}
+}
+
+// assetSelection holds the assets of a release picked for download.
+type assetSelection struct {
+	// source is set when the release's source tarball is downloaded too.
+	source bool
+	// parts holds the parts of each split asset, keyed by its joined name.
+	parts  map[string][]assetPart
+	assets []*github.ReleaseAsset
+}
+
+// empty reports whether nothing was selected.
+func (s assetSelection) empty() bool {
+	return !s.source && len(s.parts) == 0 && len(s.assets) == 0
+}
+
+// selectAssets picks the assets of release to download according to the
+// lockfile, asset hints, platform and name filters for key ("owner/repo" as
+// requested).
+func (d *Downloader) selectAssets(ctx context.Context, key, owner, repo string, release *github.RepositoryRelease) assetSelection {
+	matchFilter, ok := d.repoMatch[key]
+	if !ok {
+		matchFilter = d.matchFilter
+	}
+	// Assets pinned by a lockfile are downloaded regardless of other filters.
+	locked := lockedAssets(ctx, key)
+
+	// Let asset hints pick the asset when no filter is given
+	assets := release.Assets
+	hinted := false
+	if locked == nil && !d.hasIncludeFilter(matchFilter) && release.GetTagName() != "" {
+		hints, ok := d.repoHints[key]
+		// Hints files are read through the GitHub contents API.
+		if !ok && d.useHints && d.isGitHub(owner, repo) {
+			var err error
+			hints, err = d.fetchAssetHints(ctx, owner, repo, release.GetTagName())
+			if err != nil {
+				d.warnf("ignoring asset hints for %s/%s: %v", owner, repo, err)
+			}
+		}
+		if hints != nil {
+			if selected := d.hintedAssets(owner, repo, release.GetTagName(), hints, assets); selected != nil {
+				assets, hinted = selected, true
+			}
+		}
+	}
+
+	// Narrow the assets down to builds for the selected platform
+	if d.platformOS != "" && d.platformArch != "" && !hinted && locked == nil {
+		if matched := d.platformAssets(assets); matched != nil {
+			assets = matched
+		} else {
+			d.warnf("no asset of %s/%s looks like a build for %s/%s; downloading all assets",
+				owner, repo, d.platformOS, d.platformArch)
+		}
+	}
+
+	// Split assets are downloaded as a group and joined into one file
+	var partGroups map[string][]assetPart
+	if d.joinParts {
+		partGroups, assets = groupAssetParts(assets)
 	}
 
-	// Download each asset that matches our (optional) filter
-	for _, asset := range release.Assets {
-		if d.matchFilter != "" && !strings.Contains(asset.GetName(), d.matchFilter) {
-			fmt.Printf("Skipping asset '%s' (does not match filter '%s')\n", asset.GetName(), d.matchFilter)
+	// Keep each asset that matches our (optional) filters
+	selection := assetSelection{
+		source: locked[sourceName(repo, release.GetTagName())] || d.downloadSource && locked == nil,
+		parts:  make(map[string][]assetPart),
+	}
+	for name, parts := range partGroups {
+		if ok, why := d.filterAsset(name, matchFilter, locked); !ok {
+			d.debugf("Skipping split asset '%s' (%s)", name, why)
 			continue
 		}
-		if err := d.downloadAsset(asset, versionDir, forceDownload); err != nil {
-			fmt.Printf("Warning: failed to download asset '%s' from %s/%s: %v\n",
-				asset.GetName(), owner, repo, err)
+		selection.parts[name] = parts
+	}
+	for _, asset := range assets {
+		if ok, why := d.filterAsset(asset.GetName(), matchFilter, locked); !ok {
+			d.debugf("Skipping asset '%s' (%s)", asset.GetName(), why)
+			continue
 		}
+		selection.assets = append(selection.assets, asset)
 	}
-	return nil
+	return selection
+}
+
+// downloadReleaseAssets downloads the assets of release in selection, picked
+// for key ("owner/repo" as requested), into dir. It returns the result for
+// each asset and whether any asset failed to download.
+func (d *Downloader) downloadReleaseAssets(ctx context.Context, key, owner, repo string, release *github.RepositoryRelease, selection assetSelection, dir string, forceDownload bool) ([]DownloadResult, bool) {
+	// Queue each selected asset
+	userRepo := owner + "/" + repo
+	sums := d.newReleaseChecksums(ctx, userRepo, release.Assets)
+	type assetJob struct {
+		kind, name string
+		id         int64
+		url        string
+		unpack     bool
+		download   func() (string, bool, error)
+		// info describes the asset to the OnAssetStart hook.
+		info AssetInfo
+	}
+	tag := release.GetTagName()
+	info := func(name, path string, size int64, updated time.Time) AssetInfo {
+		return AssetInfo{Owner: owner, Repo: repo, Tag: tag, Name: name, Size: size, UpdatedAt: updated, Path: path}
+	}
+	var jobs []assetJob
+	if selection.source {
+		name := sourceName(repo, tag)
+		jobs = append(jobs, assetJob{"source tarball", name, 0, release.GetTarballURL(), false, func() (string, bool, error) {
+			return d.downloadSourceTarball(ctx, userRepo, repo, release, dir, forceDownload)
+		}, info(name, filepath.Join(dir, name), 0, release.GetPublishedAt().Time)})
+	}
+	for name, parts := range selection.parts {
+		name, parts := name, parts
+		var size int64
+		var updated time.Time
+		for _, part := range parts {
+			size += int64(part.asset.GetSize())
+			if t := part.asset.GetUpdatedAt().Time; t.After(updated) {
+				updated = t
+			}
+		}
+		jobs = append(jobs, assetJob{"split asset", name, 0, "", true, func() (string, bool, error) {
+			return d.downloadParts(ctx, userRepo, name, parts, sums, dir, forceDownload)
+		}, info(name, filepath.Join(dir, name), size, updated)})
+	}
+	for _, asset := range selection.assets {
+		asset := asset
+		jobs = append(jobs, assetJob{"asset", asset.GetName(), asset.GetID(), asset.GetBrowserDownloadURL(), true, func() (string, bool, error) {
+			return d.downloadAsset(ctx, userRepo, asset, dir, forceDownload, sums)
+		}, info(asset.GetName(), d.savedPath(dir, asset.GetName()), int64(asset.GetSize()), asset.GetUpdatedAt().Time)})
+	}
+
+	// Download the queued assets, a few at a time
+	outcomes := make([][]DownloadResult, len(jobs))
+	failures := make([]bool, len(jobs))
+	sem := newSemaphore(d.maxConcurrentAssets)
+	var wg sync.WaitGroup
+	for i, job := range jobs {
+		wg.Add(1)
+		go func(i int, job assetJob) {
+			defer wg.Done()
+			sem.acquire()
+			defer sem.release()
+
+			result := DownloadResult{Owner: owner, Repo: repo, Tag: tag, AssetName: job.name, AssetID: job.id, URL: job.url}
+			if err := d.startAsset(job.info, forceDownload); err != nil {
+				if errors.Is(err, ErrSkipAsset) {
+					d.infof("Skipping %s '%s' of %s/%s", job.kind, job.name, owner, repo)
+					return
+				}
+				d.warnf("failed to download %s '%s' from %s/%s: %v", job.kind, job.name, owner, repo, err)
+				result.Err = err
+				outcomes[i], failures[i] = []DownloadResult{result}, true
+				return
+			}
+			path, skipped, err := job.download()
+			if err != nil {
+				d.warnf("failed to download %s '%s' from %s/%s: %v", job.kind, job.name, owner, repo, err)
+				result.Err = err
+				outcomes[i], failures[i] = []DownloadResult{result}, true
+				return
+			}
+			if !job.unpack {
+				result.Path, result.Skipped = path, skipped
+				outcomes[i] = []DownloadResult{result}
+				return
+			}
+			outcomes[i], err = d.unpackResults(key, result, path, dir, skipped)
+			failures[i] = err != nil
+		}(i, job)
+	}
+	wg.Wait()
+
+	var results []DownloadResult
+	failed := false
+	for i := range jobs {
+		results = append(results, outcomes[i]...)
+		failed = failed || failures[i]
+	}
+	return results, failed
+}
+
+// unpackResults unpacks the asset saved at path if needed for key and returns
+// one result per file it contributes, based on result.
+func (d *Downloader) unpackResults(key string, result DownloadResult, path, dir string, skipped bool) ([]DownloadResult, error) {
+	files, err := d.unpack(key, path, dir)
+	if err != nil {
+		d.warnf("%v", err)
+		result.Path, result.Err = path, err
+		return []DownloadResult{result}, err
+	}
+	results := make([]DownloadResult, len(files))
+	for i, file := range files {
+		results[i] = result
+		results[i].Path, results[i].Skipped = file, skipped
+	}
+	return results, nil
 }
 
-// downloadAsset downloads a single asset and saves it to the provided directory.
-func (d *Downloader) downloadAsset(asset *github.ReleaseAsset, versionDir string, forceDownload bool) error {
+// downloadAsset downloads a single asset of userRepo and saves it to the
+// provided directory, returning the path of the saved file and whether it
+// was already there. sums holds the release checksums used when
+// verification is enabled.
+func (d *Downloader) downloadAsset(ctx context.Context, userRepo string, asset *github.ReleaseAsset, versionDir string, forceDownload bool, sums *releaseChecksums) (string, bool, error) {
 	// Use the API URL for authenticated download
 	apiURL := asset.GetURL()
 	if apiURL == "" {
-		return fmt.Errorf("asset '%s' does not have an API URL", asset.GetName())
+		return "", false, fmt.Errorf("asset '%s' does not have an API URL", asset.GetName())
 	}
 
 	fileName := asset.GetName()
 	filePath := filepath.Join(versionDir, fileName)
 
+	// Bare .gz assets are stored decompressed under the name without ".gz"
+	outPath := d.savedPath(versionDir, fileName)
+
+	// Verify the asset as published, before any decompression and before
+	// it is renamed into place.
+	verify := d.assetVerifier(ctx, userRepo, fileName, sums)
+
 	// If NOT forced (i.e., not "latest"), skip download if file exists
 	if !forceDownload {
-		if _, err := os.Stat(filePath); err == nil {
-			fmt.Printf("File '%s' already exists. Skipping download.\n", filePath)
-			d.mu.Lock()
-			d.binPaths = append(d.binPaths, filePath)
-			d.mu.Unlock()
-			return nil
+		if info, err := os.Stat(outPath); err == nil {
+			// A file of the wrong size is a leftover of an interrupted
+			// download by an older version, not a complete one.
+			if size := int64(asset.GetSize()); size > 0 && outPath == filePath && info.Size() != size {
+				d.warnf("File '%s' has %d bytes instead of %d. Downloading it again.", outPath, info.Size(), size)
+			} else if err := verifyExisting(outPath, filePath, verify); err != nil {
+				d.warnf("File '%s' failed verification: %v. Downloading it again.", outPath, err)
+			} else if !d.versionedLayout() && info.ModTime().Before(asset.GetUpdatedAt().Time) {
+				// Without the tag in its path, the file may be of an
+				// older release.
+				d.infof("File '%s' is older than the asset. Downloading it again.", outPath)
+			} else {
+				d.infof("File '%s' already exists. Skipping download.", outPath)
+				return outPath, true, nil
+			}
 		}
 	}
 
-	// Create file
-	file, err := os.Create(filePath)
-	if err != nil {
-		return fmt.Errorf("failed to create file '%s': %v", filePath, err)
+	if err := d.fetchAsset(ctx, userRepo, asset, filePath, verify); err != nil {
+		return "", false, err
 	}
-	defer file.Close()
+	d.infof("Downloaded '%s' to '%s'", asset.GetName(), filePath)
 
-	// First request: get the redirect URL from the asset API endpoint
-	req, err := http.NewRequest("GET", apiURL, nil)
-	if err != nil {
-		return fmt.Errorf("failed to create HTTP request: %v", err)
+	if outPath != filePath {
+		if err := gunzipFile(filePath, outPath); err != nil {
+			return "", false, err
+		}
+		d.infof("Decompressed '%s' to '%s'", filePath, outPath)
+	}
+	return outPath, false, nil
+}
+
+// assetVerifier returns a function checking a copy of the asset of userRepo
+// named name against the digest pinned by a lockfile download in ctx and the
+// release checksums and signatures, as enabled, or nil if there is nothing
+// to check.
+func (d *Downloader) assetVerifier(ctx context.Context, userRepo, name string, sums *releaseChecksums) func(path string) error {
+	checkSum := d.verifyChecksums && !isChecksumAsset(name)
+	checkSignature := d.verifiesSignatures() && !isChecksumAsset(name) && !isSignatureAsset(name)
+	lockedSum := lockedDigest(ctx, userRepo, name)
+	if !checkSum && !checkSignature && lockedSum == "" {
+		return nil
 	}
-	if d.token != "" {
-		req.Header.Set("Authorization", "token "+d.token)
+	return func(path string) error {
+		if lockedSum != "" {
+			sum, err := fileSHA256(path)
+			if err != nil {
+				return err
+			}
+			if !strings.EqualFold(sum, lockedSum) {
+				return fmt.Errorf("checksum mismatch for '%s': lockfile expects %s, got %s", name, lockedSum, sum)
+			}
+		}
+		if checkSum {
+			if err := sums.verify(path, name); err != nil {
+				return err
+			}
+		}
+		if checkSignature {
+			return d.verifySignature(ctx, userRepo, path, name, sums.assets)
+		}
+		return nil
 	}
-	req.Header.Set("Accept", "application/octet-stream")
+}
 
-	// Use a custom client to capture 302 redirect
-	client := &http.Client{
-		CheckRedirect: func(req *http.Request, via []*http.Request) error {
-			return http.ErrUseLastResponse
-		},
+// verifyExisting checks the file at outPath, saved from the asset downloaded
+// to filePath, with verify before it is used again, as verification may not
+// have been enabled when it was downloaded. Decompressed files no longer
+// match the published asset, so they can't be checked again.
+func verifyExisting(outPath, filePath string, verify func(path string) error) error {
+	if verify == nil || outPath != filePath {
+		return nil
 	}
+	return verify(outPath)
+}
 
-	resp, err := client.Do(req)
-	if err != nil {
-		return fmt.Errorf("failed to get asset redirect URL: %v", err)
+// partialSuffix marks files whose download hasn't completed yet.
+const partialSuffix = ".partial"
+
+// fetchAsset downloads the contents of an asset of userRepo to filePath,
+// resuming transfers aborted by the stall watchdog or a broken connection. Data is written to
+// "<filePath>.partial", which a later attempt or run resumes with a Range
+// request, and synced and renamed to filePath once its size matches the
+// asset and verify, if set, accepts it, so filePath never holds a truncated
+// or unverified download. Large assets may be downloaded in segments
+// instead.
+func (d *Downloader) fetchAsset(ctx context.Context, userRepo string, asset *github.ReleaseAsset, filePath string, verify func(path string) error) error {
+	if d.useSegments(asset, filePath) {
+		err := d.fetchAssetSegmented(ctx, userRepo, asset, filePath, verify)
+		if !errors.Is(err, errRangeUnsupported) {
+			return err
+		}
+		d.infof("Server doesn't support ranged requests for '%s'. Downloading it in a single stream.", asset.GetName())
+	}
+
+	partialPath := filePath + partialSuffix
+	var retries transferRetries
+	for {
+		err := d.fetchAssetOnce(ctx, userRepo, asset, partialPath)
+		if err == nil {
+			break
+		}
+		if !d.resumeTransfer(ctx, &retries, "'"+asset.GetName()+"'", err) {
+			return err
+		}
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusFound {
-		return fmt.Errorf("unexpected status code (expected 302 Found): got %s", resp.Status)
+	if size := int64(asset.GetSize()); size > 0 {
+		info, err := os.Stat(partialPath)
+		if err != nil {
+			return fmt.Errorf("failed to stat '%s': %v", partialPath, err)
+		}
+		if info.Size() != size {
+			os.Remove(partialPath)
+			return fmt.Errorf("size mismatch for '%s': expected %d bytes, got %d", asset.GetName(), size, info.Size())
+		}
 	}
+	if verify != nil {
+		if err := verify(partialPath); err != nil {
+			os.Remove(partialPath)
+			return err
+		}
+	}
+	if err := os.Rename(partialPath, filePath); err != nil {
+		return fmt.Errorf("failed to rename '%s': %v", partialPath, err)
+	}
+	return nil
+}
 
-	redirectURL := resp.Header.Get("Location")
-	if redirectURL == "" {
-		return fmt.Errorf("no redirect location found for asset '%s'", asset.GetName())
+// fetchAssetOnce makes a single attempt at downloading asset to partialPath,
+// continuing from the data already there if the server supports it.
+func (d *Downloader) fetchAssetOnce(ctx context.Context, userRepo string, asset *github.ReleaseAsset, partialPath string) error {
+	// Pick up where an earlier attempt left off
+	var offset int64
+	if info, err := os.Stat(partialPath); err == nil {
+		offset = info.Size()
+	}
+	if size := int64(asset.GetSize()); size > 0 && offset >= size {
+		return nil
 	}
 
-	// Second request: download the asset using the redirect URL
-	secondReq, err := http.NewRequest("GET", redirectURL, nil)
+	file, err := os.OpenFile(partialPath, os.O_CREATE|os.O_WRONLY, 0666)
 	if err != nil {
-		return fmt.Errorf("failed to create HTTP request for redirected URL: %v", err)
+		return fmt.Errorf("failed to create file '%s': %v", partialPath, err)
 	}
-	secondReq.Header.Set("Accept", "application/octet-stream")
+	defer file.Close()
+
+	// The watchdog aborts whichever request ends up carrying the data.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
 
-	secondResp, err := http.DefaultClient.Do(secondReq)
+	owner, repo, _ := parseUserRepo(userRepo)
+	dataResp, err := d.provider(owner, repo).DownloadAsset(ctx, asset, offset)
 	if err != nil {
-		return fmt.Errorf("failed to download asset from redirect URL: %v", err)
+		return err
 	}
-	defer secondResp.Body.Close()
+	defer dataResp.Body.Close()
 
-	if secondResp.StatusCode != http.StatusOK {
-		return fmt.Errorf("bad status downloading asset from redirect URL: %s", secondResp.Status)
+	switch {
+	case dataResp.StatusCode == http.StatusPartialContent && offset > 0:
+		if _, err := file.Seek(offset, io.SeekStart); err != nil {
+			return fmt.Errorf("failed to seek in '%s': %v", partialPath, err)
+		}
+		d.infof("Resuming '%s' at byte %d", asset.GetName(), offset)
+	case dataResp.StatusCode == http.StatusOK:
+		// The server ignored the range; start over.
+		offset = 0
+		if err := file.Truncate(0); err != nil {
+			return fmt.Errorf("failed to truncate '%s': %v", partialPath, err)
+		}
+	default:
+		return fmt.Errorf("bad status downloading asset: %s", dataResp.Status)
 	}
 
-	// Write the downloaded content
-	if _, err = io.Copy(file, secondResp.Body); err != nil {
-		return fmt.Errorf("failed to write to file '%s': %v", filePath, err)
+	// Write the downloaded content, watching for stalls if configured
+	body := &countingReader{r: d.throttle(ctx, dataResp.Body)}
+	stopWatchdog := func() bool { return false }
+	if d.stallWindow > 0 {
+		stopWatchdog = watchStall(cancel, body, d.stallMinRate, d.stallWindow)
+	}
+	var src io.Reader = body
+	if d.progress != nil {
+		total := dataResp.ContentLength
+		if total >= 0 {
+			total += offset
+		} else if asset.GetSize() > 0 {
+			total = int64(asset.GetSize())
+		}
+		src = &progressReader{r: body, fn: d.progress, repo: userRepo, asset: asset.GetName(), total: total, n: offset}
+		d.progress(userRepo, asset.GetName(), offset, total)
+	}
+	_, err = io.Copy(file, src)
+	if stopWatchdog() {
+		return fmt.Errorf("%w: less than %d bytes/s for %s", errStalled, d.stallMinRate, d.stallWindow)
+	}
+	if body.err != nil {
+		return fmt.Errorf("%w: downloading '%s': %v", errInterrupted, asset.GetName(), body.err)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write to file '%s': %v", partialPath, err)
+	}
+	// Make sure the data is on disk before the file is renamed into place.
+	if err := file.Sync(); err != nil {
+		return fmt.Errorf("failed to write to file '%s': %v", partialPath, err)
 	}
-
-	fmt.Printf("Downloaded '%s' to '%s'\n", asset.GetName(), filePath)
-	d.mu.Lock()
-	d.binPaths = append(d.binPaths, filePath)
-	d.mu.Unlock()
 
 	return nil
 }
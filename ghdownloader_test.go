@@ -0,0 +1,313 @@
+package ghdownloader
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v68/github"
+)
+
+// newTestDownloader returns a downloader saving to a temporary directory,
+// with logging discarded.
+func newTestDownloader(t *testing.T) *Downloader {
+	t.Helper()
+	d := New("", t.TempDir())
+	d.SetLogger(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	return d
+}
+
+// makeDirs creates the directories names in dir, modified one second apart
+// in the order given.
+func makeDirs(t *testing.T, dir string, names ...string) {
+	t.Helper()
+	base := time.Now().Add(-time.Hour)
+	for i, name := range names {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(path, 0755); err != nil {
+			t.Fatal(err)
+		}
+		modTime := base.Add(time.Duration(i) * time.Second)
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// listDir returns the names of the entries of dir.
+func listDir(t *testing.T, dir string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	return names
+}
+
+// fakeProvider serves latest releases, keyed by "owner/repo", the release
+// list of every repository and assets, by name, from memory.
+type fakeProvider struct {
+	releases map[string]*github.RepositoryRelease
+	list     []*github.RepositoryRelease
+	data     map[string][]byte
+}
+
+// newFakeAsset adds an asset named name holding data to p and returns it.
+func (p *fakeProvider) newFakeAsset(name string, data []byte) *github.ReleaseAsset {
+	if p.data == nil {
+		p.data = make(map[string][]byte)
+	}
+	p.data[name] = data
+	return &github.ReleaseAsset{
+		Name: github.String(name),
+		URL:  github.String("https://example.com/assets/" + name),
+		Size: github.Int(len(data)),
+	}
+}
+
+func (p *fakeProvider) ListReleases(ctx context.Context, owner, repo string, page, perPage int) ([]*github.RepositoryRelease, int, error) {
+	start := (page - 1) * perPage
+	if start >= len(p.list) {
+		return nil, 0, nil
+	}
+	end := min(start+perPage, len(p.list))
+	next := page + 1
+	if end == len(p.list) {
+		next = 0
+	}
+	return p.list[start:end], next, nil
+}
+
+func (p *fakeProvider) GetLatest(ctx context.Context, owner, repo string) (*github.RepositoryRelease, error) {
+	if release, ok := p.releases[owner+"/"+repo]; ok {
+		return release, nil
+	}
+	return nil, fmt.Errorf("%w: %s/%s", ErrNotFound, owner, repo)
+}
+
+func (p *fakeProvider) GetByTag(ctx context.Context, owner, repo, tag string) (*github.RepositoryRelease, error) {
+	return nil, ErrNotFound
+}
+
+func (p *fakeProvider) DownloadAsset(ctx context.Context, asset *github.ReleaseAsset, offset int64) (*http.Response, error) {
+	data, ok := p.data[asset.GetName()]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, asset.GetName())
+	}
+	resp := &http.Response{StatusCode: http.StatusOK, Status: "200 OK", Header: make(http.Header)}
+	if offset > 0 {
+		resp.StatusCode, resp.Status = http.StatusPartialContent, "206 Partial Content"
+		resp.Header.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", offset, len(data)-1, len(data)))
+		data = data[offset:]
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(data))
+	return resp, nil
+}
+
+// fakeGitHub serves the parts of the GitHub REST API the downloader uses
+// from memory, on an httptest server.
+type fakeGitHub struct {
+	srv *httptest.Server
+
+	mu       sync.Mutex
+	repos    map[string]*github.Repository          // by lowercase "owner/repo"
+	releases map[string][]*github.RepositoryRelease // newest first
+	orgs     map[string]bool
+	data     map[int64][]byte
+	nextID   int64
+	requests []string
+
+	// hook, if set, may answer a request itself by returning true.
+	hook func(w http.ResponseWriter, r *http.Request) bool
+}
+
+// newFakeGitHub starts a fake GitHub API, stopped when the test ends.
+func newFakeGitHub(t *testing.T) *fakeGitHub {
+	t.Helper()
+	g := &fakeGitHub{
+		repos:    make(map[string]*github.Repository),
+		releases: make(map[string][]*github.RepositoryRelease),
+		orgs:     make(map[string]bool),
+		data:     make(map[int64][]byte),
+	}
+	g.srv = httptest.NewServer(http.HandlerFunc(g.serve))
+	t.Cleanup(g.srv.Close)
+	return g
+}
+
+// downloader returns a downloader using g, saving to a temporary directory.
+func (g *fakeGitHub) downloader(t *testing.T) *Downloader {
+	t.Helper()
+	d := newTestDownloader(t)
+	if err := d.SetBaseURL(g.srv.URL, ""); err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+// addRepo adds the repository userRepo without releases.
+func (g *fakeGitHub) addRepo(userRepo string) *github.Repository {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.addRepoLocked(userRepo)
+}
+
+func (g *fakeGitHub) addRepoLocked(userRepo string) *github.Repository {
+	key := strings.ToLower(userRepo)
+	if repo, ok := g.repos[key]; ok {
+		return repo
+	}
+	owner, name, _ := strings.Cut(userRepo, "/")
+	g.nextID++
+	repo := &github.Repository{ID: github.Int64(g.nextID), Name: github.String(name), Owner: &github.User{Login: github.String(owner)}}
+	g.repos[key] = repo
+	return repo
+}
+
+// addRelease publishes a release of userRepo, newer than the ones added
+// before, with assets mapping names to contents.
+func (g *fakeGitHub) addRelease(userRepo, tag string, assets map[string]string) *github.RepositoryRelease {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.addRepoLocked(userRepo)
+	key := strings.ToLower(userRepo)
+	g.nextID++
+	release := &github.RepositoryRelease{
+		ID:        github.Int64(g.nextID),
+		TagName:   github.String(tag),
+		CreatedAt: &github.Timestamp{Time: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC).Add(time.Duration(g.nextID) * time.Hour)},
+	}
+	names := make([]string, 0, len(assets))
+	for name := range assets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		g.nextID++
+		g.data[g.nextID] = []byte(assets[name])
+		release.Assets = append(release.Assets, &github.ReleaseAsset{
+			ID:        github.Int64(g.nextID),
+			Name:      github.String(name),
+			Size:      github.Int(len(assets[name])),
+			URL:       github.String(fmt.Sprintf("%s/api/v3/repos/%s/releases/assets/%d", g.srv.URL, userRepo, g.nextID)),
+			UpdatedAt: release.CreatedAt,
+		})
+	}
+	g.releases[key] = append([]*github.RepositoryRelease{release}, g.releases[key]...)
+	return release
+}
+
+// served returns the paths of the requests served so far.
+func (g *fakeGitHub) served() []string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return append([]string(nil), g.requests...)
+}
+
+func (g *fakeGitHub) serve(w http.ResponseWriter, r *http.Request) {
+	g.mu.Lock()
+	g.requests = append(g.requests, r.URL.Path)
+	hook := g.hook
+	g.mu.Unlock()
+	if hook != nil && hook(w, r) {
+		return
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/v3/"), "/")
+	switch {
+	case len(parts) == 3 && (parts[0] == "orgs" || parts[0] == "users") && parts[2] == "repos":
+		if parts[0] == "orgs" && !g.orgs[strings.ToLower(parts[1])] {
+			http.NotFound(w, r)
+			return
+		}
+		var repos []*github.Repository
+		for key, repo := range g.repos {
+			if strings.HasPrefix(key, strings.ToLower(parts[1])+"/") {
+				repos = append(repos, repo)
+			}
+		}
+		sort.Slice(repos, func(i, j int) bool { return repos[i].GetName() < repos[j].GetName() })
+		writeJSON(w, repos)
+		return
+	case len(parts) < 3 || parts[0] != "repos":
+		http.NotFound(w, r)
+		return
+	}
+
+	key := strings.ToLower(parts[1] + "/" + parts[2])
+	repo, ok := g.repos[key]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	releases := g.releases[key]
+	rest := strings.Join(parts[3:], "/")
+	switch {
+	case rest == "":
+		writeJSON(w, repo)
+	case rest == "releases":
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		perPage, _ := strconv.Atoi(r.URL.Query().Get("per_page"))
+		page, perPage = max(page, 1), max(perPage, 1)
+		start, end := min((page-1)*perPage, len(releases)), min(page*perPage, len(releases))
+		if end < len(releases) {
+			next := *r.URL
+			q := next.Query()
+			q.Set("page", strconv.Itoa(page+1))
+			next.RawQuery = q.Encode()
+			w.Header().Set("Link", fmt.Sprintf(`<%s%s>; rel="next"`, g.srv.URL, next.RequestURI()))
+		}
+		writeJSON(w, releases[start:end])
+	case rest == "releases/latest":
+		for _, release := range releases {
+			if !release.GetDraft() && !release.GetPrerelease() {
+				writeJSON(w, release)
+				return
+			}
+		}
+		http.NotFound(w, r)
+	case strings.HasPrefix(rest, "releases/tags/"):
+		for _, release := range releases {
+			if release.GetTagName() == strings.TrimPrefix(rest, "releases/tags/") {
+				writeJSON(w, release)
+				return
+			}
+		}
+		http.NotFound(w, r)
+	case strings.HasPrefix(rest, "releases/assets/"):
+		id, _ := strconv.ParseInt(strings.TrimPrefix(rest, "releases/assets/"), 10, 64)
+		data, ok := g.data[id]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(data))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// writeJSON writes v as a JSON response.
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,124 @@
+package ghdownloader
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/google/go-github/v68/github"
+)
+
+// DefaultGiteaURL is the Gitea instance used when none is given.
+const DefaultGiteaURL = "https://gitea.com"
+
+// giteaProvider fetches releases from a Gitea or Forgejo instance, whose
+// release API mirrors GitHub's closely enough to decode into its types.
+type giteaProvider struct {
+	c *restClient
+}
+
+// NewGiteaProvider returns a provider for the Gitea (or Forgejo) instance at
+// baseURL (DefaultGiteaURL if empty), authenticating with token or, if that
+// is empty, the GITEA_TOKEN environment variable. Requests go through the
+// downloader's HTTP client, retries and logging.
+func (d *Downloader) NewGiteaProvider(baseURL, token string) (ReleaseProvider, error) {
+	if baseURL == "" {
+		baseURL = DefaultGiteaURL
+	}
+	c, err := newRestClient(d, baseURL, "/api/v1", "token", envToken(token, "GITEA_TOKEN"))
+	if err != nil {
+		return nil, err
+	}
+	return &giteaProvider{c: c}, nil
+}
+
+// releasesPath returns the API path of the releases of owner/repo.
+func (p *giteaProvider) releasesPath(owner, repo string) string {
+	return "/repos/" + url.PathEscape(owner) + "/" + url.PathEscape(repo) + "/releases"
+}
+
+func (p *giteaProvider) ListReleases(ctx context.Context, owner, repo string, page, perPage int) ([]*github.RepositoryRelease, int, error) {
+	var releases []*github.RepositoryRelease
+	header, err := p.c.getJSON(ctx, p.releasesPath(owner, repo), pageQuery("limit", page, perPage), &releases)
+	if err != nil {
+		return nil, 0, err
+	}
+	for _, release := range releases {
+		fixGiteaAssets(release)
+	}
+	// Instances cap the page size, so rely on the Link header rather than
+	// on short pages.
+	return releases, nextPage(header.Get("Link")), nil
+}
+
+func (p *giteaProvider) GetLatest(ctx context.Context, owner, repo string) (*github.RepositoryRelease, error) {
+	var release github.RepositoryRelease
+	if _, err := p.c.getJSON(ctx, p.releasesPath(owner, repo)+"/latest", nil, &release); err != nil {
+		return nil, err
+	}
+	fixGiteaAssets(&release)
+	return &release, nil
+}
+
+func (p *giteaProvider) GetByTag(ctx context.Context, owner, repo, tag string) (*github.RepositoryRelease, error) {
+	var release github.RepositoryRelease
+	if _, err := p.c.getJSON(ctx, p.releasesPath(owner, repo)+"/tags/"+url.PathEscape(tag), nil, &release); err != nil {
+		return nil, err
+	}
+	fixGiteaAssets(&release)
+	return &release, nil
+}
+
+func (p *giteaProvider) DownloadAsset(ctx context.Context, asset *github.ReleaseAsset, offset int64) (*http.Response, error) {
+	return p.c.download(ctx, asset.GetURL(), offset)
+}
+
+// fixGiteaAssets points the assets of release at their download URL, as
+// Gitea attachments have no API URL.
+func fixGiteaAssets(release *github.RepositoryRelease) {
+	for _, asset := range release.Assets {
+		if asset.URL == nil {
+			asset.URL = asset.BrowserDownloadURL
+		}
+		if asset.UpdatedAt == nil {
+			asset.UpdatedAt = asset.CreatedAt
+		}
+	}
+}
+
+// nextPage returns the page number of the "next" link in a Link header, or
+// 0 if there is none.
+func nextPage(link string) int {
+	for _, part := range strings.Split(link, ",") {
+		target, params, ok := strings.Cut(part, ";")
+		if !ok || !relNext(params) {
+			continue
+		}
+		u, err := url.Parse(strings.Trim(strings.TrimSpace(target), "<>"))
+		if err != nil {
+			return 0
+		}
+		page, _ := strconv.Atoi(u.Query().Get("page"))
+		return page
+	}
+	return 0
+}
+
+// relNext reports whether the parameters of a Link header entry, such as
+// `rel="next"` or `rel="next last"; type="..."`, include the "next" relation.
+func relNext(params string) bool {
+	for _, param := range strings.Split(params, ";") {
+		key, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+		if !strings.EqualFold(strings.TrimSpace(key), "rel") {
+			continue
+		}
+		for _, rel := range strings.Fields(strings.Trim(strings.TrimSpace(value), `"`)) {
+			if strings.EqualFold(rel, "next") {
+				return true
+			}
+		}
+	}
+	return false
+}
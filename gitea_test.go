@@ -0,0 +1,102 @@
+package ghdownloader
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNextPage(t *testing.T) {
+	tests := []struct {
+		name string
+		link string
+		want int
+	}{
+		{
+			name: "next and last",
+			link: `<https://gitea.com/api/v1/repos/o/r/releases?limit=50&page=2>; rel="next", <https://gitea.com/api/v1/repos/o/r/releases?limit=50&page=5>; rel="last"`,
+			want: 2,
+		},
+		{
+			name: "next after last",
+			link: `<https://gitea.com/api/v1/repos/o/r/releases?page=5>; rel="last", <https://gitea.com/api/v1/repos/o/r/releases?page=3>; rel="next"`,
+			want: 3,
+		},
+		{
+			name: "last page",
+			link: `<https://gitea.com/api/v1/repos/o/r/releases?page=1>; rel="first", <https://gitea.com/api/v1/repos/o/r/releases?page=4>; rel="prev"`,
+			want: 0,
+		},
+		{
+			name: "unquoted relation",
+			link: `<https://gitea.com/api/v1/repos/o/r/releases?page=2>; rel=next`,
+			want: 2,
+		},
+		{
+			name: "several relations and parameters",
+			link: `<https://gitea.com/api/v1/repos/o/r/releases?page=2>; type="application/json"; rel="next last"`,
+			want: 2,
+		},
+		{
+			name: "relation containing next",
+			link: `<https://gitea.com/api/v1/repos/o/r/releases?page=2>; rel="nextish"`,
+			want: 0,
+		},
+		{
+			name: "no page parameter",
+			link: `<https://gitea.com/api/v1/repos/o/r/releases?cursor=abc>; rel="next"`,
+			want: 0,
+		},
+		{
+			name: "empty",
+			link: "",
+			want: 0,
+		},
+	}
+	for _, tt := range tests {
+		if got := nextPage(tt.link); got != tt.want {
+			t.Errorf("%s: nextPage = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestGiteaListReleasesPages(t *testing.T) {
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/repos/o/r/releases" {
+			http.NotFound(w, r)
+			return
+		}
+		page := r.URL.Query().Get("page")
+		if page == "1" {
+			w.Header().Set("Link", fmt.Sprintf(`<%s/api/v1/repos/o/r/releases?limit=1&page=2>; rel="next"`, srv.URL))
+		}
+		fmt.Fprintf(w, `[{"tag_name": "v%s.0.0", "assets": [{"name": "tool", "browser_download_url": "%s/tool"}]}]`, page, srv.URL)
+	}))
+	defer srv.Close()
+
+	d := newTestDownloader(t)
+	p, err := d.NewGiteaProvider(srv.URL, "token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tags []string
+	for page := 1; page != 0; {
+		releases, next, err := p.ListReleases(context.Background(), "o", "r", page, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, release := range releases {
+			tags = append(tags, release.GetTagName())
+			if got := release.Assets[0].GetURL(); got != srv.URL+"/tool" {
+				t.Errorf("asset URL = %q, want the download URL", got)
+			}
+		}
+		page = next
+	}
+	if len(tags) != 2 || tags[0] != "v1.0.0" || tags[1] != "v2.0.0" {
+		t.Errorf("listed %v, want [v1.0.0 v2.0.0]", tags)
+	}
+}
@@ -0,0 +1,139 @@
+package ghdownloader
+
+import (
+	"context"
+	"hash/fnv"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/google/go-github/v68/github"
+)
+
+// DefaultGitLabURL is the GitLab instance used when none is given.
+const DefaultGitLabURL = "https://gitlab.com"
+
+// gitlabRelease is a release as returned by the GitLab API.
+type gitlabRelease struct {
+	TagName     string    `json:"tag_name"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	CreatedAt   time.Time `json:"created_at"`
+	ReleasedAt  time.Time `json:"released_at"`
+	Upcoming    bool      `json:"upcoming_release"`
+	Assets      struct {
+		Sources []struct {
+			Format string `json:"format"`
+			URL    string `json:"url"`
+		} `json:"sources"`
+		Links []struct {
+			ID             int64  `json:"id"`
+			Name           string `json:"name"`
+			URL            string `json:"url"`
+			DirectAssetURL string `json:"direct_asset_url"`
+		} `json:"links"`
+	} `json:"assets"`
+	Links struct {
+		Self string `json:"self"`
+	} `json:"_links"`
+}
+
+// gitlabProvider fetches releases from a GitLab instance. Owners may be
+// nested groups, as in "group/subgroup/project".
+type gitlabProvider struct {
+	c *restClient
+}
+
+// NewGitLabProvider returns a provider for the GitLab instance at baseURL
+// (DefaultGitLabURL if empty), authenticating with token or, if that is
+// empty, the GITLAB_TOKEN environment variable. Requests go through the
+// downloader's HTTP client, retries and logging. Release links are the
+// assets; upcoming releases count as pre-releases.
+func (d *Downloader) NewGitLabProvider(baseURL, token string) (ReleaseProvider, error) {
+	if baseURL == "" {
+		baseURL = DefaultGitLabURL
+	}
+	c, err := newRestClient(d, baseURL, "/api/v4", "Bearer", envToken(token, "GITLAB_TOKEN"))
+	if err != nil {
+		return nil, err
+	}
+	return &gitlabProvider{c: c}, nil
+}
+
+// releasesPath returns the API path of the releases of owner/repo.
+func (p *gitlabProvider) releasesPath(owner, repo string) string {
+	return "/projects/" + url.PathEscape(owner+"/"+repo) + "/releases"
+}
+
+func (p *gitlabProvider) ListReleases(ctx context.Context, owner, repo string, page, perPage int) ([]*github.RepositoryRelease, int, error) {
+	var releases []gitlabRelease
+	header, err := p.c.getJSON(ctx, p.releasesPath(owner, repo), pageQuery("per_page", page, perPage), &releases)
+	if err != nil {
+		return nil, 0, err
+	}
+	converted := make([]*github.RepositoryRelease, len(releases))
+	for i := range releases {
+		converted[i] = releases[i].convert()
+	}
+	next, _ := strconv.Atoi(header.Get("X-Next-Page"))
+	return converted, next, nil
+}
+
+func (p *gitlabProvider) GetLatest(ctx context.Context, owner, repo string) (*github.RepositoryRelease, error) {
+	var release gitlabRelease
+	if _, err := p.c.getJSON(ctx, p.releasesPath(owner, repo)+"/permalink/latest", nil, &release); err != nil {
+		return nil, err
+	}
+	return release.convert(), nil
+}
+
+func (p *gitlabProvider) GetByTag(ctx context.Context, owner, repo, tag string) (*github.RepositoryRelease, error) {
+	var release gitlabRelease
+	if _, err := p.c.getJSON(ctx, p.releasesPath(owner, repo)+"/"+url.PathEscape(tag), nil, &release); err != nil {
+		return nil, err
+	}
+	return release.convert(), nil
+}
+
+func (p *gitlabProvider) DownloadAsset(ctx context.Context, asset *github.ReleaseAsset, offset int64) (*http.Response, error) {
+	return p.c.download(ctx, asset.GetURL(), offset)
+}
+
+// convert describes r as a GitHub release. GitLab releases have no ID, so
+// one is derived from the tag.
+func (r *gitlabRelease) convert() *github.RepositoryRelease {
+	id := fnv.New64a()
+	id.Write([]byte(r.TagName))
+	release := &github.RepositoryRelease{
+		ID:          github.Int64(int64(id.Sum64() >> 1)),
+		TagName:     github.String(r.TagName),
+		Name:        github.String(r.Name),
+		Body:        github.String(r.Description),
+		Prerelease:  github.Bool(r.Upcoming),
+		Draft:       github.Bool(false),
+		CreatedAt:   &github.Timestamp{Time: r.CreatedAt},
+		PublishedAt: &github.Timestamp{Time: r.ReleasedAt},
+		HTMLURL:     github.String(r.Links.Self),
+	}
+	for _, source := range r.Assets.Sources {
+		if source.Format == "tar.gz" {
+			release.TarballURL = github.String(source.URL)
+		}
+	}
+	for _, link := range r.Assets.Links {
+		assetURL := link.DirectAssetURL
+		if assetURL == "" {
+			assetURL = link.URL
+		}
+		release.Assets = append(release.Assets, &github.ReleaseAsset{
+			ID:                 github.Int64(link.ID),
+			Name:               github.String(link.Name),
+			URL:                github.String(assetURL),
+			BrowserDownloadURL: github.String(assetURL),
+			CreatedAt:          release.PublishedAt,
+			UpdatedAt:          release.PublishedAt,
+		})
+	}
+	return release
+}
@@ -0,0 +1,137 @@
+package ghdownloader
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// newFakeGitLab serves the releases of group/sub/tool, one per page, with
+// v2.0.0 as the latest.
+func newFakeGitLab(t *testing.T) *httptest.Server {
+	t.Helper()
+	var srv *httptest.Server
+	release := func(tag string, upcoming bool) string {
+		return fmt.Sprintf(`{"tag_name": %q, "upcoming_release": %v, "created_at": "2024-01-01T00:00:00Z",
+			"assets": {"links": [{"id": 7, "name": "tool", "url": "%s/other", "direct_asset_url": "%s/files/%s/tool"}]}}`,
+			tag, upcoming, srv.URL, srv.URL, tag)
+	}
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		const releases = "/api/v4/projects/group%2Fsub%2Ftool/releases"
+		switch r.URL.EscapedPath() {
+		case releases:
+			if r.URL.Query().Get("page") == "1" {
+				w.Header().Set("X-Next-Page", "2")
+				fmt.Fprintf(w, "[%s]", release("v3.0.0-rc.1", true))
+				return
+			}
+			fmt.Fprintf(w, "[%s]", release("v2.0.0", false))
+		case releases + "/permalink/latest":
+			fmt.Fprint(w, release("v2.0.0", false))
+		case "/files/v2.0.0/tool":
+			fmt.Fprint(w, "bin")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestGitLabListReleasesPages(t *testing.T) {
+	srv := newFakeGitLab(t)
+	d := newTestDownloader(t)
+	p, err := d.NewGitLabProvider(srv.URL, "token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tags []string
+	for page := 1; page != 0; {
+		releases, next, err := p.ListReleases(context.Background(), "group/sub", "tool", page, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, release := range releases {
+			tags = append(tags, release.GetTagName())
+			if release.GetPrerelease() != (release.GetTagName() == "v3.0.0-rc.1") {
+				t.Errorf("%s: prerelease = %v", release.GetTagName(), release.GetPrerelease())
+			}
+			if got, want := release.Assets[0].GetURL(), srv.URL+"/files/"+release.GetTagName()+"/tool"; got != want {
+				t.Errorf("asset URL = %q, want the direct asset URL %q", got, want)
+			}
+		}
+		page = next
+	}
+	if len(tags) != 2 || tags[0] != "v3.0.0-rc.1" || tags[1] != "v2.0.0" {
+		t.Errorf("listed %v, want [v3.0.0-rc.1 v2.0.0]", tags)
+	}
+}
+
+func TestGitLabDownloadLatest(t *testing.T) {
+	srv := newFakeGitLab(t)
+	d := newTestDownloader(t)
+	p, err := d.NewGitLabProvider(srv.URL, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.SetRepoProvider("group/sub/tool", p)
+
+	paths, err := d.DownloadLatestReleases([]string{"group/sub/tool"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := filepath.Join(d.destDir, "tool-v2.0.0", "tool")
+	if len(paths) != 1 || paths[0] != want {
+		t.Fatalf("downloaded %v, want %s", paths, want)
+	}
+	if data, err := os.ReadFile(want); err != nil || string(data) != "bin" {
+		t.Errorf("downloaded %q, %v", data, err)
+	}
+}
+
+func TestRegisterRepoURL(t *testing.T) {
+	tests := []struct {
+		url      string
+		spec     string
+		provider string
+	}{
+		{"https://gitlab.com/group/sub/tool", "group/sub/tool", "gitlab"},
+		{"https://gitlab.com/group/tool/-/releases", "group/tool", "gitlab"},
+		{"https://gitlab.example.com/group/tool.git@v1.2.0", "group/tool@v1.2.0", "gitlab"},
+		{"https://codeberg.org/owner/tool", "owner/tool", "gitea"},
+		{"https://github.com/owner/tool@^1.4", "owner/tool@^1.4", "github"},
+	}
+	for _, tt := range tests {
+		d := newTestDownloader(t)
+		spec, err := d.RegisterRepoURL(tt.url)
+		if err != nil {
+			t.Errorf("RegisterRepoURL(%q): %v", tt.url, err)
+			continue
+		}
+		if spec != tt.spec {
+			t.Errorf("RegisterRepoURL(%q) = %q, want %q", tt.url, spec, tt.spec)
+		}
+		owner, repo, _, _ := parseRepoSpec(spec)
+		var got string
+		switch d.provider(owner, repo).(type) {
+		case *gitlabProvider:
+			got = "gitlab"
+		case *giteaProvider:
+			got = "gitea"
+		case *githubProvider:
+			got = "github"
+		}
+		if got != tt.provider {
+			t.Errorf("RegisterRepoURL(%q) registered %s, want %s", tt.url, got, tt.provider)
+		}
+	}
+	for _, rawURL := range []string{"ftp://gitlab.com/group/tool", "https://gitlab.com/tool", "https:///group/tool"} {
+		if _, err := newTestDownloader(t).RegisterRepoURL(rawURL); err == nil {
+			t.Errorf("RegisterRepoURL(%q) succeeded", rawURL)
+		}
+	}
+}
@@ -5,6 +5,7 @@ go 1.21.5
 require (
 	github.com/google/go-github/v68 v68.0.0
 	golang.org/x/oauth2 v0.25.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require github.com/google/go-querystring v1.1.0 // indirect
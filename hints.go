@@ -0,0 +1,137 @@
+package ghdownloader
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"text/template"
+
+	"github.com/google/go-github/v68/github"
+	"gopkg.in/yaml.v3"
+)
+
+// hintFiles are the files a repository can publish to describe its asset
+// naming scheme, in order of preference.
+var hintFiles = []string{".ghdownloader.yml", ".ghdownloader.yaml"}
+
+// AssetHints describes how a repository names its release assets. The format
+// follows the aqua registry: Asset is a template rendered with .Version (the
+// tag), .SemVer (the tag without a leading "v"), .OS and .Arch, where OS and
+// Arch are Go platform names passed through Replacements. Overrides replace
+// the asset template for specific platforms.
+type AssetHints struct {
+	Asset        string            `yaml:"asset"`
+	Replacements map[string]string `yaml:"replacements"`
+	Overrides    []AssetOverride   `yaml:"overrides"`
+}
+
+// AssetOverride replaces the asset template for a GOOS and/or GOARCH.
+type AssetOverride struct {
+	GOOS         string            `yaml:"goos"`
+	GOARCH       string            `yaml:"goarch"`
+	Asset        string            `yaml:"asset"`
+	Replacements map[string]string `yaml:"replacements"`
+}
+
+// SetUseAssetHints enables reading an upstream hints file (.ghdownloader.yml)
+// to select the asset for the current platform when no match filter is set.
+func (d *Downloader) SetUseAssetHints(useHints bool) {
+	d.useHints = useHints
+}
+
+// AssetName renders the asset name for the given tag and platform.
+func (h *AssetHints) AssetName(tag, goos, goarch string) (string, error) {
+	asset := h.Asset
+	replacements := make(map[string]string, len(h.Replacements))
+	for k, v := range h.Replacements {
+		replacements[k] = v
+	}
+	for _, o := range h.Overrides {
+		if (o.GOOS == "" || o.GOOS == goos) && (o.GOARCH == "" || o.GOARCH == goarch) {
+			if o.Asset != "" {
+				asset = o.Asset
+			}
+			for k, v := range o.Replacements {
+				replacements[k] = v
+			}
+		}
+	}
+	if asset == "" {
+		return "", fmt.Errorf("hints do not define an asset name")
+	}
+
+	tmpl, err := template.New("asset").Funcs(template.FuncMap{
+		"trimV": func(s string) string { return strings.TrimPrefix(s, "v") },
+	}).Parse(asset)
+	if err != nil {
+		return "", fmt.Errorf("invalid asset template: %v", err)
+	}
+
+	replace := func(s string) string {
+		if r, ok := replacements[s]; ok {
+			return r
+		}
+		return s
+	}
+	var buf bytes.Buffer
+	err = tmpl.Execute(&buf, map[string]string{
+		"Version": tag,
+		"SemVer":  strings.TrimPrefix(tag, "v"),
+		"OS":      replace(goos),
+		"Arch":    replace(goarch),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to render asset template: %v", err)
+	}
+	return buf.String(), nil
+}
+
+// fetchAssetHints reads the hints file published by owner/repo at ref. It
+// returns nil if the repository does not publish one.
+func (d *Downloader) fetchAssetHints(ctx context.Context, owner, repo, ref string) (*AssetHints, error) {
+	for _, name := range hintFiles {
+		file, _, resp, err := d.client.Repositories.GetContents(ctx, owner, repo, name,
+			&github.RepositoryContentGetOptions{Ref: ref})
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error fetching '%s': %v", name, err)
+		}
+		if file == nil {
+			continue
+		}
+
+		content, err := file.GetContent()
+		if err != nil {
+			return nil, fmt.Errorf("error decoding '%s': %v", name, err)
+		}
+		var hints AssetHints
+		if err := yaml.Unmarshal([]byte(content), &hints); err != nil {
+			return nil, fmt.Errorf("error parsing '%s': %v", name, err)
+		}
+		return &hints, nil
+	}
+	return nil, nil
+}
+
+// hintedAssets narrows assets down to the one named by hints for the selected
+// platform. It returns nil when the hints don't name a usable asset, in which
+// case all assets are considered.
+func (d *Downloader) hintedAssets(owner, repo, tag string, hints *AssetHints, assets []*github.ReleaseAsset) []*github.ReleaseAsset {
+	goos, goarch := d.platform()
+	name, err := hints.AssetName(tag, goos, goarch)
+	if err != nil {
+		d.warnf("ignoring asset hints for %s/%s: %v", owner, repo, err)
+		return nil
+	}
+	for _, asset := range assets {
+		if asset.GetName() == name {
+			return []*github.ReleaseAsset{asset}
+		}
+	}
+	d.warnf("asset '%s' named by hints for %s/%s not found in release", name, owner, repo)
+	return nil
+}
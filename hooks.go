@@ -0,0 +1,67 @@
+package ghdownloader
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+// ErrSkipAsset can be returned by the OnAssetStart hook to leave an asset out
+// without reporting it as failed.
+var ErrSkipAsset = errors.New("skip this asset")
+
+// Hooks let applications embedding the downloader observe and veto asset
+// downloads, e.g. to record metrics, publish events or refuse files over a
+// size limit. Any hook may be nil. Assets of a release are downloaded
+// concurrently, so hooks must be safe to call from several goroutines.
+type Hooks struct {
+	// OnAssetStart is called before an asset is downloaded, with Path set to
+	// where it will be saved. Returning ErrSkipAsset leaves the asset out;
+	// any other error rejects it, reporting the asset as failed with an
+	// error wrapping it. Nothing is written in either case.
+	OnAssetStart func(asset AssetInfo) error
+	// OnAssetComplete is called for each file saved, or already present
+	// (with Skipped set), once its release is in place, with its size and
+	// digest filled in.
+	OnAssetComplete func(result DownloadResult)
+	// OnAssetError is called for each asset that failed to download,
+	// including assets rejected by OnAssetStart.
+	OnAssetError func(result DownloadResult)
+}
+
+// SetHooks sets the hooks called as assets are downloaded, replacing any
+// set before.
+func (d *Downloader) SetHooks(hooks Hooks) {
+	d.hooks = hooks
+}
+
+// startAsset asks the OnAssetStart hook whether asset may be downloaded.
+func (d *Downloader) startAsset(asset AssetInfo, forceDownload bool) error {
+	if d.hooks.OnAssetStart == nil {
+		return nil
+	}
+	if _, err := os.Stat(asset.Path); err == nil && !forceDownload {
+		asset.Exists = true
+	}
+	if err := d.hooks.OnAssetStart(asset); err != nil {
+		if errors.Is(err, ErrSkipAsset) {
+			return err
+		}
+		return fmt.Errorf("rejected by OnAssetStart: %w", err)
+	}
+	return nil
+}
+
+// finishAssets passes the results of assets to the OnAssetComplete and
+// OnAssetError hooks. Results for whole repositories are left out.
+func (d *Downloader) finishAssets(results []DownloadResult) {
+	for _, r := range results {
+		switch {
+		case r.AssetName == "":
+		case r.Err != nil && d.hooks.OnAssetError != nil:
+			d.hooks.OnAssetError(r)
+		case r.Err == nil && d.hooks.OnAssetComplete != nil:
+			d.hooks.OnAssetComplete(r)
+		}
+	}
+}
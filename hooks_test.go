@@ -0,0 +1,77 @@
+package ghdownloader
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+)
+
+func TestHooks(t *testing.T) {
+	g := newFakeGitHub(t)
+	g.addRelease("owner/tool", "v1.0.0", map[string]string{"tool": "bin", "tool.iso": "big", "README.md": "docs"})
+	errTooLarge := errors.New("too large")
+
+	var mu sync.Mutex
+	var started, completed, failed []string
+	d := g.downloader(t)
+	d.SetHooks(Hooks{
+		OnAssetStart: func(asset AssetInfo) error {
+			mu.Lock()
+			defer mu.Unlock()
+			started = append(started, asset.Name)
+			switch asset.Name {
+			case "README.md":
+				return ErrSkipAsset
+			case "tool.iso":
+				return errTooLarge
+			}
+			if asset.Path != filepath.Join(d.destDir, "tool-v1.0.0", "tool") || asset.Exists {
+				t.Errorf("OnAssetStart got %+v", asset)
+			}
+			return nil
+		},
+		OnAssetComplete: func(r DownloadResult) {
+			mu.Lock()
+			defer mu.Unlock()
+			completed = append(completed, r.AssetName)
+			if r.Size != 3 || r.SHA256 == "" {
+				t.Errorf("OnAssetComplete got %+v without size and digest", r)
+			}
+		},
+		OnAssetError: func(r DownloadResult) {
+			mu.Lock()
+			defer mu.Unlock()
+			failed = append(failed, r.AssetName)
+			if !errors.Is(r.Err, errTooLarge) {
+				t.Errorf("OnAssetError got %v, want an error wrapping %v", r.Err, errTooLarge)
+			}
+		},
+	})
+
+	if _, err := d.DownloadLatestReleases([]string{"owner/tool"}); err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(started)
+	if len(started) != 3 || started[0] != "README.md" || started[1] != "tool" || started[2] != "tool.iso" {
+		t.Errorf("OnAssetStart called for %v, want every asset", started)
+	}
+	if len(completed) != 1 || completed[0] != "tool" {
+		t.Errorf("OnAssetComplete called for %v, want [tool]", completed)
+	}
+	if len(failed) != 1 || failed[0] != "tool.iso" {
+		t.Errorf("OnAssetError called for %v, want [tool.iso]", failed)
+	}
+	for _, name := range []string{"README.md", "tool.iso"} {
+		if _, err := os.Stat(filepath.Join(d.destDir, "tool-v1.0.0", name)); err == nil {
+			t.Errorf("'%s' was saved despite OnAssetStart", name)
+		}
+	}
+	for _, r := range d.Results() {
+		if r.AssetName == "README.md" {
+			t.Errorf("skipped asset reported as %+v", r)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package ghdownloader
+
+import (
+	"context"
+	"io"
+	"net/http"
+)
+
+// SetHTTPClient makes the downloader send all requests, API calls and asset
+// transfers alike, through client's Transport (http.DefaultTransport if nil),
+// giving callers control over proxies and TLS, e.g. to trust a corporate
+// CA. A non-zero client.Timeout limits every request, including reading its
+// response, so it should leave room for the largest asset; see
+// SetStallWatchdog for aborting stalled transfers instead. Retries, logging
+// and authentication still apply. A nil client restores the default. Proxies
+// are taken from HTTPS_PROXY and related variables by http.DefaultTransport,
+// and by transports that set Proxy to http.ProxyFromEnvironment.
+func (d *Downloader) SetHTTPClient(client *http.Client) {
+	d.httpClient = client
+}
+
+// baseTransport sends requests through the transport of the client set with
+// SetHTTPClient, enforcing its timeout.
+type baseTransport struct {
+	d *Downloader
+}
+
+func (t *baseTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	client := t.d.httpClient
+	if client == nil {
+		return http.DefaultTransport.RoundTrip(req)
+	}
+	next := client.Transport
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	if client.Timeout <= 0 {
+		return next.RoundTrip(req)
+	}
+
+	ctx, cancel := context.WithTimeout(req.Context(), client.Timeout)
+	resp, err := next.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	// The timeout covers reading the body, so it ends when the body is
+	// closed.
+	resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// cancelBody cancels a request's context once its response body is closed.
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
@@ -0,0 +1,186 @@
+package ghdownloader
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// installSkipSuffixes mark files that are never installed, such as
+// documentation shipped next to the binaries.
+var installSkipSuffixes = []string{".txt", ".md", ".json", ".yaml", ".yml", ".html", ".1"}
+
+// SetInstallDir installs the executables of each downloaded release into dir
+// (e.g. "~/bin" expanded by the caller), marked executable, atomically
+// replacing any previously installed version. The versioned copy under the
+// download directory is kept. If symlink is set, links to the versioned
+// files are installed instead of copies (copies are always used on
+// Windows). An empty dir disables installing.
+func (d *Downloader) SetInstallDir(dir string, symlink bool) {
+	d.installDir, d.installSymlink = dir, symlink
+}
+
+// SetRepoInstallDir installs the executables of a single "owner/repo" into
+// dir instead of the directory set with SetInstallDir, even if none is set.
+func (d *Downloader) SetRepoInstallDir(userRepo, dir string, symlink bool) {
+	d.repoInstall[strings.ToLower(userRepo)] = installTarget{dir: dir, symlink: symlink}
+}
+
+// installTarget is where, and how, the executables of a repository are
+// installed.
+type installTarget struct {
+	dir     string
+	symlink bool
+}
+
+// installTargetFor returns the install target of key; an empty dir means
+// nothing is installed.
+func (d *Downloader) installTargetFor(key string) installTarget {
+	if target, ok := d.repoInstall[key]; ok {
+		return target
+	}
+	return installTarget{dir: d.installDir, symlink: d.installSymlink}
+}
+
+// SetInstallName installs the executable of a single "owner/repo" under
+// name. The release must then contain exactly one executable.
+func (d *Downloader) SetInstallName(userRepo, name string) {
+	d.installNames[strings.ToLower(userRepo)] = name
+}
+
+// installResults places the executables among results into the install
+// directory of key, naming them as configured for key. It returns the
+// installed files, mapped to the files they were installed from.
+func (d *Downloader) installResults(key, repo string, results []DownloadResult) (map[string]string, error) {
+	var candidates []DownloadResult
+	for _, r := range results {
+		if isInstallable(r) {
+			candidates = append(candidates, r)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("no executable to install")
+	}
+	name, renamed := d.installNames[key]
+	if renamed && len(candidates) > 1 {
+		return nil, fmt.Errorf("cannot install %d executables as '%s'", len(candidates), name)
+	}
+	dest := d.installTargetFor(key)
+	if err := os.MkdirAll(dest.dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create install directory: %v", err)
+	}
+
+	installed := make(map[string]string)
+	for _, r := range candidates {
+		if !renamed {
+			name = installName(repo, r, len(candidates) == 1)
+		}
+		target := filepath.Join(dest.dir, name)
+		if err := installFile(r.Path, target, dest.symlink); err != nil {
+			return nil, fmt.Errorf("failed to install '%s': %v", target, err)
+		}
+		d.infof("Installed '%s' as '%s'", r.Path, target)
+		installed[target] = r.Path
+	}
+	return installed, nil
+}
+
+// isInstallable reports whether the file of r looks like an executable.
+// Files extracted from archives must be marked executable; downloaded
+// assets only must not look like archives, packages, signatures or docs.
+func isInstallable(r DownloadResult) bool {
+	if r.Err != nil || r.Path == "" {
+		return false
+	}
+	lower := strings.ToLower(filepath.Base(r.Path))
+	if isArchive(lower) || isChecksumAsset(lower) || isSignatureAsset(lower) ||
+		hasAnySuffix(lower, packageSuffixes) || hasAnySuffix(lower, platformSkipSuffixes) ||
+		hasAnySuffix(lower, installSkipSuffixes) {
+		return false
+	}
+	info, err := os.Stat(r.Path)
+	if err != nil || !info.Mode().IsRegular() {
+		return false
+	}
+	if isArchive(r.AssetName) {
+		return info.Mode()&0111 != 0 || strings.HasSuffix(lower, ".exe")
+	}
+	return true
+}
+
+// installName returns the name the file of r is installed as. A lone asset
+// named after its platform, such as "jq-linux-amd64", is installed under the
+// repository name instead.
+func installName(repo string, r DownloadResult, only bool) string {
+	base := filepath.Base(r.Path)
+	if !only || isArchive(r.AssetName) {
+		return base
+	}
+	tokens := assetTokens(base)
+	if !hasAnyArch(tokens) && !hasAnyToken(tokens, osNames()) {
+		return base
+	}
+	if strings.HasSuffix(strings.ToLower(base), ".exe") {
+		return repo + ".exe"
+	}
+	return repo
+}
+
+// osNames returns the Go names of the operating systems assets are built for.
+func osNames() []string {
+	names := make([]string, 0, len(osAliases))
+	for goos := range osAliases {
+		names = append(names, goos)
+	}
+	return names
+}
+
+// installFile atomically replaces target with an executable copy of, or a
+// link to, src.
+func installFile(src, target string, symlink bool) error {
+	if symlink && runtime.GOOS != "windows" {
+		if err := os.Chmod(src, 0755); err != nil {
+			return err
+		}
+		absSrc, err := filepath.Abs(src)
+		if err != nil {
+			return err
+		}
+		absTarget, err := filepath.Abs(target)
+		if err != nil {
+			return err
+		}
+		return replaceLink(absSrc, absTarget)
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	tmp, err := os.CreateTemp(filepath.Dir(target), "."+filepath.Base(target)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := io.Copy(tmp, in); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0755); err != nil {
+		return err
+	}
+	// Windows can't rename over a file that is in use, but can move it
+	// out of the way first.
+	if runtime.GOOS == "windows" {
+		os.Remove(target + ".old")
+		os.Rename(target, target+".old")
+	}
+	return os.Rename(tmp.Name(), target)
+}
@@ -0,0 +1,93 @@
+package ghdownloader
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestInstallExecutables(t *testing.T) {
+	g := newFakeGitHub(t)
+	g.addRelease("owner/tool", "v1.0.0", map[string]string{"tool-linux-amd64": "v1", "README.md": "docs", "checksums.txt": "sums"})
+	binDir := filepath.Join(t.TempDir(), "bin")
+	d := g.downloader(t)
+	d.SetInstallDir(binDir, false)
+	if _, err := d.DownloadLatestReleases([]string{"owner/tool"}); err != nil {
+		t.Fatal(err)
+	}
+	// A lone asset named after its platform is installed under the
+	// repository name, leaving the docs and checksums behind.
+	if names := listDir(t, binDir); !slices.Equal(names, []string{"tool"}) {
+		t.Fatalf("installed %q, want [tool]", names)
+	}
+	target := filepath.Join(binDir, "tool")
+	info, err := os.Stat(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if runtime.GOOS != "windows" && info.Mode().Perm()&0111 == 0 {
+		t.Errorf("installed tool isn't executable: %s", info.Mode())
+	}
+
+	// A new release replaces the installed copy; the old one stays versioned.
+	g.addRelease("owner/tool", "v2.0.0", map[string]string{"tool-linux-amd64": "v2"})
+	if _, err := d.DownloadLatestReleases([]string{"owner/tool"}); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := os.ReadFile(target); err != nil || string(data) != "v2" {
+		t.Errorf("installed %q, %v, want v2", data, err)
+	}
+	if _, err := os.Stat(filepath.Join(d.destDir, "tool-v1.0.0", "tool-linux-amd64")); err != nil {
+		t.Errorf("versioned copy of v1.0.0: %v", err)
+	}
+}
+
+func TestInstallSymlinks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("copies are always installed on Windows")
+	}
+	g := newFakeGitHub(t)
+	g.addRelease("owner/tool", "v1.0.0", map[string]string{"tool": "bin", "helper": "bin"})
+	binDir := filepath.Join(t.TempDir(), "bin")
+	d := g.downloader(t)
+	d.SetInstallDir(binDir, true)
+	if _, err := d.DownloadLatestReleases([]string{"owner/tool"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"helper", "tool"} {
+		target, err := readLinkTarget(filepath.Join(binDir, name))
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if want, _ := filepath.Abs(filepath.Join(d.destDir, "tool-v1.0.0", name)); target != want {
+			t.Errorf("%s links to '%s', want '%s'", name, target, want)
+		}
+	}
+}
+
+func TestInstallName(t *testing.T) {
+	g := newFakeGitHub(t)
+	g.addRelease("owner/tool", "v1.0.0", map[string]string{"tool": "bin"})
+	g.addRelease("owner/multi", "v1.0.0", map[string]string{"a": "a", "b": "b"})
+	defaultDir, toolDir := filepath.Join(t.TempDir(), "bin"), filepath.Join(t.TempDir(), "tools")
+	d := g.downloader(t)
+	d.SetInstallDir(defaultDir, false)
+	d.SetRepoInstallDir("Owner/Tool", toolDir, false)
+	d.SetInstallName("owner/tool", "renamed")
+	d.SetInstallName("owner/multi", "one")
+
+	_, err := d.DownloadLatestReleases([]string{"owner/tool", "owner/multi"})
+	if err == nil || !strings.Contains(err.Error(), "cannot install 2 executables as 'one'") {
+		t.Errorf("DownloadLatestReleases: %v, want an error for renaming two executables", err)
+	}
+	if names := listDir(t, toolDir); !slices.Equal(names, []string{"renamed"}) {
+		t.Errorf("installed %q into the repository's directory, want [renamed]", names)
+	}
+	if _, err := os.Stat(defaultDir); err == nil {
+		t.Errorf("installed %q into the default directory", listDir(t, defaultDir))
+	}
+}
@@ -0,0 +1,186 @@
+package ghdownloader
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// DefaultPathTemplate is the layout of downloads in the destination
+// directory: one "<repo>-<tag>" directory per version.
+const DefaultPathTemplate = "{{.Repo}}-{{.Tag}}/{{.Asset}}"
+
+// Placeholders rendered into the path template to find where its fields end
+// up. They can't occur in real names.
+const (
+	ownerPlaceholder = "\x00owner\x00"
+	tagPlaceholder   = "\x00tag\x00"
+	assetPlaceholder = "\x00asset\x00"
+)
+
+// versionTagPattern matches the tags of version directories, which must start
+// with a version number. Otherwise "foo-bar-v1.0.0" of repository foo-bar
+// would be taken for version "bar-v1.0.0" of repository foo.
+var versionTagPattern = regexp.MustCompile(`^[vV]?[0-9]`)
+
+// defaultPathTemplate is DefaultPathTemplate, parsed.
+var defaultPathTemplate = template.Must(template.New("path").Option("missingkey=error").Parse(DefaultPathTemplate))
+
+// pathFields are the fields available to path templates.
+type pathFields struct {
+	Owner string
+	Repo  string
+	Tag   string
+	Asset string
+}
+
+// SetPathTemplate sets where assets are saved, relative to the destination
+// directory (and the subdirectory set with SetRepoDestDir), as a
+// text/template with the fields .Owner, .Repo, .Tag and .Asset. The template
+// must end with the asset name as its own path element; the directory before
+// it is the version directory. For example,
+// "{{.Owner}}/{{.Repo}}/{{.Tag}}/{{.Asset}}" organizes downloads by owner,
+// "{{.Repo}}/{{.Asset}}" keeps stable paths across versions and "{{.Asset}}"
+// flattens everything into the destination directory. In layouts without the
+// tag in the directory, an existing file is downloaded again when the asset
+// was updated after it, and pruning, blue/green installs and finding the
+// version on disk don't apply. An empty template restores
+// DefaultPathTemplate.
+func (d *Downloader) SetPathTemplate(tmpl string) error {
+	if tmpl == "" {
+		tmpl = DefaultPathTemplate
+	}
+	t, err := template.New("path").Option("missingkey=error").Parse(tmpl)
+	if err != nil {
+		return fmt.Errorf("invalid path template: %v", err)
+	}
+
+	// Check the shape of the paths the template produces.
+	var b strings.Builder
+	if err := t.Execute(&b, pathFields{Owner: "owner", Repo: "repo", Tag: "v1.0.0", Asset: assetPlaceholder}); err != nil {
+		return fmt.Errorf("invalid path template: %v", err)
+	}
+	path := filepath.FromSlash(b.String())
+	dir, name := filepath.Split(path)
+	if name != assetPlaceholder || strings.Contains(dir, assetPlaceholder) {
+		return fmt.Errorf("invalid path template '%s': it must end with '{{.Asset}}' as its own path element", tmpl)
+	}
+	if !filepath.IsLocal(strings.Replace(path, assetPlaceholder, "asset", 1)) {
+		return fmt.Errorf("invalid path template '%s': paths must stay within the destination directory", tmpl)
+	}
+	d.pathTemplate = t
+	return nil
+}
+
+// renderDir returns the version directory the path template gives for
+// fields, relative to the subdirectory of the repository.
+func (d *Downloader) renderDir(fields pathFields) (string, error) {
+	fields.Asset = assetPlaceholder
+	var b strings.Builder
+	if err := d.pathTemplate.Execute(&b, fields); err != nil {
+		return "", err
+	}
+	return filepath.Dir(filepath.FromSlash(b.String())), nil
+}
+
+// versionDir returns the directory the assets of the release of owner/repo
+// tagged tag are saved in.
+func (d *Downloader) versionDir(key, owner, repo, tag string) (string, error) {
+	dir, err := d.renderDir(pathFields{Owner: owner, Repo: repo, Tag: tag})
+	if err != nil {
+		return "", fmt.Errorf("failed to apply path template: %v", err)
+	}
+	dir = filepath.Join(d.repoDest[key], dir)
+	if !filepath.IsLocal(dir) {
+		return "", fmt.Errorf("version directory '%s' is outside the destination directory", dir)
+	}
+	return filepath.Join(d.destDir, dir), nil
+}
+
+// versionedLayout reports whether the path template puts each version in a
+// directory of its own.
+func (d *Downloader) versionedLayout() bool {
+	dir, err := d.renderDir(pathFields{Owner: "owner", Repo: "repo", Tag: tagPlaceholder})
+	return err == nil && strings.Contains(dir, tagPlaceholder)
+}
+
+// versionPattern returns a glob matching the version directories of repo,
+// owned by owner or by anyone if owner is empty, and a regexp extracting the
+// tag from their paths. It returns false if the layout has no version
+// directories.
+func (d *Downloader) versionPattern(key, owner, repo string) (string, *regexp.Regexp, bool) {
+	if owner == "" {
+		owner = ownerPlaceholder
+	}
+	dir, err := d.renderDir(pathFields{Owner: owner, Repo: repo, Tag: tagPlaceholder})
+	if err != nil || !strings.Contains(dir, tagPlaceholder) {
+		return "", nil, false
+	}
+	dir = filepath.Join(d.destDir, d.repoDest[key], dir)
+
+	glob := strings.NewReplacer(ownerPlaceholder, "*", tagPlaceholder, "*").Replace(dir)
+	// Only the first occurrence of the tag is captured.
+	pattern := strings.Replace(regexp.QuoteMeta(dir), tagPlaceholder, "(.+)", 1)
+	pattern = strings.NewReplacer(ownerPlaceholder, `[^/\\]+`, tagPlaceholder, ".+").Replace(pattern)
+	re, err := regexp.Compile("^" + pattern + "$")
+	if err != nil {
+		return "", nil, false
+	}
+	return glob, re, true
+}
+
+// versionEntry is a version directory in destDir.
+type versionEntry struct {
+	path    string
+	tag     string
+	modTime time.Time
+}
+
+// versionDirs returns the version directories of repo, owned by owner or by
+// anyone if owner is empty, most recent first: by tag if every tag is a
+// semantic version, and by modification time otherwise. Only directories
+// whose tag starts with a version number are returned.
+func (d *Downloader) versionDirs(key, owner, repo string) ([]versionEntry, error) {
+	glob, tagPattern, ok := d.versionPattern(key, owner, repo)
+	if !ok {
+		return nil, nil
+	}
+	paths, err := filepath.Glob(glob)
+	if err != nil {
+		return nil, err
+	}
+	var dirs []versionEntry
+	bySemver := true
+	for _, path := range paths {
+		// Lstat skips the "current" and "previous" links.
+		info, err := os.Lstat(path)
+		if err != nil || !info.IsDir() || strings.HasSuffix(path, ".staging") {
+			continue
+		}
+		m := tagPattern.FindStringSubmatch(path)
+		if m == nil || !versionTagPattern.MatchString(m[1]) {
+			continue
+		}
+		if _, ok := parseTagVersion(m[1]); !ok {
+			bySemver = false
+		}
+		dirs = append(dirs, versionEntry{path: path, tag: m[1], modTime: info.ModTime()})
+	}
+
+	sort.SliceStable(dirs, func(i, j int) bool {
+		if bySemver {
+			v, _ := parseTagVersion(dirs[i].tag)
+			w, _ := parseTagVersion(dirs[j].tag)
+			if c := v.compare(w); c != 0 {
+				return c > 0
+			}
+		}
+		return dirs[i].modTime.After(dirs[j].modTime)
+	})
+	return dirs, nil
+}
@@ -0,0 +1,44 @@
+package ghdownloader
+
+import (
+	"fmt"
+)
+
+// SetUpdateCurrentLink enables maintaining a "current" link that points at
+// the most recently downloaded version directory, giving consumers a stable
+// path across upgrades. The link sits where the path template would put a
+// version tagged "current": "<repo>-current" in destDir by default, or
+// "<owner>/<repo>/current" with "{{.Owner}}/{{.Repo}}/{{.Tag}}/{{.Asset}}".
+func (d *Downloader) SetUpdateCurrentLink(update bool) {
+	d.updateCurrentLink = update
+}
+
+// currentLinkPath returns the path of the "current" link of owner/repo,
+// requested as key.
+func (d *Downloader) currentLinkPath(key, owner, repo string) (string, error) {
+	return d.linkPath(key, owner, repo, "current")
+}
+
+// linkPath returns the path of the link called name of owner/repo, which is
+// where the path template puts the version directory tagged name, so links
+// are kept apart exactly like the versions they point at.
+func (d *Downloader) linkPath(key, owner, repo, name string) (string, error) {
+	if !d.versionedLayout() {
+		return "", fmt.Errorf("'%s' links need the tag in the directory of the path template", name)
+	}
+	return d.versionDir(key, owner, repo, name)
+}
+
+// updateCurrent atomically points the "current" link of owner/repo at
+// versionDir.
+func (d *Downloader) updateCurrent(key, owner, repo, versionDir string) error {
+	linkPath, err := d.currentLinkPath(key, owner, repo)
+	if err != nil {
+		return err
+	}
+	if err := replaceLink(versionDir, linkPath); err != nil {
+		return fmt.Errorf("failed to update '%s': %v", linkPath, err)
+	}
+	d.infof("Updated '%s' to point at '%s'", linkPath, versionDir)
+	return nil
+}
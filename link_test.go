@@ -0,0 +1,101 @@
+package ghdownloader
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestCurrentLinkFollowsPathTemplate(t *testing.T) {
+	g := newFakeGitHub(t)
+	g.addRelease("alice/tool", "v1.0.0", map[string]string{"tool": "alice"})
+	g.addRelease("bob/tool", "v2.0.0", map[string]string{"tool": "bob"})
+
+	d := g.downloader(t)
+	if err := d.SetPathTemplate("{{.Owner}}/{{.Repo}}/{{.Tag}}/{{.Asset}}"); err != nil {
+		t.Fatal(err)
+	}
+	d.SetUpdateCurrentLink(true)
+	if _, err := d.DownloadLatestReleases([]string{"alice/tool", "bob/tool"}); err != nil {
+		t.Fatal(err)
+	}
+	for owner, data := range map[string]string{"alice": "alice", "bob": "bob"} {
+		got, err := os.ReadFile(filepath.Join(d.destDir, owner, "tool", "current", "tool"))
+		if err != nil || string(got) != data {
+			t.Errorf("%s/tool/current/tool = %q, %v, want %q", owner, got, err, data)
+		}
+	}
+
+	// Without a tag in the directory there's nowhere to put the link.
+	flat := g.downloader(t)
+	if err := flat.SetPathTemplate("{{.Repo}}/{{.Asset}}"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := flat.currentLinkPath("alice/tool", "alice", "tool"); err == nil {
+		t.Error("expected an error for a path template without the tag")
+	}
+}
+
+func TestBlueGreenRollback(t *testing.T) {
+	g := newFakeGitHub(t)
+	g.addRelease("owner/tool", "v1.0.0", map[string]string{"tool": "v1"})
+	d := g.downloader(t)
+	d.SetBlueGreen(true)
+	if _, err := d.DownloadLatestReleases([]string{"owner/tool"}); err != nil {
+		t.Fatal(err)
+	}
+	g.addRelease("owner/tool", "v2.0.0", map[string]string{"tool": "v2"})
+	if _, err := d.DownloadLatestReleases([]string{"owner/tool"}); err != nil {
+		t.Fatal(err)
+	}
+
+	live := func() string {
+		t.Helper()
+		data, err := os.ReadFile(filepath.Join(d.destDir, "tool-current", "tool"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+	if got := live(); got != "v2" {
+		t.Fatalf("promoted %q, want v2", got)
+	}
+	if _, err := os.Stat(stagingDir(filepath.Join(d.destDir, "tool-v2.0.0"))); err == nil {
+		t.Error("the staging directory was left behind")
+	}
+
+	if err := d.Rollback("owner/tool"); err != nil {
+		t.Fatal(err)
+	}
+	if got := live(); got != "v1" {
+		t.Errorf("rolled back to %q, want v1", got)
+	}
+	if err := d.Rollback("owner/tool"); err != nil {
+		t.Fatal(err)
+	}
+	if got := live(); got != "v2" {
+		t.Errorf("rolled forward to %q, want v2", got)
+	}
+	if err := d.Rollback("tool"); err == nil {
+		t.Error("expected an error for a repository without an owner")
+	}
+}
+
+func TestBlueGreenSmokeTestFailure(t *testing.T) {
+	if _, err := exec.LookPath("false"); err != nil {
+		t.Skip("no false command")
+	}
+	g := newFakeGitHub(t)
+	g.addRelease("owner/tool", "v1.0.0", map[string]string{"tool": "v1"})
+	d := g.downloader(t)
+	d.SetBlueGreen(true)
+	d.SetSmokeTest("false")
+
+	if _, err := d.DownloadLatestReleases([]string{"owner/tool"}); err == nil {
+		t.Fatal("a release failing the smoke test was promoted")
+	}
+	if names := listDir(t, d.destDir); len(names) != 0 {
+		t.Errorf("left %q behind", names)
+	}
+}
@@ -0,0 +1,29 @@
+//go:build !windows
+
+package ghdownloader
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// replaceLink atomically replaces linkPath with a symlink to target by
+// creating the new link under a temporary name and renaming it into place.
+func replaceLink(target, linkPath string) error {
+	// Use a relative target so the tree can be moved as a whole.
+	if rel, err := filepath.Rel(filepath.Dir(linkPath), target); err == nil {
+		target = rel
+	}
+
+	tmpPath := fmt.Sprintf("%s.tmp-%d", linkPath, os.Getpid())
+	os.Remove(tmpPath)
+	if err := os.Symlink(target, tmpPath); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, linkPath); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,28 @@
+//go:build windows
+
+package ghdownloader
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// replaceLink replaces linkPath with a directory junction to target. Junctions
+// don't require elevated privileges, but can't be renamed over an existing
+// one, so the old link is removed first.
+func replaceLink(target, linkPath string) error {
+	target, err := filepath.Abs(target)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(linkPath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	out, err := exec.Command("cmd", "/c", "mklink", "/J", linkPath, target).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("mklink failed: %v: %s", err, out)
+	}
+	return nil
+}
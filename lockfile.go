@@ -0,0 +1,240 @@
+package ghdownloader
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Lockfile records exactly which release assets were downloaded, so the same
+// files can be downloaded again later and verified against their digests.
+type Lockfile struct {
+	Generated time.Time     `json:"generated"`
+	Assets    []LockedAsset `json:"assets"`
+}
+
+// LockedAsset describes one downloaded file. Path is relative to the
+// download directory and always uses forward slashes; for extracted archives
+// there is one entry per extracted file, all naming the archive as AssetName.
+type LockedAsset struct {
+	Repo         string    `json:"repo"`
+	Tag          string    `json:"tag"`
+	AssetID      int64     `json:"asset_id,omitempty"`
+	AssetName    string    `json:"asset_name"`
+	URL          string    `json:"url,omitempty"`
+	Path         string    `json:"path"`
+	SHA256       string    `json:"sha256"`
+	Size         int64     `json:"size"`
+	DownloadedAt time.Time `json:"downloaded_at"`
+}
+
+// Lockfile returns a lockfile describing every file the most recent download
+// call downloaded or found already present.
+func (d *Downloader) Lockfile() *Lockfile {
+	return d.LockfileFor(d.Results())
+}
+
+// LockfileFor returns a lockfile describing the files among results, such as
+// the combined results of several calls.
+func (d *Downloader) LockfileFor(results []DownloadResult) *Lockfile {
+	byPath := make(map[string]LockedAsset)
+	for _, r := range results {
+		if r.Err != nil || r.Path == "" {
+			continue
+		}
+		rel, err := filepath.Rel(d.destDir, r.Path)
+		if err != nil {
+			continue
+		}
+		asset := LockedAsset{
+			Repo:      r.Owner + "/" + r.Repo,
+			Tag:       r.Tag,
+			AssetID:   r.AssetID,
+			AssetName: r.AssetName,
+			URL:       r.URL,
+			Path:      filepath.ToSlash(rel),
+			SHA256:    r.SHA256,
+			Size:      r.Size,
+		}
+		if info, err := os.Stat(r.Path); err == nil {
+			asset.DownloadedAt = info.ModTime().UTC()
+		}
+		byPath[asset.Path] = asset
+	}
+
+	lock := &Lockfile{Generated: time.Now().UTC(), Assets: make([]LockedAsset, 0, len(byPath))}
+	for _, asset := range byPath {
+		lock.Assets = append(lock.Assets, asset)
+	}
+	sort.Slice(lock.Assets, func(i, j int) bool {
+		return lock.Assets[i].Path < lock.Assets[j].Path
+	})
+	return lock
+}
+
+// Write saves the lockfile as JSON to path.
+func (l *Lockfile) Write(path string) error {
+	data, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return err
+	}
+	err = writeFileAtomic(path, 0644, func(w io.Writer) error {
+		_, err := w.Write(append(data, '\n'))
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write lockfile: %v", err)
+	}
+	return nil
+}
+
+// RenameRepos updates the entries of repositories that moved to their new
+// location, as reported by MovedRepos, and reports whether any changed.
+func (l *Lockfile) RenameRepos(moved map[string]string) bool {
+	changed := false
+	for i, asset := range l.Assets {
+		for from, to := range moved {
+			if strings.EqualFold(asset.Repo, from) {
+				l.Assets[i].Repo = to
+				changed = true
+			}
+		}
+	}
+	return changed
+}
+
+// LoadLockfile reads a lockfile written by Lockfile.Write.
+func LoadLockfile(path string) (*Lockfile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var lock Lockfile
+	if err := json.Unmarshal(data, &lock); err != nil {
+		return nil, fmt.Errorf("invalid lockfile '%s': %v", path, err)
+	}
+	for _, asset := range lock.Assets {
+		if _, _, err := parseUserRepo(asset.Repo); err != nil || asset.Tag == "" || asset.AssetName == "" {
+			return nil, fmt.Errorf("invalid lockfile '%s': incomplete entry for '%s'", path, asset.Path)
+		}
+	}
+	return &lock, nil
+}
+
+// DownloadFromLockfile downloads the release assets recorded in lock, ignoring
+// the asset filters, and verifies each file against its recorded SHA-256
+// digest: assets saved as published before they are moved into place, and
+// extracted or decompressed files afterwards, deleting those that don't
+// match.
+func (d *Downloader) DownloadFromLockfile(lock *Lockfile) ([]string, error) {
+	return d.DownloadFromLockfileContext(context.Background(), lock)
+}
+
+// DownloadFromLockfileContext is like DownloadFromLockfile but honors
+// cancellation of ctx.
+func (d *Downloader) DownloadFromLockfileContext(ctx context.Context, lock *Lockfile) ([]string, error) {
+	// Pin each repository to its locked releases and assets.
+	var specs []string
+	seen := make(map[string]bool)
+	pins := &lockPins{assets: make(map[string]map[string]bool), digests: make(map[string]map[string]string)}
+	for _, asset := range lock.Assets {
+		spec := asset.Repo + "@" + asset.Tag
+		if !seen[spec] {
+			seen[spec] = true
+			specs = append(specs, spec)
+		}
+		key := strings.ToLower(asset.Repo)
+		if pins.assets[key] == nil {
+			pins.assets[key] = make(map[string]bool)
+			pins.digests[key] = make(map[string]string)
+		}
+		pins.assets[key][asset.AssetName] = true
+		// Assets saved as published are checked before they are moved
+		// into place; extracted and decompressed files only afterwards.
+		if path.Base(asset.Path) == asset.AssetName {
+			pins.digests[key][asset.AssetName] = asset.SHA256
+		}
+	}
+
+	ctx = context.WithValue(ctx, lockKey{}, pins)
+	results, err := d.withRun(ctx, func(ctx context.Context) error {
+		return d.downloadLatestReleases(ctx, specs)
+	})
+	if err != nil {
+		return resultPaths(results), err
+	}
+	return resultPaths(results), d.verifyLockfile(lock, results)
+}
+
+// lockPins holds the assets a download from a lockfile is pinned to, keyed
+// by lowercase "owner/repo" and asset name.
+type lockPins struct {
+	assets  map[string]map[string]bool
+	digests map[string]map[string]string
+}
+
+// lockKey is the context key of the lockfile pins of a download.
+type lockKey struct{}
+
+// lockedAssets returns the names of the assets of key pinned by the
+// lockfile download in ctx, or nil if there is none.
+func lockedAssets(ctx context.Context, key string) map[string]bool {
+	if pins, ok := ctx.Value(lockKey{}).(*lockPins); ok {
+		return pins.assets[key]
+	}
+	return nil
+}
+
+// lockedDigest returns the SHA-256 the lockfile download in ctx expects for
+// the asset name of userRepo as published, or "" if there is none.
+func lockedDigest(ctx context.Context, userRepo, name string) string {
+	if pins, ok := ctx.Value(lockKey{}).(*lockPins); ok {
+		return pins.digests[strings.ToLower(userRepo)][name]
+	}
+	return ""
+}
+
+// verifyLockfile checks that results contain every file in lock with its
+// recorded digest, deleting files that don't match.
+func (d *Downloader) verifyLockfile(lock *Lockfile, results []DownloadResult) error {
+	byPath := make(map[string]DownloadResult)
+	failed := make(map[string]error)
+	for _, r := range results {
+		if r.Err != nil {
+			failed[strings.ToLower(r.Owner+"/"+r.Repo)+"@"+r.Tag+"/"+r.AssetName] = r.Err
+			continue
+		}
+		if r.Path == "" {
+			continue
+		}
+		if rel, err := filepath.Rel(d.destDir, r.Path); err == nil {
+			byPath[filepath.ToSlash(rel)] = r
+		}
+	}
+
+	var errs []string
+	for _, asset := range lock.Assets {
+		r, ok := byPath[asset.Path]
+		err := failed[strings.ToLower(asset.Repo)+"@"+asset.Tag+"/"+asset.AssetName]
+		switch {
+		case !ok && err != nil:
+			errs = append(errs, fmt.Sprintf("'%s' from %s@%s failed: %v", asset.Path, asset.Repo, asset.Tag, err))
+		case !ok:
+			errs = append(errs, fmt.Sprintf("'%s' from %s@%s was not downloaded", asset.Path, asset.Repo, asset.Tag))
+		case !strings.EqualFold(r.SHA256, asset.SHA256):
+			os.Remove(r.Path)
+			errs = append(errs, fmt.Sprintf("checksum mismatch for '%s': expected %s, got %s", asset.Path, asset.SHA256, r.SHA256))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("lockfile verification failed:\n%s", strings.Join(errs, "\n"))
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package ghdownloader
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLockfileRoundTrip(t *testing.T) {
+	g := newFakeGitHub(t)
+	g.addRelease("owner/tool", "v1.0.0", map[string]string{"tool-linux": "linux", "tool-darwin": "darwin"})
+	g.addRelease("owner/tool", "v2.0.0", map[string]string{"tool-linux": "linux2", "tool-darwin": "darwin2"})
+
+	d := g.downloader(t)
+	d.SetMatchFilter("linux")
+	if _, err := d.DownloadLatestReleases([]string{"owner/tool@v1.0.0"}); err != nil {
+		t.Fatal(err)
+	}
+	lockPath := filepath.Join(t.TempDir(), "downloads.lock.json")
+	if err := d.Lockfile().Write(lockPath); err != nil {
+		t.Fatal(err)
+	}
+	lock, err := LoadLockfile(lockPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lock.Assets) != 1 || lock.Assets[0].Path != "tool-v1.0.0/tool-linux" || lock.Assets[0].Tag != "v1.0.0" {
+		t.Fatalf("locked %+v, want tool-v1.0.0/tool-linux", lock.Assets)
+	}
+
+	// The lockfile pins the release and asset regardless of the filters.
+	other := g.downloader(t)
+	other.SetMatchFilter("darwin")
+	paths, err := other.DownloadFromLockfile(lock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := filepath.Join(other.destDir, "tool-v1.0.0", "tool-linux")
+	if len(paths) != 1 || paths[0] != want {
+		t.Errorf("downloaded %v, want %s", paths, want)
+	}
+
+	// Later downloads are no longer pinned.
+	paths, err = other.DownloadLatestReleases([]string{"owner/tool"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) != 1 || filepath.Base(paths[0]) != "tool-darwin" {
+		t.Errorf("downloaded %v after the lockfile download, want tool-darwin", paths)
+	}
+}
+
+func TestDownloadFromLockfileMismatch(t *testing.T) {
+	g := newFakeGitHub(t)
+	g.addRelease("owner/tool", "v1.0.0", map[string]string{"tool": "tampered"})
+	lock := &Lockfile{Assets: []LockedAsset{{
+		Repo:      "owner/tool",
+		Tag:       "v1.0.0",
+		AssetName: "tool",
+		Path:      "tool-v1.0.0/tool",
+		SHA256:    strings.Repeat("0", 64),
+	}}}
+
+	d := g.downloader(t)
+	_, err := d.DownloadFromLockfile(lock)
+	if err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Fatalf("DownloadFromLockfile: %v, want a checksum mismatch", err)
+	}
+	dir := filepath.Join(d.destDir, "tool-v1.0.0")
+	for _, name := range []string{"tool", "tool" + partialSuffix} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			t.Errorf("'%s' was left behind by a download failing the lockfile digest", name)
+		}
+	}
+}
+
+func TestLoadLockfileRejectsIncompleteEntries(t *testing.T) {
+	for _, data := range []string{
+		`{"assets": [{"repo": "tool", "tag": "v1", "asset_name": "tool", "path": "tool-v1/tool"}]}`,
+		`{"assets": [{"repo": "owner/tool", "asset_name": "tool", "path": "tool-v1/tool"}]}`,
+		`{"assets": [{"repo": "owner/tool", "tag": "v1", "path": "tool-v1/tool"}]}`,
+		`{"assets": [`,
+	} {
+		path := filepath.Join(t.TempDir(), "lock.json")
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadLockfile(path); err == nil {
+			t.Errorf("LoadLockfile accepted %s", data)
+		}
+	}
+}
+
+func TestLockfileRenameRepos(t *testing.T) {
+	lock := &Lockfile{Assets: []LockedAsset{
+		{Repo: "Old/Tool", Tag: "v1", AssetName: "tool", Path: "tool-v1/tool"},
+		{Repo: "owner/other", Tag: "v1", AssetName: "other", Path: "other-v1/other"},
+	}}
+	if !lock.RenameRepos(map[string]string{"old/tool": "new/tool"}) {
+		t.Fatal("RenameRepos reported no change")
+	}
+	if lock.Assets[0].Repo != "new/tool" || lock.Assets[1].Repo != "owner/other" {
+		t.Errorf("renamed to %+v", lock.Assets)
+	}
+	if lock.RenameRepos(map[string]string{"old/tool": "new/tool"}) {
+		t.Error("RenameRepos reported a change for an up to date lockfile")
+	}
+}
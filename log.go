@@ -0,0 +1,87 @@
+package ghdownloader
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sync"
+)
+
+// SetLogger sets the logger that receives progress messages (Info),
+// warnings (Warn) and, in verbose mode, HTTP requests (Debug). Messages are
+// scrubbed of credentials before they reach it. The default logger writes
+// plain messages at Info level and above to stderr; a nil logger discards
+// everything.
+func (d *Downloader) SetLogger(logger *slog.Logger) {
+	if logger == nil {
+		logger = slog.New(NewPlainHandler(io.Discard, slog.LevelError+1))
+	}
+	d.logger = logger
+}
+
+func (d *Downloader) debugf(format string, args ...interface{}) {
+	d.log(slog.LevelDebug, format, args...)
+}
+
+func (d *Downloader) infof(format string, args ...interface{}) {
+	d.log(slog.LevelInfo, format, args...)
+}
+
+func (d *Downloader) warnf(format string, args ...interface{}) {
+	d.log(slog.LevelWarn, format, args...)
+}
+
+// log sends a message to the logger with credentials scrubbed.
+func (d *Downloader) log(level slog.Level, format string, args ...interface{}) {
+	ctx := context.Background()
+	if !d.logger.Enabled(ctx, level) {
+		return
+	}
+	d.logger.Log(ctx, level, d.redact(fmt.Sprintf(format, args...)))
+}
+
+// plainHandler is a slog.Handler that writes bare messages, one per line,
+// prefixing warnings and errors so they stand out.
+type plainHandler struct {
+	mu    *sync.Mutex
+	w     io.Writer
+	level slog.Leveler
+}
+
+// NewPlainHandler returns a slog.Handler that writes each message on its own
+// line to w, without timestamps or attributes, prefixed with "Warning: " or
+// "Error: " for those levels. Records below level are dropped.
+func NewPlainHandler(w io.Writer, level slog.Leveler) slog.Handler {
+	if level == nil {
+		level = slog.LevelInfo
+	}
+	return &plainHandler{mu: &sync.Mutex{}, w: w, level: level}
+}
+
+func (h *plainHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+func (h *plainHandler) Handle(_ context.Context, r slog.Record) error {
+	prefix := ""
+	switch {
+	case r.Level >= slog.LevelError:
+		prefix = "Error: "
+	case r.Level >= slog.LevelWarn:
+		prefix = "Warning: "
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := fmt.Fprintf(h.w, "%s%s\n", prefix, r.Message)
+	return err
+}
+
+func (h *plainHandler) WithAttrs([]slog.Attr) slog.Handler { return h }
+func (h *plainHandler) WithGroup(string) slog.Handler      { return h }
+
+// defaultLogger writes plain messages at level and above to stderr.
+func defaultLogger(level slog.Leveler) *slog.Logger {
+	return slog.New(NewPlainHandler(os.Stderr, level))
+}
@@ -0,0 +1,71 @@
+package ghdownloader
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// SetMatchRegexp only downloads assets whose names match re, in addition to
+// any match filter. A nil re removes the filter.
+func (d *Downloader) SetMatchRegexp(re *regexp.Regexp) {
+	d.matchRegexp = re
+}
+
+// SetMatchGlob only downloads assets whose names match the glob pattern
+// (e.g. "*_linux_amd64.tar.gz"), in addition to any match filter. An empty
+// pattern removes the filter.
+func (d *Downloader) SetMatchGlob(pattern string) error {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return fmt.Errorf("invalid glob '%s': %v", pattern, err)
+	}
+	d.matchGlob = pattern
+	return nil
+}
+
+// SetExcludeFilter skips assets whose names match any of the glob patterns
+// (e.g. "*checksums*", "*.sig"), even if they match the other filters.
+func (d *Downloader) SetExcludeFilter(patterns ...string) error {
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid glob '%s': %v", pattern, err)
+		}
+	}
+	d.excludeGlobs = patterns
+	return nil
+}
+
+// hasIncludeFilter reports whether the assets to download are picked by a
+// filter, in which case asset hints aren't consulted.
+func (d *Downloader) hasIncludeFilter(matchFilter string) bool {
+	return matchFilter != "" || d.matchRegexp != nil || d.matchGlob != ""
+}
+
+// filterAsset reports whether the asset named name passes the filters, and if
+// not, why it is skipped. If locked is non-nil, only the assets it names pass.
+func (d *Downloader) filterAsset(name, matchFilter string, locked map[string]bool) (bool, string) {
+	if locked != nil {
+		if !locked[name] {
+			return false, "not in lockfile"
+		}
+		return true, ""
+	}
+	if matchFilter != "" && !strings.Contains(name, matchFilter) {
+		return false, fmt.Sprintf("does not match filter '%s'", matchFilter)
+	}
+	if d.matchRegexp != nil && !d.matchRegexp.MatchString(name) {
+		return false, fmt.Sprintf("does not match pattern '%s'", d.matchRegexp)
+	}
+	if d.matchGlob != "" {
+		if ok, _ := path.Match(d.matchGlob, name); !ok {
+			return false, fmt.Sprintf("does not match glob '%s'", d.matchGlob)
+		}
+	}
+	for _, pattern := range d.excludeGlobs {
+		if ok, _ := path.Match(pattern, name); ok {
+			return false, fmt.Sprintf("excluded by '%s'", pattern)
+		}
+	}
+	return true, ""
+}
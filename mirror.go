@@ -0,0 +1,156 @@
+package ghdownloader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v68/github"
+)
+
+// MirrorReleases downloads the assets of every published release of the given
+// user/repos into "<repo>-<tag>" directories. The newest mirrored release of
+// each repository is recorded in destDir, so later runs only fetch releases
+// created since.
+func (d *Downloader) MirrorReleases(userRepos []string) ([]string, error) {
+	return d.MirrorReleasesContext(context.Background(), userRepos)
+}
+
+// MirrorReleasesContext is like MirrorReleases but honors cancellation of
+// ctx. Releases mirrored before cancellation stay recorded.
+func (d *Downloader) MirrorReleasesContext(ctx context.Context, userRepos []string) ([]string, error) {
+	results, err := d.withRun(ctx, func(ctx context.Context) error {
+		return d.mirrorReleases(ctx, userRepos)
+	})
+	return resultPaths(results), err
+}
+
+// mirrorReleases implements MirrorReleasesContext, recording results in the
+// run of ctx.
+func (d *Downloader) mirrorReleases(ctx context.Context, userRepos []string) error {
+	ctx = d.rateLimitContext(ctx)
+
+	if err := os.MkdirAll(d.destDir, 0755); err != nil {
+		return fmt.Errorf("failed to create destination directory: %v", err)
+	}
+
+	errChan := make(chan *RepoError, len(userRepos))
+	sem := newSemaphore(d.maxConcurrentRepos)
+
+	var wg sync.WaitGroup
+	for _, userRepo := range userRepos {
+		owner, repo, err := parseUserRepo(userRepo)
+		if err != nil {
+			return fmt.Errorf("invalid user/repo format '%s': %v", userRepo, err)
+		}
+
+		wg.Add(1)
+		go func(owner, repo string) {
+			defer wg.Done()
+			sem.acquire()
+			defer sem.release()
+			if err := d.mirrorRepo(ctx, owner, repo); err != nil {
+				d.record(ctx, DownloadResult{Owner: owner, Repo: repo, Err: err})
+				errChan <- d.repoError(owner, repo, err)
+			}
+		}(owner, repo)
+	}
+
+	wg.Wait()
+	close(errChan)
+
+	var errs []*RepoError
+	for err := range errChan {
+		errs = append(errs, err)
+	}
+	return joinRepoErrors("mirror", errs)
+}
+
+// mirrorRepo downloads every release of owner/repo created since the last
+// mirrored one, oldest first, recording progress after each release.
+func (d *Downloader) mirrorRepo(ctx context.Context, owner, repo string) error {
+	key := strings.ToLower(owner + "/" + repo)
+	state, err := d.loadState()
+	if err != nil {
+		return err
+	}
+	last := state.Mirrors[key]
+
+	releases, err := d.releasesSince(ctx, owner, repo, last)
+	if err != nil {
+		return err
+	}
+	if len(releases) == 0 {
+		d.infof("Mirror of %s/%s is up to date.", owner, repo)
+		return nil
+	}
+
+	// Releases are listed newest first; mirror oldest first so the recorded
+	// position only ever moves forward.
+	for i := len(releases) - 1; i >= 0; i-- {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		release := releases[i]
+		tag := release.GetTagName()
+		failed, err := d.downloadReleaseDir(ctx, key, owner, repo, release)
+		switch {
+		case errors.Is(err, ErrNoAssets):
+			// Tag-only releases would otherwise block every later one.
+			d.infof("Release '%s' of %s/%s has no assets matching the filters. Skipping.", tag, owner, repo)
+			d.record(ctx, DownloadResult{Owner: owner, Repo: repo, Tag: tag, Skipped: true})
+		case err != nil:
+			return err
+		case failed:
+			return fmt.Errorf("some assets of release '%s' failed to download", tag)
+		}
+
+		err = d.updateState(func(s *syncState) {
+			if s.Mirrors == nil {
+				s.Mirrors = make(map[string]mirrorState)
+			}
+			s.Mirrors[key] = mirrorState{
+				LastReleaseID: release.GetID(),
+				LastTag:       tag,
+				LastCreatedAt: release.GetCreatedAt().Time,
+				SyncedAt:      time.Now().UTC(),
+			}
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// releasesSince lists the published releases of owner/repo created after the
+// last mirrored release, newest first. It stops paging as soon as it reaches
+// already-mirrored history.
+func (d *Downloader) releasesSince(ctx context.Context, owner, repo string, last mirrorState) ([]*github.RepositoryRelease, error) {
+	var releases []*github.RepositoryRelease
+	p := d.provider(owner, repo)
+	for pageNum := 1; ; {
+		page, next, err := p.ListReleases(ctx, owner, repo, pageNum, 100)
+		if err != nil {
+			return nil, fmt.Errorf("error listing releases: %w", err)
+		}
+		for _, release := range page {
+			if release.GetID() == last.LastReleaseID ||
+				(!last.LastCreatedAt.IsZero() && !release.GetCreatedAt().After(last.LastCreatedAt)) {
+				return releases, nil
+			}
+			if release.GetDraft() || release.GetTagName() == "" {
+				continue
+			}
+			releases = append(releases, release)
+		}
+		if next == 0 {
+			return releases, nil
+		}
+		pageNum = next
+	}
+}
@@ -0,0 +1,65 @@
+package ghdownloader
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestMirrorReleases(t *testing.T) {
+	g := newFakeGitHub(t)
+	g.addRelease("owner/tool", "v1.0.0", map[string]string{"tool": "v1"})
+	g.addRelease("owner/tool", "v2.0.0", nil)
+	g.addRelease("owner/tool", "v3.0.0", map[string]string{"tool": "v3"})
+	d := g.downloader(t)
+
+	if _, err := d.MirrorReleases([]string{"owner/tool"}); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{".ghdownloader-state.json", "tool-v1.0.0", "tool-v3.0.0"}
+	if got := listDir(t, d.destDir); !slices.Equal(got, want) {
+		t.Errorf("mirrored %v, want %v", got, want)
+	}
+	state, err := d.loadState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := state.Mirrors["owner/tool"].LastTag; got != "v3.0.0" {
+		t.Errorf("recorded %s as mirrored, want v3.0.0", got)
+	}
+
+	// Later runs only fetch new releases, even past a release without assets.
+	g.addRelease("owner/tool", "v4.0.0", nil)
+	g.addRelease("owner/tool", "v5.0.0", map[string]string{"tool": "v5"})
+	before := len(g.served())
+	if _, err := d.MirrorReleases([]string{"owner/tool"}); err != nil {
+		t.Fatal(err)
+	}
+	var assets int
+	for _, path := range g.served()[before:] {
+		if strings.Contains(path, "/releases/assets/") {
+			assets++
+		}
+	}
+	if assets != 1 {
+		t.Errorf("downloaded %d assets on the second run, want 1", assets)
+	}
+	if state, _ := d.loadState(); state.Mirrors["owner/tool"].LastTag != "v5.0.0" {
+		t.Errorf("recorded %s as mirrored, want v5.0.0", state.Mirrors["owner/tool"].LastTag)
+	}
+}
+
+func TestMirrorNothingNew(t *testing.T) {
+	g := newFakeGitHub(t)
+	g.addRelease("owner/tool", "v1.0.0", nil)
+	d := g.downloader(t)
+
+	for i := 0; i < 2; i++ {
+		if _, err := d.MirrorReleases([]string{"owner/tool"}); err != nil {
+			t.Fatalf("run %d: %v", i+1, err)
+		}
+	}
+	if state, _ := d.loadState(); state.Mirrors["owner/tool"].LastTag != "v1.0.0" {
+		t.Error("a release without assets wasn't recorded as mirrored")
+	}
+}
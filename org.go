@@ -0,0 +1,127 @@
+package ghdownloader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/google/go-github/v68/github"
+)
+
+// errNoReleases is returned for repositories without published releases.
+var errNoReleases = errors.New("no published releases found")
+
+// RepoFilter selects repositories when expanding an owner with ExpandOwner.
+// Empty fields match every repository.
+type RepoFilter struct {
+	// Topic selects repositories tagged with this topic.
+	Topic string
+	// Language selects repositories whose primary language this is,
+	// ignoring case.
+	Language string
+	// Name is a glob repository names must match, such as "cli-*".
+	Name string
+}
+
+// matches reports whether repo passes the filter.
+func (f RepoFilter) matches(repo *github.Repository) bool {
+	if f.Topic != "" && !slices.Contains(repo.Topics, strings.ToLower(f.Topic)) {
+		return false
+	}
+	if f.Language != "" && !strings.EqualFold(repo.GetLanguage(), f.Language) {
+		return false
+	}
+	if f.Name != "" {
+		if ok, _ := path.Match(strings.ToLower(f.Name), strings.ToLower(repo.GetName())); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// ExpandOwner lists the repositories of the GitHub organization or user
+// owner that match filter, as "owner/repo" specs sorted by name, for use
+// with DownloadLatestReleases. Repositories found this way are skipped
+// rather than failing when they have no releases or their latest release
+// has no assets matching the filters, as is common for libraries and
+// documentation.
+func (d *Downloader) ExpandOwner(owner string, filter RepoFilter) ([]string, error) {
+	return d.ExpandOwnerContext(context.Background(), owner, filter)
+}
+
+// ExpandOwnerContext is like ExpandOwner but honors cancellation of ctx.
+func (d *Downloader) ExpandOwnerContext(ctx context.Context, owner string, filter RepoFilter) ([]string, error) {
+	if _, err := path.Match(filter.Name, ""); err != nil {
+		return nil, fmt.Errorf("invalid name pattern '%s': %v", filter.Name, err)
+	}
+	ctx = d.rateLimitContext(ctx)
+
+	repos, err := d.listOwnerRepos(ctx, owner)
+	if err != nil {
+		return nil, d.redactError(fmt.Errorf("failed to list repositories of '%s': %v", owner, err))
+	}
+	var specs []string
+	d.mu.Lock()
+	for _, repo := range repos {
+		if !filter.matches(repo) {
+			continue
+		}
+		spec := owner + "/" + repo.GetName()
+		d.expandedRepos[strings.ToLower(spec)] = true
+		specs = append(specs, spec)
+	}
+	d.mu.Unlock()
+	sort.Slice(specs, func(i, j int) bool { return strings.ToLower(specs[i]) < strings.ToLower(specs[j]) })
+	return specs, nil
+}
+
+// listOwnerRepos returns every repository of the organization owner, or of
+// the user owner if there is no such organization.
+func (d *Downloader) listOwnerRepos(ctx context.Context, owner string) ([]*github.Repository, error) {
+	var all []*github.Repository
+	orgOpts := &github.RepositoryListByOrgOptions{ListOptions: github.ListOptions{PerPage: 100}}
+	for {
+		repos, resp, err := d.client.Repositories.ListByOrg(ctx, owner, orgOpts)
+		if err != nil {
+			if resp != nil && resp.StatusCode == http.StatusNotFound && len(all) == 0 {
+				return d.listUserRepos(ctx, owner)
+			}
+			return nil, err
+		}
+		all = append(all, repos...)
+		if resp.NextPage == 0 {
+			return all, nil
+		}
+		orgOpts.Page = resp.NextPage
+	}
+}
+
+// listUserRepos returns every repository owned by the user owner.
+func (d *Downloader) listUserRepos(ctx context.Context, owner string) ([]*github.Repository, error) {
+	var all []*github.Repository
+	opts := &github.RepositoryListByUserOptions{Type: "owner", ListOptions: github.ListOptions{PerPage: 100}}
+	for {
+		repos, resp, err := d.client.Repositories.ListByUser(ctx, owner, opts)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, repos...)
+		if resp.NextPage == 0 {
+			return all, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+// isExpanded reports whether the repository keyed key was found by
+// ExpandOwner.
+func (d *Downloader) isExpanded(key string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.expandedRepos[key]
+}
@@ -0,0 +1,88 @@
+package ghdownloader
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-github/v68/github"
+)
+
+// newFakeOrg returns a fake GitHub with the organization acme holding a
+// tool with a release, a library without releases and docs whose release
+// has no binaries.
+func newFakeOrg(t *testing.T) *fakeGitHub {
+	t.Helper()
+	g := newFakeGitHub(t)
+	g.orgs["acme"] = true
+	g.addRelease("acme/cli-tool", "v1.0.0", map[string]string{"tool": "bin"})
+	g.addRepo("acme/lib").Language = github.String("Go")
+	g.addRelease("acme/docs", "v1.0.0", map[string]string{"docs.pdf": "pdf"})
+	g.addRepo("acme/cli-tool").Topics = []string{"cli"}
+	return g
+}
+
+func TestExpandOwner(t *testing.T) {
+	g := newFakeOrg(t)
+	g.addRepo("someone/dotfiles")
+	tests := []struct {
+		owner  string
+		filter RepoFilter
+		want   []string
+	}{
+		{"acme", RepoFilter{}, []string{"acme/cli-tool", "acme/docs", "acme/lib"}},
+		{"acme", RepoFilter{Name: "CLI-*"}, []string{"acme/cli-tool"}},
+		{"acme", RepoFilter{Topic: "CLI"}, []string{"acme/cli-tool"}},
+		{"acme", RepoFilter{Language: "go"}, []string{"acme/lib"}},
+		{"someone", RepoFilter{}, []string{"someone/dotfiles"}},
+	}
+	for _, tt := range tests {
+		specs, err := g.downloader(t).ExpandOwner(tt.owner, tt.filter)
+		if err != nil {
+			t.Errorf("ExpandOwner(%s, %+v): %v", tt.owner, tt.filter, err)
+			continue
+		}
+		if strings.Join(specs, " ") != strings.Join(tt.want, " ") {
+			t.Errorf("ExpandOwner(%s, %+v) = %v, want %v", tt.owner, tt.filter, specs, tt.want)
+		}
+	}
+	if _, err := g.downloader(t).ExpandOwner("acme", RepoFilter{Name: "["}); err == nil {
+		t.Error("expected an error for an invalid name pattern")
+	}
+}
+
+func TestDownloadExpandedOwner(t *testing.T) {
+	g := newFakeOrg(t)
+	d := g.downloader(t)
+	d.SetMatchFilter("tool")
+	specs, err := d.ExpandOwner("acme", RepoFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	paths, err := d.DownloadLatestReleases(specs)
+	if err != nil {
+		t.Fatalf("repositories without matching releases failed the run: %v", err)
+	}
+	want := filepath.Join(d.destDir, "cli-tool-v1.0.0", "tool")
+	if len(paths) != 1 || paths[0] != want {
+		t.Errorf("downloaded %v, want only %s", paths, want)
+	}
+	if _, err := os.Stat(filepath.Join(d.destDir, "docs-v1.0.0")); err == nil {
+		t.Error("a version directory was created for a skipped release")
+	}
+
+	assets, err := d.ListLatestReleaseAssets(specs)
+	if err != nil {
+		t.Fatalf("dry run: %v", err)
+	}
+	if len(assets) != 1 || assets[0].Repo != "cli-tool" || !assets[0].Exists {
+		t.Errorf("dry run listed %+v, want only the downloaded cli-tool asset", assets)
+	}
+
+	// Without the expansion the same repositories are errors.
+	if _, err := g.downloader(t).ListLatestReleaseAssets([]string{"acme/lib"}); err == nil {
+		t.Error("dry run of a repository without releases succeeded")
+	}
+}
@@ -0,0 +1,164 @@
+package ghdownloader
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/google/go-github/v68/github"
+)
+
+// partPattern matches split assets named "name.part1", "name.part2", ... or
+// "name.001", "name.002", ...
+var partPattern = regexp.MustCompile(`^(.+)\.(?:part(\d+)|(\d{3}))$`)
+
+// assetPart is one piece of a split asset.
+type assetPart struct {
+	index int
+	asset *github.ReleaseAsset
+}
+
+// SetJoinParts enables reassembling split assets ("name.part1", "name.part2",
+// ... or "name.001", "name.002", ...) into a single file named "name".
+func (d *Downloader) SetJoinParts(join bool) {
+	d.joinParts = join
+}
+
+// groupAssetParts separates split assets from the rest, returning the parts
+// of each split asset, keyed by the reassembled name and sorted by index, and
+// the remaining assets. A lone part, or a set of parts with a gap or a
+// duplicate index, is treated as regular assets rather than joined into a
+// corrupt file.
+func groupAssetParts(assets []*github.ReleaseAsset) (map[string][]assetPart, []*github.ReleaseAsset) {
+	groups := make(map[string][]assetPart)
+	for _, asset := range assets {
+		m := partPattern.FindStringSubmatch(asset.GetName())
+		if m == nil {
+			continue
+		}
+		index, _ := strconv.Atoi(m[2] + m[3])
+		groups[m[1]] = append(groups[m[1]], assetPart{index: index, asset: asset})
+	}
+	for name, parts := range groups {
+		sort.Slice(parts, func(i, j int) bool { return parts[i].index < parts[j].index })
+		if len(parts) < 2 || !consecutiveParts(parts) {
+			delete(groups, name)
+		}
+	}
+
+	var rest []*github.ReleaseAsset
+	for _, asset := range assets {
+		if m := partPattern.FindStringSubmatch(asset.GetName()); m == nil || groups[m[1]] == nil {
+			rest = append(rest, asset)
+		}
+	}
+	return groups, rest
+}
+
+// consecutiveParts reports whether the sorted parts are numbered one after
+// the other from 0 or 1.
+func consecutiveParts(parts []assetPart) bool {
+	if parts[0].index > 1 {
+		return false
+	}
+	for i, part := range parts {
+		if part.index != parts[0].index+i {
+			return false
+		}
+	}
+	return true
+}
+
+// downloadParts downloads every part of a split asset into versionDir,
+// concatenates them in order into name, verifies the result against the
+// release checksums if they cover it and the signatures if enabled, and
+// removes the parts. The joined file only appears once verified; parts are
+// kept for the next attempt unless they joined into a file failing
+// verification. It reports whether the joined file was already there.
+func (d *Downloader) downloadParts(ctx context.Context, userRepo, name string, parts []assetPart, sums *releaseChecksums, versionDir string, forceDownload bool) (string, bool, error) {
+	filePath := filepath.Join(versionDir, name)
+
+	// Split assets can't be checked part by part, so the reassembled file is
+	// always verified when the release publishes a checksum for it.
+	verify := func(path string) error {
+		expected, err := sums.lookup(name)
+		if err == nil && expected != "" {
+			err = verifyDigest(path, expected)
+		}
+		if err == nil && d.verifiesSignatures() {
+			err = d.verifySignature(ctx, userRepo, path, name, sums.assets)
+		}
+		return err
+	}
+
+	if !forceDownload {
+		if _, err := os.Stat(filePath); err == nil {
+			if err := verify(filePath); err != nil {
+				d.warnf("File '%s' failed verification: %v. Downloading it again.", filePath, err)
+			} else {
+				d.infof("File '%s' already exists. Skipping download.", filePath)
+				return filePath, true, nil
+			}
+		}
+	}
+
+	// Parts are kept until they are joined, so a later run only downloads
+	// the ones still missing.
+	var partPaths []string
+	for _, part := range parts {
+		partPath := filepath.Join(versionDir, part.asset.GetName())
+		partPaths = append(partPaths, partPath)
+		size := int64(part.asset.GetSize())
+		if info, err := os.Stat(partPath); err == nil && !forceDownload && size > 0 && info.Size() == size {
+			d.infof("Part '%s' already exists. Skipping download.", partPath)
+			continue
+		}
+		if err := d.fetchAsset(ctx, userRepo, part.asset, partPath, nil); err != nil {
+			return "", false, fmt.Errorf("failed to download part '%s': %v", part.asset.GetName(), err)
+		}
+	}
+
+	// Parts that join into a file failing verification are downloaded
+	// again next time.
+	rejected := false
+	err := concatFiles(filePath, partPaths, func(path string) error {
+		err := verify(path)
+		rejected = err != nil
+		return err
+	})
+	if err == nil || rejected {
+		for _, partPath := range partPaths {
+			os.Remove(partPath)
+		}
+	}
+	if err != nil {
+		return "", false, err
+	}
+
+	d.infof("Reassembled %d parts into '%s'", len(parts), filePath)
+	return filePath, false, nil
+}
+
+// concatFiles writes the contents of srcs, in order, to dst, once verify
+// accepts them.
+func concatFiles(dst string, srcs []string, verify func(path string) error) error {
+	return writeFileVerified(dst, 0666, func(out io.Writer) error {
+		for _, src := range srcs {
+			in, err := os.Open(src)
+			if err != nil {
+				return fmt.Errorf("failed to open part '%s': %v", src, err)
+			}
+			_, err = io.Copy(out, in)
+			in.Close()
+			if err != nil {
+				return fmt.Errorf("failed to append part '%s': %v", src, err)
+			}
+		}
+		return nil
+	}, verify)
+}
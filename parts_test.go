@@ -0,0 +1,182 @@
+package ghdownloader
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/google/go-github/v68/github"
+)
+
+func TestConcatFilesVerify(t *testing.T) {
+	dir := t.TempDir()
+	var srcs []string
+	for i, data := range []string{"ab", "cd"} {
+		src := filepath.Join(dir, "part"+string(rune('1'+i)))
+		if err := os.WriteFile(src, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+		srcs = append(srcs, src)
+	}
+	dst := filepath.Join(dir, "joined")
+
+	errRejected := errors.New("rejected")
+	var verified string
+	err := concatFiles(dst, srcs, func(path string) error {
+		data, _ := os.ReadFile(path)
+		verified = string(data)
+		return errRejected
+	})
+	if !errors.Is(err, errRejected) {
+		t.Fatalf("err = %v, want %v", err, errRejected)
+	}
+	if verified != "abcd" {
+		t.Errorf("verified %q, want %q", verified, "abcd")
+	}
+	for _, path := range []string{dst, dst + partialSuffix} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("%s exists after failed verification", path)
+		}
+	}
+
+	if err := concatFiles(dst, srcs, func(string) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(dst); string(data) != "abcd" {
+		t.Errorf("joined %q, want %q", data, "abcd")
+	}
+}
+
+func TestGroupAssetParts(t *testing.T) {
+	tests := []struct {
+		name   string
+		assets []string
+		groups map[string][]string
+		rest   []string
+	}{
+		{
+			name:   "part suffixes",
+			assets: []string{"tool.tar.gz.part2", "checksums.txt", "tool.tar.gz.part1", "tool.tar.gz.part10", "tool.tar.gz.part3", "tool.tar.gz.part4", "tool.tar.gz.part5", "tool.tar.gz.part6", "tool.tar.gz.part7", "tool.tar.gz.part8", "tool.tar.gz.part9"},
+			groups: map[string][]string{"tool.tar.gz": {
+				"tool.tar.gz.part1", "tool.tar.gz.part2", "tool.tar.gz.part3", "tool.tar.gz.part4", "tool.tar.gz.part5",
+				"tool.tar.gz.part6", "tool.tar.gz.part7", "tool.tar.gz.part8", "tool.tar.gz.part9", "tool.tar.gz.part10",
+			}},
+			rest: []string{"checksums.txt"},
+		},
+		{
+			name:   "numbered suffixes from zero",
+			assets: []string{"image.iso.001", "image.iso.000", "image.iso.002"},
+			groups: map[string][]string{"image.iso": {"image.iso.000", "image.iso.001", "image.iso.002"}},
+		},
+		{
+			name:   "several split assets",
+			assets: []string{"a.zip.001", "b.zip.part1", "a.zip.002", "b.zip.part2"},
+			groups: map[string][]string{"a.zip": {"a.zip.001", "a.zip.002"}, "b.zip": {"b.zip.part1", "b.zip.part2"}},
+		},
+		{
+			name:   "lone part",
+			assets: []string{"tool.tar.gz.part1", "tool.zip"},
+			groups: map[string][]string{},
+			rest:   []string{"tool.tar.gz.part1", "tool.zip"},
+		},
+		{
+			name:   "missing part",
+			assets: []string{"tool.tar.gz.part1", "tool.tar.gz.part3"},
+			groups: map[string][]string{},
+			rest:   []string{"tool.tar.gz.part1", "tool.tar.gz.part3"},
+		},
+		{
+			name:   "missing first part",
+			assets: []string{"tool.tar.gz.part2", "tool.tar.gz.part3"},
+			groups: map[string][]string{},
+			rest:   []string{"tool.tar.gz.part2", "tool.tar.gz.part3"},
+		},
+		{
+			name:   "duplicate index",
+			assets: []string{"tool.tar.gz.part1", "tool.tar.gz.001", "tool.tar.gz.part2"},
+			groups: map[string][]string{},
+			rest:   []string{"tool.tar.gz.part1", "tool.tar.gz.001", "tool.tar.gz.part2"},
+		},
+		{
+			name:   "not parts",
+			assets: []string{"tool-1.001", "tool.part", "tool.0001", "tool.parta"},
+			groups: map[string][]string{},
+			rest:   []string{"tool-1.001", "tool.part", "tool.0001", "tool.parta"},
+		},
+	}
+	for _, tt := range tests {
+		var assets []*github.ReleaseAsset
+		for _, name := range tt.assets {
+			assets = append(assets, &github.ReleaseAsset{Name: github.String(name)})
+		}
+		groups, rest := groupAssetParts(assets)
+
+		gotGroups := make(map[string][]string)
+		for name, parts := range groups {
+			for _, part := range parts {
+				gotGroups[name] = append(gotGroups[name], part.asset.GetName())
+			}
+		}
+		var gotRest []string
+		for _, asset := range rest {
+			gotRest = append(gotRest, asset.GetName())
+		}
+		if !reflect.DeepEqual(gotGroups, tt.groups) {
+			t.Errorf("%s: groups = %v, want %v", tt.name, gotGroups, tt.groups)
+		}
+		if !reflect.DeepEqual(gotRest, tt.rest) {
+			t.Errorf("%s: rest = %v, want %v", tt.name, gotRest, tt.rest)
+		}
+	}
+}
+
+func TestDownloadPartsResumes(t *testing.T) {
+	g := newFakeGitHub(t)
+	release := g.addRelease("owner/tool", "v1.0.0", map[string]string{"tool.bin.part1": "ab", "tool.bin.part2": "cd"})
+	part1, part2 := release.Assets[0], release.Assets[1]
+	failPart2 := true
+	g.hook = func(w http.ResponseWriter, r *http.Request) bool {
+		if failPart2 && strings.HasSuffix(r.URL.Path, fmt.Sprintf("/releases/assets/%d", part2.GetID())) {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return true
+		}
+		return false
+	}
+	d := g.downloader(t)
+	d.SetRetries(0)
+	d.SetJoinParts(true)
+	dir := filepath.Join(d.destDir, "tool-v1.0.0")
+
+	if _, err := d.DownloadLatestReleases([]string{"owner/tool"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := listDir(t, dir); len(got) == 0 || got[0] != "tool.bin.part1" {
+		t.Fatalf("after a failed part the version directory holds %v, want the downloaded part", got)
+	}
+
+	failPart2 = false
+	paths, err := d.DownloadLatestReleases([]string{"owner/tool"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, err := os.ReadFile(paths[0]); err != nil || string(data) != "abcd" {
+		t.Errorf("joined %q, %v, want %q", data, err, "abcd")
+	}
+	if got := listDir(t, dir); !reflect.DeepEqual(got, []string{"tool.bin"}) {
+		t.Errorf("version directory holds %v, want only the joined file", got)
+	}
+	var part1Requests int
+	for _, path := range g.served() {
+		if strings.HasSuffix(path, fmt.Sprintf("/releases/assets/%d", part1.GetID())) {
+			part1Requests++
+		}
+	}
+	if part1Requests != 1 {
+		t.Errorf("part1 was downloaded %d times, want once", part1Requests)
+	}
+}
@@ -0,0 +1,179 @@
+package ghdownloader
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+
+	"github.com/google/go-github/v68/github"
+)
+
+// osAliases lists the names release assets commonly use for each GOOS.
+var osAliases = map[string][]string{
+	"linux":   {"linux"},
+	"darwin":  {"darwin", "macos", "mac", "osx", "apple"},
+	"windows": {"windows", "win", "win32", "win64"},
+	"freebsd": {"freebsd"},
+	"openbsd": {"openbsd"},
+	"netbsd":  {"netbsd"},
+}
+
+// archAliases lists the names release assets commonly use for each GOARCH.
+var archAliases = map[string][]string{
+	"amd64":   {"amd64", "x86_64", "x64", "64bit", "win64"},
+	"386":     {"386", "i386", "i686", "x86", "32bit", "win32"},
+	"arm64":   {"arm64", "aarch64", "armv8"},
+	"arm":     {"arm", "armv7", "armv6", "armhf", "armel"},
+	"ppc64le": {"ppc64le"},
+	"s390x":   {"s390x"},
+	"riscv64": {"riscv64"},
+}
+
+// universalArches are architecture names that run on any architecture of
+// their OS, such as macOS universal binaries.
+var universalArches = []string{"universal", "all"}
+
+// platformSkipSuffixes mark assets that describe other assets rather than
+// being downloads for a platform.
+var platformSkipSuffixes = []string{".sha256", ".sha256sum", ".sha512", ".sha512sum", ".sig", ".asc", ".pem", ".cert", ".sbom", ".spdx", ".json"}
+
+// packageSuffixes mark OS packages, which are only picked when nothing else
+// is published for the platform.
+var packageSuffixes = []string{".deb", ".rpm", ".apk", ".msi", ".pkg", ".dmg"}
+
+// SetPlatformFilter selects release assets built for goos/goarch by their
+// names, understanding common spellings such as "Linux-x86_64",
+// "darwin_arm64" or "win64". Empty values disable the filter. If no asset
+// looks like a build for the platform, every asset is downloaded as before.
+// Asset hints are rendered for this platform too.
+func (d *Downloader) SetPlatformFilter(goos, goarch string) {
+	d.platformOS = canonicalName(osAliases, goos)
+	d.platformArch = canonicalName(archAliases, goarch)
+}
+
+// ParsePlatform parses "auto" (the running platform) or "os/arch", where os
+// and arch may be common aliases such as "macos/x86_64".
+func ParsePlatform(platform string) (goos, goarch string, err error) {
+	if platform == "auto" {
+		return runtime.GOOS, runtime.GOARCH, nil
+	}
+	goos, goarch, ok := strings.Cut(platform, "/")
+	if !ok || goos == "" || goarch == "" {
+		return "", "", fmt.Errorf("expected 'auto' or 'os/arch'")
+	}
+	return canonicalName(osAliases, goos), canonicalName(archAliases, goarch), nil
+}
+
+// canonicalName maps an alias to its Go name, or returns it lowercased.
+func canonicalName(aliases map[string][]string, name string) string {
+	name = strings.ToLower(name)
+	for canonical, names := range aliases {
+		for _, alias := range names {
+			if alias == name {
+				return canonical
+			}
+		}
+	}
+	return name
+}
+
+// platform returns the platform assets are selected for.
+func (d *Downloader) platform() (string, string) {
+	if d.platformOS != "" && d.platformArch != "" {
+		return d.platformOS, d.platformArch
+	}
+	return runtime.GOOS, runtime.GOARCH
+}
+
+// platformAssets returns the assets whose names mark them as builds for the
+// platform filter. Assets naming the OS and architecture are preferred over
+// universal builds and then ones naming only the OS, and archives and
+// binaries over OS packages. It returns nil if none match.
+func (d *Downloader) platformAssets(assets []*github.ReleaseAsset) []*github.ReleaseAsset {
+	var exact, universal, osOnly []*github.ReleaseAsset
+	for _, asset := range assets {
+		if hasAnySuffix(strings.ToLower(asset.GetName()), platformSkipSuffixes) || isChecksumAsset(asset.GetName()) {
+			continue
+		}
+		tokens := assetTokens(asset.GetName())
+		if !tokens[d.platformOS] {
+			continue
+		}
+		switch {
+		case tokens[d.platformArch]:
+			exact = append(exact, asset)
+		case hasAnyToken(tokens, universalArches):
+			universal = append(universal, asset)
+		case !hasAnyArch(tokens):
+			osOnly = append(osOnly, asset)
+		}
+	}
+	if len(exact) == 0 {
+		exact = universal
+	}
+	if len(exact) == 0 {
+		exact = osOnly
+	}
+
+	var preferred []*github.ReleaseAsset
+	for _, asset := range exact {
+		if !hasAnySuffix(strings.ToLower(asset.GetName()), packageSuffixes) {
+			preferred = append(preferred, asset)
+		}
+	}
+	if len(preferred) > 0 {
+		return preferred
+	}
+	return exact
+}
+
+// assetTokens splits an asset name into its lowercase words, adding the Go
+// names of any OS or architecture aliases among them.
+func assetTokens(name string) map[string]bool {
+	lower := strings.ToLower(name)
+	// Spellings that contain separators must be joined before splitting.
+	for _, alias := range []string{"x86_64", "x86-64"} {
+		lower = strings.ReplaceAll(lower, alias, "amd64")
+	}
+
+	tokens := make(map[string]bool)
+	for _, word := range strings.FieldsFunc(lower, func(r rune) bool {
+		return !('a' <= r && r <= 'z' || '0' <= r && r <= '9')
+	}) {
+		tokens[word] = true
+		for _, aliases := range []map[string][]string{osAliases, archAliases} {
+			if canonical := canonicalName(aliases, word); canonical != word {
+				tokens[canonical] = true
+			}
+		}
+	}
+	return tokens
+}
+
+// hasAnyArch reports whether tokens name any architecture.
+func hasAnyArch(tokens map[string]bool) bool {
+	for arch := range archAliases {
+		if tokens[arch] {
+			return true
+		}
+	}
+	return hasAnyToken(tokens, universalArches)
+}
+
+func hasAnyToken(tokens map[string]bool, words []string) bool {
+	for _, word := range words {
+		if tokens[word] {
+			return true
+		}
+	}
+	return false
+}
+
+func hasAnySuffix(s string, suffixes []string) bool {
+	for _, suffix := range suffixes {
+		if strings.HasSuffix(s, suffix) {
+			return true
+		}
+	}
+	return false
+}
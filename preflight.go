@@ -0,0 +1,105 @@
+package ghdownloader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/google/go-github/v68/github"
+)
+
+// maxSuggestions is how many "did you mean" candidates Preflight offers for
+// a repository that can't be found.
+const maxSuggestions = 3
+
+// Preflight checks that every user/repo exists and is accessible with the
+// configured token before any download starts, so a typo fails the run up
+// front instead of midway through. For repositories that can't be found it
+// suggests similarly named ones found through the search API.
+func (d *Downloader) Preflight(userRepos []string) error {
+	return d.PreflightContext(context.Background(), userRepos)
+}
+
+// PreflightContext is like Preflight but honors cancellation of ctx.
+func (d *Downloader) PreflightContext(ctx context.Context, userRepos []string) error {
+	ctx = d.rateLimitContext(ctx)
+
+	errs := make([]error, len(userRepos))
+	var wg sync.WaitGroup
+	sem := newSemaphore(d.maxConcurrentRepos)
+	for i, userRepo := range userRepos {
+		owner, repo, _, err := parseRepoSpec(userRepo)
+		if err != nil {
+			errs[i] = fmt.Errorf("invalid user/repo format '%s': %v", userRepo, err)
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int, owner, repo string) {
+			defer wg.Done()
+			sem.acquire()
+			defer sem.release()
+			if err := d.checkRepo(ctx, owner, repo); err != nil {
+				errs[i] = d.repoError(owner, repo, err)
+			}
+		}(i, owner, repo)
+	}
+	wg.Wait()
+
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("preflight check failed:\n%w", err)
+	}
+	return nil
+}
+
+// checkRepo verifies that owner/repo is accessible.
+func (d *Downloader) checkRepo(ctx context.Context, owner, repo string) error {
+	if !d.isGitHub(owner, repo) {
+		if _, _, err := d.provider(owner, repo).ListReleases(ctx, owner, repo, 1, 1); err != nil {
+			return fmt.Errorf("error listing releases: %v", err)
+		}
+		return nil
+	}
+	_, resp, err := d.client.Repositories.Get(ctx, owner, repo)
+	if err == nil {
+		return nil
+	}
+	if resp == nil || resp.StatusCode != http.StatusNotFound {
+		return fmt.Errorf("error fetching repository: %v", err)
+	}
+
+	// GitHub answers 404 for private repositories the token can't see too.
+	msg := "repository not found or not accessible with the given token"
+	if suggestions := d.suggestRepos(ctx, owner, repo); len(suggestions) > 0 {
+		msg += fmt.Sprintf(" (did you mean %s?)", strings.Join(suggestions, ", "))
+	}
+	return fmt.Errorf("%s", msg)
+}
+
+// suggestRepos searches for repositories named like repo, preferring those
+// owned by owner. Search failures just mean there are no suggestions.
+func (d *Downloader) suggestRepos(ctx context.Context, owner, repo string) []string {
+	opts := &github.SearchOptions{ListOptions: github.ListOptions{PerPage: 10}}
+	result, _, err := d.client.Search.Repositories(ctx, repo+" in:name", opts)
+	if err != nil {
+		return nil
+	}
+
+	var same, other []string
+	for _, r := range result.Repositories {
+		name := r.GetFullName()
+		if strings.EqualFold(r.GetOwner().GetLogin(), owner) {
+			same = append(same, name)
+		} else {
+			other = append(other, name)
+		}
+	}
+	suggestions := append(same, other...)
+	if len(suggestions) > maxSuggestions {
+		suggestions = suggestions[:maxSuggestions]
+	}
+	return suggestions
+}
@@ -0,0 +1,54 @@
+package ghdownloader
+
+import (
+	"context"
+	"os/exec"
+	"regexp"
+	"strings"
+)
+
+// versionPattern matches dotted version numbers such as "1.2.3", "v0.10" or
+// "2.0.0-rc.1" inside tags and command output.
+var versionPattern = regexp.MustCompile(`\d+(?:\.\d+)+(?:-[0-9A-Za-z.-]+)?`)
+
+// SetVersionProbe registers a command (e.g. "tool", "--version") that reports
+// the installed version of the binary released by userRepo. If the version it
+// prints matches the latest release tag, the download is skipped.
+func (d *Downloader) SetVersionProbe(userRepo string, command ...string) {
+	if len(command) == 0 {
+		delete(d.versionProbes, strings.ToLower(userRepo))
+		return
+	}
+	d.versionProbes[strings.ToLower(userRepo)] = command
+}
+
+// installedVersion runs the version probe registered for owner/repo, if any,
+// and returns the version it reports.
+func (d *Downloader) installedVersion(ctx context.Context, owner, repo string) (string, bool) {
+	command, ok := d.versionProbes[strings.ToLower(owner+"/"+repo)]
+	if !ok {
+		return "", false
+	}
+
+	// A failing probe usually means the tool isn't installed; treat it as such.
+	out, err := exec.CommandContext(ctx, command[0], command[1:]...).CombinedOutput()
+	if err != nil {
+		return "", false
+	}
+
+	version := extractVersion(string(out))
+	return version, version != ""
+}
+
+// extractVersion returns the first version number found in s, without any
+// leading "v".
+func extractVersion(s string) string {
+	return versionPattern.FindString(s)
+}
+
+// versionsMatch reports whether an installed version string matches a
+// release tag, ignoring prefixes such as "v" or "tool-".
+func versionsMatch(installed, tag string) bool {
+	tagVersion := extractVersion(tag)
+	return tagVersion != "" && extractVersion(installed) == tagVersion
+}
@@ -0,0 +1,56 @@
+package ghdownloader
+
+import (
+	"os/exec"
+	"testing"
+)
+
+func TestVersionsMatch(t *testing.T) {
+	tests := []struct {
+		installed string
+		tag       string
+		want      bool
+	}{
+		{"tool version 1.2.3", "v1.2.3", true},
+		{"tool 1.2.3 (abc123)", "tool-1.2.3", true},
+		{"v2.0.0-rc.1", "2.0.0-rc.1", true},
+		{"tool version 1.2.3", "v1.2.4", false},
+		{"1.2.3", "v1.2", false},
+		{"no version here", "v1.2.3", false},
+		{"1.2.3", "nightly", false},
+	}
+	for _, tt := range tests {
+		if got := versionsMatch(tt.installed, tt.tag); got != tt.want {
+			t.Errorf("versionsMatch(%q, %q) = %v, want %v", tt.installed, tt.tag, got, tt.want)
+		}
+	}
+}
+
+func TestVersionProbeSkipsInstalledRelease(t *testing.T) {
+	if _, err := exec.LookPath("echo"); err != nil {
+		t.Skip("no echo command")
+	}
+	g := newFakeGitHub(t)
+	g.addRelease("owner/tool", "v1.2.3", map[string]string{"tool": "bin"})
+	tests := []struct {
+		name  string
+		probe []string
+		skip  bool
+	}{
+		{name: "same version", probe: []string{"echo", "tool version 1.2.3"}, skip: true},
+		{name: "older version", probe: []string{"echo", "tool version 1.2.2"}},
+		{name: "probe fails", probe: []string{"ghdownloader-no-such-command"}},
+		{name: "no probe"},
+	}
+	for _, tt := range tests {
+		d := g.downloader(t)
+		d.SetVersionProbe("Owner/Tool", tt.probe...)
+		paths, err := d.DownloadLatestReleases([]string{"owner/tool"})
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if skipped := len(paths) == 0; skipped != tt.skip {
+			t.Errorf("%s: downloaded %v, want skipped %v", tt.name, paths, tt.skip)
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package ghdownloader
+
+import "io"
+
+// ProgressFunc receives progress updates while an asset of repo ("owner/repo")
+// downloads. total is the asset size in bytes, or -1 if unknown. It is called
+// from the downloading goroutines, so it must be safe for concurrent use.
+type ProgressFunc func(repo, asset string, downloaded, total int64)
+
+// SetProgressFunc sets the function that receives download progress. A nil
+// function disables progress reporting.
+func (d *Downloader) SetProgressFunc(fn ProgressFunc) {
+	d.progress = fn
+}
+
+// progressReader reports the bytes read through it to a ProgressFunc.
+type progressReader struct {
+	r     io.Reader
+	fn    ProgressFunc
+	repo  string
+	asset string
+	total int64
+	n     int64
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	if n > 0 {
+		p.n += int64(n)
+		p.fn(p.repo, p.asset, p.n, p.total)
+	}
+	return n, err
+}
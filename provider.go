@@ -0,0 +1,343 @@
+package ghdownloader
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/google/go-github/v68/github"
+)
+
+// ErrNotFound is wrapped by ReleaseProvider errors for releases and
+// repositories that don't exist.
+var ErrNotFound = errors.New("not found")
+
+// ReleaseProvider fetches releases and their assets from a hosting service.
+// Releases and assets are described with go-github's types whatever the
+// service, so the rest of the downloader handles them alike. Only the
+// fields the service knows about are set; an asset without a size is not
+// size-checked.
+type ReleaseProvider interface {
+	// ListReleases returns a page of the releases of owner/repo, newest
+	// first, and the number of the next page, or 0 after the last one.
+	// Pages are numbered from 1.
+	ListReleases(ctx context.Context, owner, repo string, page, perPage int) ([]*github.RepositoryRelease, int, error)
+	// GetLatest returns the latest release of owner/repo, or an error
+	// wrapping ErrNotFound if there is none.
+	GetLatest(ctx context.Context, owner, repo string) (*github.RepositoryRelease, error)
+	// GetByTag returns the release of owner/repo tagged tag, or an error
+	// wrapping ErrNotFound if there is none.
+	GetByTag(ctx context.Context, owner, repo, tag string) (*github.RepositoryRelease, error)
+	// DownloadAsset requests the content of asset from byte offset on. The
+	// response is either 200 with the whole asset or 206 with the rest of
+	// it; other statuses are returned as errors.
+	DownloadAsset(ctx context.Context, asset *github.ReleaseAsset, offset int64) (*http.Response, error)
+}
+
+// SetProvider fetches the releases of every repository without a provider
+// of its own from p instead of GitHub. Features built on other GitHub APIs
+// (following moved repositories, archived policies, asset hints files and
+// preflight suggestions) only apply to repositories served by GitHub. A nil
+// p restores GitHub.
+func (d *Downloader) SetProvider(p ReleaseProvider) {
+	d.defaultProvider = p
+}
+
+// SetRepoProvider fetches the releases of a single "owner/repo" from p.
+func (d *Downloader) SetRepoProvider(userRepo string, p ReleaseProvider) {
+	d.repoProviders[strings.ToLower(userRepo)] = p
+}
+
+// RegisterRepoURL sets up the provider for the repository at rawURL, such as
+// "https://gitlab.com/group/tool" or "https://gitea.example.com/owner/tool",
+// and returns the "owner/repo" spec to download it with. A trailing "@tag"
+// is kept in the spec. Hosts named "gitlab…" are served by GitLab, the
+// GitHub host by GitHub, and any other host by Gitea (which also covers
+// Forgejo instances such as Codeberg). Tokens are taken from the
+// GITLAB_TOKEN and GITEA_TOKEN environment variables.
+func (d *Downloader) RegisterRepoURL(rawURL string) (string, error) {
+	u, path, spec, err := parseRepoURL(rawURL)
+	if err != nil {
+		return "", err
+	}
+
+	baseURL := u.Scheme + "://" + u.Host
+	host := strings.ToLower(u.Hostname())
+	var p ReleaseProvider
+	switch {
+	case host == "github.com" || host == "www.github.com" || strings.EqualFold(u.Host, d.client.BaseURL.Host):
+		p = &githubProvider{d: d}
+	case strings.HasPrefix(host, "gitlab.") || strings.Contains(host, ".gitlab."):
+		p, err = d.NewGitLabProvider(baseURL, "")
+	default:
+		p, err = d.NewGiteaProvider(baseURL, "")
+	}
+	if err != nil {
+		return "", err
+	}
+	d.SetRepoProvider(path, p)
+	return spec, nil
+}
+
+// parseRepoURL splits a repository URL as accepted by RegisterRepoURL into
+// the parsed URL, the "owner/repo" path and the spec including any "@tag".
+func parseRepoURL(rawURL string) (u *url.URL, path, spec string, err error) {
+	u, err = url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, "", "", fmt.Errorf("invalid repository URL '%s'", rawURL)
+	}
+	path = strings.Trim(u.Path, "/")
+	path, tag, hasTag := strings.Cut(path, "@")
+	// Drop GitLab's "/-/releases" style suffixes and clone URL extensions.
+	path, _, _ = strings.Cut(path, "/-/")
+	path = strings.TrimSuffix(strings.TrimSuffix(path, "/"), ".git")
+	if _, _, err := parseUserRepo(path); err != nil {
+		return nil, "", "", fmt.Errorf("invalid repository URL '%s': %v", rawURL, err)
+	}
+	spec = path
+	if hasTag {
+		spec += "@" + tag
+	}
+	return u, path, spec, nil
+}
+
+// validateRepo checks a repository given either as "owner/repo" or as a URL
+// accepted by RegisterRepoURL.
+func validateRepo(repo string) error {
+	if strings.Contains(repo, "://") {
+		_, _, _, err := parseRepoURL(repo)
+		return err
+	}
+	_, _, err := parseUserRepo(repo)
+	return err
+}
+
+// provider returns the provider serving owner/repo.
+func (d *Downloader) provider(owner, repo string) ReleaseProvider {
+	if p, ok := d.repoProviders[strings.ToLower(owner+"/"+repo)]; ok {
+		return p
+	}
+	if d.defaultProvider != nil {
+		return d.defaultProvider
+	}
+	return &githubProvider{d: d}
+}
+
+// isGitHub reports whether owner/repo is served by GitHub.
+func (d *Downloader) isGitHub(owner, repo string) bool {
+	_, ok := d.provider(owner, repo).(*githubProvider)
+	return ok
+}
+
+// githubProvider fetches releases through the downloader's GitHub client.
+type githubProvider struct {
+	d *Downloader
+}
+
+func (p *githubProvider) ListReleases(ctx context.Context, owner, repo string, page, perPage int) ([]*github.RepositoryRelease, int, error) {
+	opts := &github.ListOptions{Page: page, PerPage: perPage}
+	releases, resp, err := p.d.client.Repositories.ListReleases(ctx, owner, repo, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	return releases, resp.NextPage, nil
+}
+
+func (p *githubProvider) GetLatest(ctx context.Context, owner, repo string) (*github.RepositoryRelease, error) {
+	release, resp, err := p.d.client.Repositories.GetLatestRelease(ctx, owner, repo)
+	if err != nil && resp != nil && resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("%w: %v", ErrNotFound, err)
+	}
+	return release, err
+}
+
+func (p *githubProvider) GetByTag(ctx context.Context, owner, repo, tag string) (*github.RepositoryRelease, error) {
+	release, resp, err := p.d.client.Repositories.GetReleaseByTag(ctx, owner, repo, tag)
+	if err != nil && resp != nil && resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("%w: %v", ErrNotFound, err)
+	}
+	return release, err
+}
+
+func (p *githubProvider) DownloadAsset(ctx context.Context, asset *github.ReleaseAsset, offset int64) (*http.Response, error) {
+	// First request: ask the asset API endpoint for the asset. GitHub.com
+	// redirects to its CDN, while GitHub Enterprise Server may serve the
+	// asset itself.
+	req, err := http.NewRequestWithContext(ctx, "GET", asset.GetURL(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create HTTP request: %v", err)
+	}
+	if p.d.token != "" {
+		req.Header.Set("Authorization", "token "+p.d.token)
+	}
+	req.Header.Set("Accept", "application/octet-stream")
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+
+	// Use a custom client to capture the redirect, so the token isn't sent
+	// to the CDN
+	client := &http.Client{
+		Transport: p.d.transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get asset redirect URL: %v", err)
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusPartialContent:
+		// Served directly
+		return resp, nil
+	case http.StatusFound, http.StatusTemporaryRedirect:
+		resp.Body.Close()
+		// Location may be relative to the asset URL.
+		redirectURL, err := resp.Location()
+		if err != nil {
+			return nil, fmt.Errorf("no redirect location found for asset '%s'", asset.GetName())
+		}
+
+		// Second request: download the asset using the redirect URL
+		secondReq, err := http.NewRequestWithContext(ctx, "GET", redirectURL.String(), nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create HTTP request for redirected URL: %v", err)
+		}
+		secondReq.Header.Set("Accept", "application/octet-stream")
+		if offset > 0 {
+			secondReq.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		}
+
+		secondResp, err := (&http.Client{Transport: p.d.transport}).Do(secondReq)
+		if err != nil {
+			return nil, fmt.Errorf("failed to download asset from redirect URL: %v", err)
+		}
+		return checkStatus(secondResp)
+	default:
+		return checkStatus(resp)
+	}
+}
+
+// checkStatus returns resp if its status is 200 or 206. Otherwise it closes
+// the body and returns an error, wrapping ErrNotFound for 404 and
+// ErrRateLimited for 429 or an exhausted GitHub rate limit.
+func checkStatus(resp *http.Response) (*http.Response, error) {
+	target := redactURL(resp.Request.URL)
+	switch {
+	case resp.StatusCode == http.StatusOK || resp.StatusCode == http.StatusPartialContent:
+		return resp, nil
+	case resp.StatusCode == http.StatusNotFound:
+		resp.Body.Close()
+		return nil, fmt.Errorf("%w: GET %s: %s", ErrNotFound, target, resp.Status)
+	case resp.StatusCode == http.StatusTooManyRequests,
+		resp.StatusCode == http.StatusForbidden && resp.Header.Get("X-RateLimit-Remaining") == "0":
+		resp.Body.Close()
+		return nil, fmt.Errorf("%w: GET %s: %s", ErrRateLimited, target, resp.Status)
+	default:
+		resp.Body.Close()
+		return nil, fmt.Errorf("GET %s: %s", target, resp.Status)
+	}
+}
+
+// restClient makes the requests of the GitLab and Gitea providers through
+// the downloader's transport, so they are retried and logged like GitHub's.
+type restClient struct {
+	d *Downloader
+	// apiURL is the API root, without a trailing slash.
+	apiURL string
+	// host is the only host credentials are sent to.
+	host string
+	// auth is the Authorization header value, if any.
+	auth string
+}
+
+// newRestClient returns a client for the API at apiPath of the instance at
+// baseURL, authenticating with auth if token isn't empty.
+func newRestClient(d *Downloader, baseURL, apiPath, auth, token string) (*restClient, error) {
+	u, err := url.Parse(strings.TrimSuffix(baseURL, "/"))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid URL '%s': expected 'https://host'", baseURL)
+	}
+	c := &restClient{d: d, apiURL: u.String() + apiPath, host: u.Host}
+	if token != "" {
+		c.auth = auth + " " + token
+	}
+	return c, nil
+}
+
+// get sends a GET request for rawURL, adding credentials if it goes to the
+// instance. Statuses other than 200 and 206 are returned as errors, as
+// checkStatus does.
+func (c *restClient) get(ctx context.Context, rawURL string, header http.Header) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", rawURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create HTTP request: %v", err)
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	// The client drops the header if a download redirects to another host.
+	if c.auth != "" && strings.EqualFold(req.URL.Host, c.host) {
+		req.Header.Set("Authorization", c.auth)
+	}
+
+	resp, err := (&http.Client{Transport: c.d.transport}).Do(req)
+	if err != nil {
+		return nil, err
+	}
+	return checkStatus(resp)
+}
+
+// getJSON decodes the response to a GET request for the API path into v and
+// returns the response headers.
+func (c *restClient) getJSON(ctx context.Context, path string, query url.Values, v any) (http.Header, error) {
+	rawURL := c.apiURL + path
+	if len(query) > 0 {
+		rawURL += "?" + query.Encode()
+	}
+	resp, err := c.get(ctx, rawURL, http.Header{"Accept": {"application/json"}})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return nil, fmt.Errorf("failed to decode response of '%s': %v", path, err)
+	}
+	return resp.Header, nil
+}
+
+// download requests rawURL from byte offset on.
+func (c *restClient) download(ctx context.Context, rawURL string, offset int64) (*http.Response, error) {
+	header := http.Header{"Accept": {"application/octet-stream"}}
+	if offset > 0 {
+		header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+	resp, err := c.get(ctx, rawURL, header)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download asset: %v", err)
+	}
+	return resp, nil
+}
+
+// pageQuery returns the query parameters selecting a page, using the
+// service's name for the page size.
+func pageQuery(sizeParam string, page, perPage int) url.Values {
+	return url.Values{"page": {strconv.Itoa(page)}, sizeParam: {strconv.Itoa(perPage)}}
+}
+
+// envToken returns token, or the environment variable env if token is empty.
+func envToken(token, env string) string {
+	if token == "" {
+		return os.Getenv(env)
+	}
+	return token
+}
@@ -0,0 +1,61 @@
+package ghdownloader
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-github/v68/github"
+)
+
+func TestGitHubDownloadAssetStatus(t *testing.T) {
+	tests := []struct {
+		name    string
+		cdn     func(w http.ResponseWriter)
+		want    error
+		wantErr bool
+	}{
+		{"ok", func(w http.ResponseWriter) { io.WriteString(w, "data") }, nil, false},
+		{"not found", func(w http.ResponseWriter) { w.WriteHeader(http.StatusNotFound) }, ErrNotFound, true},
+		{"too many requests", func(w http.ResponseWriter) { w.WriteHeader(http.StatusTooManyRequests) }, ErrRateLimited, true},
+		{"forbidden", func(w http.ResponseWriter) { w.WriteHeader(http.StatusForbidden) }, nil, true},
+		{"server error", func(w http.ResponseWriter) { w.WriteHeader(http.StatusBadGateway) }, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/api/asset" {
+					http.Redirect(w, r, "/cdn/asset?signature=secret", http.StatusFound)
+					return
+				}
+				tt.cdn(w)
+			}))
+			defer srv.Close()
+
+			d := newTestDownloader(t)
+			d.SetRetries(0)
+			asset := &github.ReleaseAsset{Name: github.String("asset"), URL: github.String(srv.URL + "/api/asset")}
+			resp, err := (&githubProvider{d: d}).DownloadAsset(context.Background(), asset, 0)
+			if !tt.wantErr {
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer resp.Body.Close()
+				if data, _ := io.ReadAll(resp.Body); string(data) != "data" {
+					t.Errorf("body = %q", data)
+				}
+				return
+			}
+			if err == nil {
+				resp.Body.Close()
+				t.Fatalf("status %s accepted as asset content", resp.Status)
+			}
+			if tt.want != nil && !errors.Is(err, tt.want) {
+				t.Errorf("err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
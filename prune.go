@@ -0,0 +1,80 @@
+package ghdownloader
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// SetKeepVersions prunes the version directories of each repository after
+// its release downloads successfully, keeping only the keep most recent (see
+// Prune). Zero or less keeps every version.
+func (d *Downloader) SetKeepVersions(keep int) {
+	d.keepVersions = max(keep, 0)
+}
+
+// SetPruneDryRun makes Prune and the pruning done after downloads only log
+// and return the version directories they would delete.
+func (d *Downloader) SetPruneDryRun(dryRun bool) {
+	d.pruneDryRun = dryRun
+}
+
+// Prune deletes all but the keep most recent version directories of
+// userRepo ("owner/repo", or just the repository name to match any owner)
+// in destDir, returning the deleted paths. Versions are ordered by tag if
+// every tag is a semantic version, and by modification time otherwise. Only
+// directories whose tag starts with a version number, such as "v1.2.0" or
+// "2024.01", are considered, so those of repositories whose name merely
+// starts with the same prefix are left alone. The targets of the "current"
+// and "previous" links are never deleted.
+func (d *Downloader) Prune(userRepo string, keep int) ([]string, error) {
+	owner, repo := "", userRepo
+	if i := strings.LastIndex(userRepo, "/"); i >= 0 {
+		owner, repo = userRepo[:i], userRepo[i+1:]
+	}
+	return d.prune(strings.ToLower(owner+"/"+repo), owner, repo, keep, "")
+}
+
+// prune implements Prune for the repository keyed key, also keeping the
+// version directory keepDir.
+func (d *Downloader) prune(key, owner, repo string, keep int, keepDir string) ([]string, error) {
+	if keep < 1 {
+		return nil, fmt.Errorf("invalid number of versions to keep: %d", keep)
+	}
+	dirs, err := d.versionDirs(key, owner, repo)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list versions of '%s': %v", repo, err)
+	}
+	if len(dirs) <= keep {
+		return nil, nil
+	}
+
+	protected := map[string]bool{filepath.Clean(keepDir): keepDir != ""}
+	for _, name := range []string{"current", "previous"} {
+		linkPath, err := d.linkPath(key, owner, repo, name)
+		if err != nil {
+			continue
+		}
+		if target, err := readLinkTarget(linkPath); err == nil {
+			protected[target] = true
+		}
+	}
+
+	var removed []string
+	for _, dir := range dirs[keep:] {
+		if protected[dir.path] {
+			continue
+		}
+		if d.pruneDryRun {
+			d.infof("Would remove '%s'", dir.path)
+		} else {
+			if err := os.RemoveAll(dir.path); err != nil {
+				return removed, fmt.Errorf("failed to remove '%s': %v", dir.path, err)
+			}
+			d.infof("Removed '%s'", dir.path)
+		}
+		removed = append(removed, dir.path)
+	}
+	return removed, nil
+}
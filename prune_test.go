@@ -0,0 +1,111 @@
+package ghdownloader
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestPrune(t *testing.T) {
+	tests := []struct {
+		name    string
+		dirs    []string
+		repo    string
+		keep    int
+		removed []string
+	}{
+		{
+			name:    "semver order",
+			dirs:    []string{"foo-v0.10.0", "foo-v0.9.0", "foo-v0.2.0"},
+			repo:    "owner/foo",
+			keep:    1,
+			removed: []string{"foo-v0.9.0", "foo-v0.2.0"},
+		},
+		{
+			// Directories are modified in the order given.
+			name:    "modification time order",
+			dirs:    []string{"foo-2024.02", "foo-2023.12", "foo-2024.01"},
+			repo:    "owner/foo",
+			keep:    2,
+			removed: []string{"foo-2024.02"},
+		},
+		{
+			name:    "repository sharing a name prefix",
+			dirs:    []string{"foo-v0.1.0", "foo-v0.2.0", "foo-bar-v0.1.0", "foo-bar-v0.2.0"},
+			repo:    "owner/foo",
+			keep:    1,
+			removed: []string{"foo-v0.1.0"},
+		},
+		{
+			name:    "longer repository name",
+			dirs:    []string{"foo-v0.1.0", "foo-bar-v0.1.0", "foo-bar-v0.2.0"},
+			repo:    "owner/foo-bar",
+			keep:    1,
+			removed: []string{"foo-bar-v0.1.0"},
+		},
+		{
+			name: "fewer versions than kept",
+			dirs: []string{"foo-v0.1.0", "foo-v0.2.0"},
+			repo: "foo",
+			keep: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := newTestDownloader(t)
+			makeDirs(t, d.destDir, tt.dirs...)
+			removed, err := d.Prune(tt.repo, tt.keep)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var want []string
+			for _, name := range tt.removed {
+				want = append(want, filepath.Join(d.destDir, name))
+			}
+			if !slices.Equal(removed, want) {
+				t.Errorf("removed %q, want %q", removed, want)
+			}
+			for _, name := range tt.dirs {
+				gone := slices.Contains(tt.removed, name)
+				if exists := slices.Contains(listDir(t, d.destDir), name); exists == gone {
+					t.Errorf("%s exists = %v, want %v", name, exists, !gone)
+				}
+			}
+		})
+	}
+}
+
+func TestPruneKeepsCurrentTarget(t *testing.T) {
+	d := newTestDownloader(t)
+	makeDirs(t, d.destDir, "foo-v0.1.0", "foo-v0.2.0", "foo-v0.3.0")
+	if err := d.updateCurrent("owner/foo", "owner", "foo", filepath.Join(d.destDir, "foo-v0.1.0")); err != nil {
+		t.Fatal(err)
+	}
+	removed, err := d.Prune("owner/foo", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{filepath.Join(d.destDir, "foo-v0.2.0")}; !slices.Equal(removed, want) {
+		t.Errorf("removed %q, want %q", removed, want)
+	}
+}
+
+func TestPruneDryRun(t *testing.T) {
+	d := newTestDownloader(t)
+	d.SetPruneDryRun(true)
+	makeDirs(t, d.destDir, "foo-v0.1.0", "foo-v0.2.0")
+	removed, err := d.Prune("owner/foo", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(removed) != 1 || len(listDir(t, d.destDir)) != 2 {
+		t.Errorf("removed %q, left %q", removed, listDir(t, d.destDir))
+	}
+}
+
+func TestPruneInvalidKeep(t *testing.T) {
+	d := newTestDownloader(t)
+	if _, err := d.Prune("owner/foo", 0); err == nil {
+		t.Error("expected an error for keep 0")
+	}
+}
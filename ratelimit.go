@@ -0,0 +1,163 @@
+package ghdownloader
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxRateBurst caps how many bytes may be read at once under a rate limit,
+// so throttled transfers flow evenly instead of in large bursts.
+const maxRateBurst = 256 << 10
+
+// SetRateLimit caps the combined throughput of all asset transfers at
+// bytesPerSec, however many run at once. API requests aren't throttled.
+// Zero or less disables the limit. Keep the minimum rate of the stall
+// watchdog well below the limit, or throttled transfers look stalled.
+func (d *Downloader) SetRateLimit(bytesPerSec int64) {
+	if bytesPerSec <= 0 {
+		d.rateLimiter = nil
+		return
+	}
+	d.rateLimiter = newTokenBucket(bytesPerSec, min(bytesPerSec, maxRateBurst))
+}
+
+// SetMaxHostConnections limits how many asset transfers run against a single
+// host at once, such as GitHub's asset CDN, across all repositories and
+// releases. Transfers over the limit wait for a connection to free up. Zero
+// or less removes the limit.
+func (d *Downloader) SetMaxHostConnections(n int) {
+	d.hostMu.Lock()
+	defer d.hostMu.Unlock()
+	d.maxHostConns = max(n, 0)
+	d.hostSlots = make(map[string]semaphore)
+}
+
+// throttle returns r, limited to the downloader's rate limit if one is set.
+func (d *Downloader) throttle(ctx context.Context, r io.Reader) io.Reader {
+	if d.rateLimiter == nil {
+		return r
+	}
+	return &throttledReader{ctx: ctx, r: r, bucket: d.rateLimiter}
+}
+
+// tokenBucket hands out bytes at a fixed rate, allowing bursts of up to
+// burst bytes after idle periods.
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate, burst int64) *tokenBucket {
+	return &tokenBucket{rate: float64(rate), burst: float64(burst), tokens: float64(burst), last: time.Now()}
+}
+
+// wait takes n tokens, blocking until they have accrued or ctx is done.
+// Tokens are reserved up front, so concurrent readers queue fairly.
+func (b *tokenBucket) wait(ctx context.Context, n int) error {
+	b.mu.Lock()
+	now := time.Now()
+	b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+	b.tokens -= float64(n)
+	delay := time.Duration(-b.tokens / b.rate * float64(time.Second))
+	b.mu.Unlock()
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// throttledReader reads from r no faster than its bucket allows.
+type throttledReader struct {
+	ctx    context.Context
+	r      io.Reader
+	bucket *tokenBucket
+}
+
+func (t *throttledReader) Read(p []byte) (int, error) {
+	if len(p) > int(t.bucket.burst) {
+		p = p[:int(t.bucket.burst)]
+	}
+	n, err := t.r.Read(p)
+	if n > 0 {
+		if waitErr := t.bucket.wait(t.ctx, n); waitErr != nil && err == nil {
+			err = waitErr
+		}
+	}
+	return n, err
+}
+
+// hostLimitTransport holds one of a limited number of per-host slots for
+// each asset transfer until its response body is closed.
+type hostLimitTransport struct {
+	d    *Downloader
+	next http.RoundTripper
+}
+
+func (t *hostLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	slots := t.d.hostSlot(req)
+	if slots == nil {
+		return t.next.RoundTrip(req)
+	}
+	select {
+	case slots <- struct{}{}:
+	case <-req.Context().Done():
+		return nil, req.Context().Err()
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		slots.release()
+		return resp, err
+	}
+	resp.Body = &releaseBody{ReadCloser: resp.Body, release: slots.release}
+	return resp, nil
+}
+
+// hostSlot returns the semaphore limiting transfers to the host of req, or
+// nil if req isn't limited.
+func (d *Downloader) hostSlot(req *http.Request) semaphore {
+	if !strings.Contains(req.Header.Get("Accept"), "application/octet-stream") {
+		return nil
+	}
+	d.hostMu.Lock()
+	defer d.hostMu.Unlock()
+	if d.maxHostConns == 0 {
+		return nil
+	}
+	host := strings.ToLower(req.URL.Host)
+	slots, ok := d.hostSlots[host]
+	if !ok {
+		slots = newSemaphore(d.maxHostConns)
+		d.hostSlots[host] = slots
+	}
+	return slots
+}
+
+// releaseBody frees a host slot once the response body is closed.
+type releaseBody struct {
+	io.ReadCloser
+	once    sync.Once
+	release func()
+}
+
+func (b *releaseBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.release)
+	return err
+}
@@ -0,0 +1,81 @@
+package ghdownloader
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRateLimit(t *testing.T) {
+	// The first rate bytes pass at once, the rest take half a second.
+	const rate = 20000
+	data := strings.Repeat("x", rate*3/2)
+	g := newFakeGitHub(t)
+	g.addRelease("owner/tool", "v1.0.0", map[string]string{"tool": data})
+	d := g.downloader(t)
+	d.SetRateLimit(rate)
+
+	start := time.Now()
+	paths, err := d.DownloadLatestReleases([]string{"owner/tool"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 400*time.Millisecond {
+		t.Errorf("downloaded %d bytes in %s at %d bytes/s", len(data), elapsed, rate)
+	}
+	if got, err := os.ReadFile(paths[0]); err != nil || string(got) != data {
+		t.Errorf("downloaded %d bytes, %v", len(got), err)
+	}
+}
+
+func TestTokenBucketCancel(t *testing.T) {
+	b := newTokenBucket(1, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := b.wait(ctx, 100); err != context.Canceled {
+		t.Errorf("wait = %v, want context.Canceled", err)
+	}
+}
+
+func TestMaxHostConnections(t *testing.T) {
+	g := newFakeGitHub(t)
+	assets := make(map[string]string)
+	for _, name := range []string{"a", "b", "c", "d"} {
+		assets[name] = name
+	}
+	g.addRelease("owner/tool", "v1.0.0", assets)
+	var mu sync.Mutex
+	var active, peak int
+	g.hook = func(w http.ResponseWriter, r *http.Request) bool {
+		if !strings.Contains(r.URL.Path, "/releases/assets/") {
+			return false
+		}
+		mu.Lock()
+		active++
+		peak = max(peak, active)
+		mu.Unlock()
+		time.Sleep(20 * time.Millisecond)
+		mu.Lock()
+		active--
+		mu.Unlock()
+		return false
+	}
+	d := g.downloader(t)
+	d.SetMaxConcurrentAssets(4)
+	d.SetMaxHostConnections(1)
+
+	paths, err := d.DownloadLatestReleases([]string{"owner/tool"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) != 4 {
+		t.Errorf("downloaded %v, want 4 assets", paths)
+	}
+	if peak != 1 {
+		t.Errorf("%d transfers ran against the host at once, want 1", peak)
+	}
+}
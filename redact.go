@@ -0,0 +1,104 @@
+package ghdownloader
+
+import (
+	"log/slog"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// redacted replaces secrets removed from log output and errors.
+const redacted = "REDACTED"
+
+var (
+	// tokenPattern matches GitHub tokens (classic, fine-grained, OAuth, app
+	// and refresh tokens) even when they aren't the configured token.
+	tokenPattern = regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{20,}|github_pat_[A-Za-z0-9_]{20,})\b`)
+	// urlQueryPattern matches the query string of URLs, which for asset
+	// redirects carries short-lived signed credentials.
+	urlQueryPattern = regexp.MustCompile(`(https?://[^\s?'"]+)\?[^\s'"]*`)
+	// authHeaderPattern matches Authorization header values.
+	authHeaderPattern = regexp.MustCompile(`(?i)(authorization:\s*(?:token|bearer|basic)\s+)\S+`)
+)
+
+// SetVerbose enables logging of every HTTP request made by the downloader,
+// at Debug level. Only the method, redacted URL, status and duration are
+// logged; headers and bodies never are, so credentials can't leak into the
+// log. The default logger shows Debug messages while verbose.
+func (d *Downloader) SetVerbose(verbose bool) {
+	d.verbose = verbose
+	if verbose {
+		d.defaultLevel.Set(slog.LevelDebug)
+	} else {
+		d.defaultLevel.Set(slog.LevelInfo)
+	}
+}
+
+// redact scrubs the token and other credentials from s.
+func (d *Downloader) redact(s string) string {
+	if d.token != "" {
+		s = strings.ReplaceAll(s, d.token, redacted)
+	}
+	s = tokenPattern.ReplaceAllString(s, redacted)
+	s = authHeaderPattern.ReplaceAllString(s, "${1}"+redacted)
+	return urlQueryPattern.ReplaceAllString(s, "${1}?"+redacted)
+}
+
+// redactError returns err with credentials scrubbed from its message. The
+// original error stays in the chain so errors.Is and errors.As still see
+// the errors it wraps, whose own messages are not scrubbed.
+func (d *Downloader) redactError(err error) error {
+	if err == nil {
+		return nil
+	}
+	msg := err.Error()
+	if clean := d.redact(msg); clean != msg {
+		return &redactedError{msg: clean, err: err}
+	}
+	return err
+}
+
+// redactedError is an error whose message was scrubbed of credentials.
+type redactedError struct {
+	msg string
+	err error
+}
+
+func (e *redactedError) Error() string { return e.msg }
+
+func (e *redactedError) Unwrap() error { return e.err }
+
+// loggingTransport logs requests when the downloader is verbose. It only
+// ever sees the method, URL and status, which are redacted before logging.
+type loggingTransport struct {
+	d    *Downloader
+	next http.RoundTripper
+}
+
+func (t *loggingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !t.d.verbose {
+		return t.next.RoundTrip(req)
+	}
+
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	target := redactURL(req.URL)
+	if err != nil {
+		t.d.debugf("HTTP %s %s failed after %s: %v", req.Method, target, time.Since(start).Round(time.Millisecond), err)
+		return resp, err
+	}
+	t.d.debugf("HTTP %s %s -> %s (%s)", req.Method, target, resp.Status, time.Since(start).Round(time.Millisecond))
+	return resp, err
+}
+
+// redactURL renders u without user info or query parameters.
+func redactURL(u *url.URL) string {
+	clean := *u
+	clean.User = nil
+	if clean.RawQuery != "" {
+		clean.RawQuery = redacted
+	}
+	return clean.String()
+}
@@ -0,0 +1,126 @@
+package ghdownloader
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed registry.yaml
+var builtinRegistry []byte
+
+// RegistryEntry maps a tool name to the repository that releases it and the
+// rules for picking its asset. If neither an asset template nor Match is
+// given, every asset of the latest release is downloaded.
+type RegistryEntry struct {
+	Repo       string `yaml:"repo"`
+	Match      string `yaml:"match,omitempty"`
+	AssetHints `yaml:",inline"`
+}
+
+// Registry maps tool names to registry entries.
+type Registry map[string]RegistryEntry
+
+// DefaultRegistry returns the built-in registry merged with the user registry
+// at <user config dir>/ghdownloader/registry.yaml, if it exists.
+func DefaultRegistry() (Registry, error) {
+	registry, err := ParseRegistry(builtinRegistry)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse built-in registry: %v", err)
+	}
+
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return registry, nil
+	}
+	userPath := filepath.Join(configDir, "ghdownloader", "registry.yaml")
+	if _, err := os.Stat(userPath); err != nil {
+		return registry, nil
+	}
+	user, err := LoadRegistry(userPath)
+	if err != nil {
+		return nil, err
+	}
+	registry.Merge(user)
+	return registry, nil
+}
+
+// LoadRegistry reads a registry from a YAML (or JSON) file.
+func LoadRegistry(path string) (Registry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read registry '%s': %v", path, err)
+	}
+	registry, err := ParseRegistry(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse registry '%s': %v", path, err)
+	}
+	return registry, nil
+}
+
+// ParseRegistry parses a registry from YAML (or JSON) data.
+func ParseRegistry(data []byte) (Registry, error) {
+	registry := make(Registry)
+	if err := yaml.Unmarshal(data, &registry); err != nil {
+		return nil, err
+	}
+	for name, entry := range registry {
+		if _, _, err := parseUserRepo(entry.Repo); err != nil {
+			return nil, fmt.Errorf("invalid repo '%s' for '%s': %v", entry.Repo, name, err)
+		}
+	}
+	return registry, nil
+}
+
+// Merge adds the entries of other to r, replacing entries with the same name.
+func (r Registry) Merge(other Registry) {
+	for name, entry := range other {
+		r[name] = entry
+	}
+}
+
+// Names returns the tool names in the registry, sorted.
+func (r Registry) Names() []string {
+	names := make([]string, 0, len(r))
+	for name := range r {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Install downloads the latest release of each named tool, using the
+// repository and asset-selection rules from registry.
+func (d *Downloader) Install(names []string, registry Registry) ([]string, error) {
+	return d.InstallContext(context.Background(), names, registry)
+}
+
+// InstallContext is like Install but honors cancellation of ctx.
+func (d *Downloader) InstallContext(ctx context.Context, names []string, registry Registry) ([]string, error) {
+	var repos, unknown []string
+	for _, name := range names {
+		entry, ok := registry[name]
+		if !ok {
+			unknown = append(unknown, name)
+			continue
+		}
+		if entry.Asset != "" {
+			hints := entry.AssetHints
+			d.SetRepoAssetHints(entry.Repo, &hints)
+		}
+		if entry.Match != "" {
+			d.SetRepoMatchFilter(entry.Repo, entry.Match)
+		}
+		repos = append(repos, entry.Repo)
+	}
+	if len(unknown) > 0 {
+		return nil, fmt.Errorf("unknown tool(s) not found in registry: %s", strings.Join(unknown, ", "))
+	}
+	return d.DownloadLatestReleasesContext(ctx, repos)
+}
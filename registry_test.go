@@ -0,0 +1,71 @@
+package ghdownloader
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-github/v68/github"
+)
+
+func TestInstallPlatformFilter(t *testing.T) {
+	d := newTestDownloader(t)
+	p := &fakeProvider{}
+	p.releases = map[string]*github.RepositoryRelease{
+		"owner/tool": {
+			TagName: github.String("v1.0.0"),
+			Assets: []*github.ReleaseAsset{
+				p.newFakeAsset("tool-linux-amd64", []byte("linux")),
+				p.newFakeAsset("tool-darwin-arm64", []byte("darwin")),
+				p.newFakeAsset("tool-windows-amd64.exe", []byte("windows")),
+			},
+		},
+	}
+	d.SetProvider(p)
+	d.SetPlatformFilter("darwin", "arm64")
+
+	paths, err := d.InstallContext(context.Background(), []string{"tool"}, Registry{"tool": {Repo: "owner/tool"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) != 1 || filepath.Base(paths[0]) != "tool-darwin-arm64" {
+		t.Errorf("installed %v, want only the darwin/arm64 build", paths)
+	}
+}
+
+func TestInstallUnknownTool(t *testing.T) {
+	d := newTestDownloader(t)
+	if _, err := d.InstallContext(context.Background(), []string{"nope"}, Registry{}); err == nil {
+		t.Error("expected an error for a tool missing from the registry")
+	}
+}
+
+func TestInstallIntoInstallDir(t *testing.T) {
+	d := newTestDownloader(t)
+	p := &fakeProvider{}
+	p.releases = map[string]*github.RepositoryRelease{
+		"owner/tool": {
+			TagName: github.String("v1.0.0"),
+			Assets: []*github.ReleaseAsset{
+				p.newFakeAsset("tool-linux-amd64", []byte("linux")),
+				p.newFakeAsset("tool-darwin-arm64", []byte("darwin")),
+			},
+		},
+	}
+	d.SetProvider(p)
+	d.SetPlatformFilter("linux", "amd64")
+	binDir := filepath.Join(t.TempDir(), "bin")
+	d.SetInstallDir(binDir, false)
+
+	if _, err := d.InstallContext(context.Background(), []string{"tool"}, Registry{"tool": {Repo: "owner/tool"}}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(binDir, "tool"))
+	if err != nil || string(data) != "linux" {
+		t.Fatalf("installed %q, %v, want the linux/amd64 build named after the tool", data, err)
+	}
+	if info, err := os.Stat(filepath.Join(binDir, "tool")); err != nil || info.Mode()&0111 == 0 {
+		t.Errorf("installed executable isn't executable: %v, %v", info, err)
+	}
+}
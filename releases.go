@@ -0,0 +1,149 @@
+package ghdownloader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/google/go-github/v68/github"
+)
+
+// ReleaseRange selects the releases DownloadReleases downloads. The zero
+// value selects every published release, including pre-releases.
+type ReleaseRange struct {
+	// Last limits the range to the N most recent releases, if positive.
+	Last int
+	// SinceTag limits the range to releases created after the one tagged
+	// SinceTag (with or without a leading "v").
+	SinceTag string
+}
+
+// ParseReleaseRange parses "all", "last:N" or "since:TAG".
+func ParseReleaseRange(s string) (ReleaseRange, error) {
+	kind, value, _ := strings.Cut(s, ":")
+	switch {
+	case s == "all":
+		return ReleaseRange{}, nil
+	case kind == "last":
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 {
+			return ReleaseRange{}, fmt.Errorf("expected a positive number in 'last:N'")
+		}
+		return ReleaseRange{Last: n}, nil
+	case kind == "since" && value != "":
+		return ReleaseRange{SinceTag: value}, nil
+	}
+	return ReleaseRange{}, fmt.Errorf("expected 'all', 'last:N' or 'since:TAG'")
+}
+
+// DownloadReleases downloads the assets of the releases of owner/repo
+// selected by r into one "<repo>-<tag>" directory per release, oldest first.
+// Unlike MirrorReleases nothing is recorded, so every run downloads the
+// whole range (skipping files that are already present).
+func (d *Downloader) DownloadReleases(owner, repo string, r ReleaseRange) ([]string, error) {
+	return d.DownloadReleasesContext(context.Background(), owner, repo, r)
+}
+
+// DownloadReleasesContext is like DownloadReleases but honors cancellation
+// of ctx.
+func (d *Downloader) DownloadReleasesContext(ctx context.Context, owner, repo string, r ReleaseRange) ([]string, error) {
+	results, err := d.withRun(ctx, func(ctx context.Context) error {
+		return d.downloadReleases(ctx, owner, repo, r)
+	})
+	return resultPaths(results), err
+}
+
+// downloadReleases implements DownloadReleasesContext, recording results in
+// the run of ctx.
+func (d *Downloader) downloadReleases(ctx context.Context, owner, repo string, r ReleaseRange) error {
+	ctx = d.rateLimitContext(ctx)
+
+	releases, err := d.releasesInRange(ctx, owner, repo, r)
+	if err != nil {
+		d.record(ctx, DownloadResult{Owner: owner, Repo: repo, Err: err})
+		return fmt.Errorf("failed to download releases of %w", d.repoError(owner, repo, err))
+	}
+	if len(releases) == 0 {
+		d.infof("No releases of %s/%s in range.", owner, repo)
+		return nil
+	}
+
+	// Releases are listed newest first.
+	key := strings.ToLower(owner + "/" + repo)
+	var errs []error
+	for i := len(releases) - 1; i >= 0; i-- {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		tag := releases[i].GetTagName()
+		failed, err := d.downloadReleaseDir(ctx, key, owner, repo, releases[i])
+		switch {
+		case err != nil:
+			errs = append(errs, fmt.Errorf("release '%s': %w", tag, err))
+		case failed:
+			errs = append(errs, fmt.Errorf("release '%s': some assets failed to download", tag))
+		}
+	}
+
+	if len(errs) > 0 {
+		err := fmt.Errorf("errors occurred:\n%w", errors.Join(errs...))
+		return fmt.Errorf("failed to download releases of %w", d.repoError(owner, repo, err))
+	}
+	return nil
+}
+
+// releasesInRange lists the published releases of owner/repo selected by r,
+// newest first, paging only as far as the range reaches.
+func (d *Downloader) releasesInRange(ctx context.Context, owner, repo string, r ReleaseRange) ([]*github.RepositoryRelease, error) {
+	var releases []*github.RepositoryRelease
+	p := d.provider(owner, repo)
+	for pageNum := 1; ; {
+		page, next, err := p.ListReleases(ctx, owner, repo, pageNum, 100)
+		if err != nil {
+			return nil, fmt.Errorf("error listing releases: %w", err)
+		}
+		for _, release := range page {
+			if r.SinceTag != "" && strings.TrimPrefix(release.GetTagName(), "v") == strings.TrimPrefix(r.SinceTag, "v") {
+				return releases, nil
+			}
+			if release.GetDraft() || release.GetTagName() == "" {
+				continue
+			}
+			releases = append(releases, release)
+			if r.Last > 0 && len(releases) == r.Last {
+				return releases, nil
+			}
+		}
+		if next == 0 {
+			break
+		}
+		pageNum = next
+	}
+	if r.SinceTag != "" {
+		return nil, fmt.Errorf("no release tagged '%s'", r.SinceTag)
+	}
+	return releases, nil
+}
+
+// downloadReleaseDir downloads the selected assets of release into its
+// version directory and records the results, reporting whether any asset
+// failed.
+func (d *Downloader) downloadReleaseDir(ctx context.Context, key, owner, repo string, release *github.RepositoryRelease) (bool, error) {
+	versionDir, err := d.versionDir(key, owner, repo, release.GetTagName())
+	if err != nil {
+		return false, err
+	}
+	selection := d.selectAssets(ctx, key, owner, repo, release)
+	if selection.empty() {
+		return false, fmt.Errorf("%w matching the filters", ErrNoAssets)
+	}
+	if err := os.MkdirAll(versionDir, 0755); err != nil {
+		return false, fmt.Errorf("failed to create version directory '%s': %v", versionDir, err)
+	}
+	results, failed := d.downloadReleaseAssets(ctx, key, owner, repo, release, selection, versionDir, false)
+	d.record(ctx, results...)
+	return failed, nil
+}
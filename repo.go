@@ -0,0 +1,119 @@
+package ghdownloader
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ArchivedPolicy controls what happens when a repository has been archived
+// upstream.
+type ArchivedPolicy int
+
+const (
+	// ArchivedWarn prints a warning and downloads the latest release as usual.
+	ArchivedWarn ArchivedPolicy = iota
+	// ArchivedSkip prints a warning and skips the repository.
+	ArchivedSkip
+	// ArchivedPin keeps using whatever version is already in destDir and only
+	// downloads when nothing has been downloaded yet.
+	ArchivedPin
+)
+
+// SetArchivedPolicy sets how archived repositories are handled.
+func (d *Downloader) SetArchivedPolicy(policy ArchivedPolicy) {
+	d.archivedPolicy = policy
+}
+
+// SetRepoMovedFunc registers a callback invoked when a requested repository
+// turns out to have been renamed or transferred. Callers can use it to rewrite
+// their own configuration to the new location.
+func (d *Downloader) SetRepoMovedFunc(fn func(from, to string)) {
+	d.repoMovedFunc = fn
+}
+
+// MovedRepos returns the repositories found to have moved, keyed by the
+// requested "owner/repo" with the new "owner/repo" as value.
+func (d *Downloader) MovedRepos() map[string]string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	moved := make(map[string]string, len(d.movedRepos))
+	for from, to := range d.movedRepos {
+		moved[from] = to
+	}
+	return moved
+}
+
+// resolveRepo looks up owner/repo and returns its canonical location and
+// whether it is archived. The API transparently redirects renamed and
+// transferred repositories, so a moved repository shows up as a different
+// full name in the response. Repositories served by other providers are
+// returned as is.
+func (d *Downloader) resolveRepo(ctx context.Context, owner, repo string) (string, string, bool, error) {
+	if !d.isGitHub(owner, repo) {
+		return owner, repo, false, nil
+	}
+	if strings.Contains(owner, "/") {
+		return "", "", false, fmt.Errorf("expected format 'owner/repo'")
+	}
+	info, resp, err := d.client.Repositories.Get(ctx, owner, repo)
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			err = fmt.Errorf("%w: %v", ErrNotFound, err)
+		}
+		return "", "", false, fmt.Errorf("error fetching repository: %w", err)
+	}
+
+	archived := info.GetArchived()
+	newOwner, newRepo := info.GetOwner().GetLogin(), info.GetName()
+	if newOwner == "" || newRepo == "" {
+		return owner, repo, archived, nil
+	}
+	if strings.EqualFold(newOwner, owner) && strings.EqualFold(newRepo, repo) {
+		return owner, repo, archived, nil
+	}
+
+	from, to := owner+"/"+repo, newOwner+"/"+newRepo
+	d.warnf("repository '%s' has moved to '%s'. Following to the new location.", from, to)
+	d.mu.Lock()
+	d.movedRepos[from] = to
+	d.mu.Unlock()
+	if d.repoMovedFunc != nil {
+		d.repoMovedFunc(from, to)
+	}
+	return newOwner, newRepo, archived, nil
+}
+
+// pinnedFiles returns the tag and files of the most recently modified
+// version directory of owner/repo, or nothing if no version has been
+// downloaded yet.
+func (d *Downloader) pinnedFiles(key, owner, repo string) (string, []string, error) {
+	dirs, err := d.versionDirs(key, owner, repo)
+	if err != nil {
+		return "", nil, err
+	}
+	var newest *versionEntry
+	for i := range dirs {
+		if newest == nil || dirs[i].modTime.After(newest.modTime) {
+			newest = &dirs[i]
+		}
+	}
+	if newest == nil {
+		return "", nil, nil
+	}
+
+	entries, err := os.ReadDir(newest.path)
+	if err != nil {
+		return "", nil, err
+	}
+	var files []string
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			files = append(files, filepath.Join(newest.path, entry.Name()))
+		}
+	}
+	return newest.tag, files, nil
+}
@@ -0,0 +1,36 @@
+package ghdownloader
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestPinnedFiles(t *testing.T) {
+	d := newTestDownloader(t)
+	// foo-bar's version is the most recently modified.
+	makeDirs(t, d.destDir, "foo-v1.0.0", "foo-v1.1.0", "foo-bar-v2.0.0")
+	for _, name := range []string{"foo-v1.1.0/foo", "foo-bar-v2.0.0/foo-bar"} {
+		if err := os.WriteFile(filepath.Join(d.destDir, name), []byte("bin"), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tag, files, err := d.pinnedFiles("owner/foo", "owner", "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{filepath.Join(d.destDir, "foo-v1.1.0", "foo")}; tag != "v1.1.0" || !slices.Equal(files, want) {
+		t.Errorf("pinnedFiles = %q, %q, want %q, %q", tag, files, "v1.1.0", want)
+	}
+}
+
+func TestPinnedFilesNothingDownloaded(t *testing.T) {
+	d := newTestDownloader(t)
+	makeDirs(t, d.destDir, "foo-bar-v2.0.0")
+	tag, files, err := d.pinnedFiles("owner/foo", "owner", "foo")
+	if err != nil || tag != "" || files != nil {
+		t.Errorf("pinnedFiles = %q, %q, %v, want nothing", tag, files, err)
+	}
+}
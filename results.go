@@ -0,0 +1,153 @@
+package ghdownloader
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DownloadResult describes the outcome for a single file of a release, or
+// for a whole repository when AssetName is empty (e.g. when its release
+// could not be fetched or the download was skipped up front).
+type DownloadResult struct {
+	Owner     string
+	Repo      string
+	Tag       string
+	AssetName string
+	// AssetID and URL identify the release asset on GitHub, when the file
+	// came from a single asset.
+	AssetID int64
+	URL     string
+	// Path is where the file was saved; for extracted archives there is
+	// one result per extracted executable, all with the archive's AssetName.
+	Path   string
+	Size   int64
+	SHA256 string
+	// Skipped is set when nothing was downloaded because the file or
+	// version was already present.
+	Skipped bool
+	Err     error
+}
+
+// Results returns the outcome of every file and repository handled by the
+// most recent download call, such as DownloadLatestReleases, sorted by
+// repository, asset name and path. Each call starts with no results, so
+// calls don't see files handled by earlier ones.
+func (d *Downloader) Results() []DownloadResult {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]DownloadResult(nil), d.lastResults...)
+}
+
+// Reset forgets what earlier calls found: the results of the last call, the
+// repositories found to have moved and those listed by ExpandOwner, which
+// then fail again when they have no releases or assets. Settings are kept.
+func (d *Downloader) Reset() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.lastResults = nil
+	d.movedRepos = make(map[string]string)
+	d.expandedRepos = make(map[string]bool)
+}
+
+// run collects the results of a single download call, so concurrent calls
+// on the same downloader keep their results apart.
+type run struct {
+	mu      sync.Mutex
+	results []DownloadResult
+}
+
+// runKey is the context key of the current run.
+type runKey struct{}
+
+// withRun calls fn with a context carrying a new run and returns the results
+// recorded during it, sorted and without duplicates. They also become the
+// downloader's last results.
+func (d *Downloader) withRun(ctx context.Context, fn func(context.Context) error) ([]DownloadResult, error) {
+	r := &run{}
+	err := fn(context.WithValue(ctx, runKey{}, r))
+	results := sortResults(r.results)
+	d.mu.Lock()
+	d.lastResults = results
+	d.mu.Unlock()
+	return results, err
+}
+
+// sortResults sorts results by repository, asset name and path, keeping only
+// the last of results for the same file, which repositories downloaded
+// several times in a call produce.
+func sortResults(results []DownloadResult) []DownloadResult {
+	type resultKey struct{ repo, tag, asset, path string }
+	index := make(map[resultKey]int)
+	var unique []DownloadResult
+	for _, r := range results {
+		key := resultKey{strings.ToLower(r.Owner + "/" + r.Repo), r.Tag, r.AssetName, r.Path}
+		if i, ok := index[key]; ok {
+			unique[i] = r
+			continue
+		}
+		index[key] = len(unique)
+		unique = append(unique, r)
+	}
+	sort.SliceStable(unique, func(i, j int) bool {
+		a, b := unique[i], unique[j]
+		if repoA, repoB := strings.ToLower(a.Owner+"/"+a.Repo), strings.ToLower(b.Owner+"/"+b.Repo); repoA != repoB {
+			return repoA < repoB
+		}
+		if a.AssetName != b.AssetName {
+			return a.AssetName < b.AssetName
+		}
+		return a.Path < b.Path
+	})
+	return unique
+}
+
+// resultPaths returns the paths of the files among results, in order and
+// without duplicates.
+func resultPaths(results []DownloadResult) []string {
+	var paths []string
+	seen := make(map[string]bool)
+	for _, r := range results {
+		if r.Err == nil && r.Path != "" && !seen[r.Path] {
+			seen[r.Path] = true
+			paths = append(paths, r.Path)
+		}
+	}
+	return paths
+}
+
+// record fills in the size and digest of saved files, passes them to the
+// hooks and adds them to the results of the run in ctx.
+func (d *Downloader) record(ctx context.Context, results ...DownloadResult) {
+	for i := range results {
+		r := &results[i]
+		if r.Err != nil || r.Path == "" {
+			continue
+		}
+		if info, err := os.Stat(r.Path); err == nil {
+			r.Size = info.Size()
+		}
+		if sum, err := fileSHA256(r.Path); err == nil {
+			r.SHA256 = sum
+		}
+	}
+	d.finishAssets(results)
+
+	if r, ok := ctx.Value(runKey{}).(*run); ok {
+		r.mu.Lock()
+		r.results = append(r.results, results...)
+		r.mu.Unlock()
+	}
+}
+
+// existingResults describes files already present for a release as skipped.
+func existingResults(owner, repo, tag string, files []string) []DownloadResult {
+	results := make([]DownloadResult, len(files))
+	for i, file := range files {
+		results[i] = DownloadResult{Owner: owner, Repo: repo, Tag: tag, AssetName: filepath.Base(file), Path: file, Skipped: true}
+	}
+	return results
+}
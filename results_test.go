@@ -0,0 +1,53 @@
+package ghdownloader
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-github/v68/github"
+)
+
+func TestResetForgetsExpandedRepos(t *testing.T) {
+	d := newTestDownloader(t)
+	p := &fakeProvider{}
+	d.SetProvider(p)
+	p.releases = map[string]*github.RepositoryRelease{
+		"owner/docs": {TagName: github.String("v1.0.0")},
+	}
+	// As if found by ExpandOwner, which lists repositories through the
+	// GitHub API.
+	d.expandedRepos["owner/docs"] = true
+
+	if _, err := d.DownloadLatestReleases([]string{"owner/docs"}); err != nil {
+		t.Fatalf("expanded repository without assets failed: %v", err)
+	}
+	if results := d.Results(); len(results) != 1 || !results[0].Skipped {
+		t.Errorf("Results = %+v, want one skipped result", results)
+	}
+
+	d.Reset()
+	if _, err := d.DownloadLatestReleases([]string{"owner/docs"}); !errors.Is(err, ErrNoAssets) {
+		t.Errorf("err = %v after Reset, want ErrNoAssets", err)
+	}
+}
+
+func TestSortResults(t *testing.T) {
+	results := sortResults([]DownloadResult{
+		{Owner: "o", Repo: "b", AssetName: "x", Path: "/b/x"},
+		{Owner: "o", Repo: "a", AssetName: "y", Path: "/a/y"},
+		{Owner: "o", Repo: "a", AssetName: "x", Path: "/a/x", Size: 1},
+		{Owner: "O", Repo: "A", AssetName: "x", Path: "/a/x", Size: 2},
+	})
+	want := []string{"/a/x", "/a/y", "/b/x"}
+	if len(results) != len(want) {
+		t.Fatalf("sortResults returned %d results, want %d", len(results), len(want))
+	}
+	for i, path := range want {
+		if results[i].Path != path {
+			t.Errorf("result %d is %s, want %s", i, results[i].Path, path)
+		}
+	}
+	if results[0].Size != 2 {
+		t.Errorf("duplicate kept size %d, want the last one", results[0].Size)
+	}
+}
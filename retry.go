@@ -0,0 +1,156 @@
+package ghdownloader
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/go-github/v68/github"
+)
+
+const (
+	// DefaultRetries is how many times a failed request is retried.
+	DefaultRetries = 3
+	// retryBaseDelay is the first backoff delay, doubled on every retry.
+	retryBaseDelay = time.Second
+	// retryMaxDelay caps the exponential backoff.
+	retryMaxDelay = 30 * time.Second
+)
+
+// errInterrupted is returned when the connection carrying a download breaks
+// off midway.
+var errInterrupted = errors.New("transfer interrupted")
+
+// SetRetries sets how many times a request that failed with a network
+// error, a 5xx status or a rate limit is retried (default 3), and how many
+// times a download whose connection broke off is resumed. Transient
+// failures back off exponentially; rate limits wait for Retry-After or the
+// rate limit reset. Zero disables retries.
+func (d *Downloader) SetRetries(retries int) {
+	d.retries = max(retries, 0)
+}
+
+// rateLimitContext makes the GitHub client wait for the primary rate limit
+// to reset instead of failing, if retries are enabled.
+func (d *Downloader) rateLimitContext(ctx context.Context) context.Context {
+	if d.retries == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, github.SleepUntilPrimaryRateLimitResetWhenRateLimited, true)
+}
+
+// retryTransport retries idempotent requests that fail transiently or are
+// rate limited.
+type retryTransport struct {
+	d    *Downloader
+	next http.RoundTripper
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// Requests with a body can't be replayed.
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return t.next.RoundTrip(req)
+	}
+
+	for attempt := 0; ; attempt++ {
+		resp, err := t.next.RoundTrip(req)
+		if attempt >= t.d.retries || req.Context().Err() != nil {
+			return resp, err
+		}
+
+		wait, reason := retryDelay(resp, err, attempt)
+		if wait < 0 {
+			return resp, err
+		}
+		if resp != nil {
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+			resp.Body.Close()
+		}
+		t.d.warnf("%s for '%s'; retrying in %s (%d/%d)",
+			reason, redactURL(req.URL), wait.Round(time.Second), attempt+1, t.d.retries)
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// retryDelay returns how long to wait before retrying a request that got
+// resp or err, and why, or a negative delay if it shouldn't be retried.
+func retryDelay(resp *http.Response, err error, attempt int) (time.Duration, string) {
+	if err != nil {
+		return backoff(attempt), "request failed"
+	}
+
+	switch resp.StatusCode {
+	case http.StatusForbidden, http.StatusTooManyRequests:
+		// Secondary rate limits say how long to wait.
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+			return time.Duration(seconds) * time.Second, "rate limited"
+		}
+		// Primary rate limits say when they reset.
+		if resp.Header.Get("X-RateLimit-Remaining") == "0" {
+			if reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+				return max(time.Until(time.Unix(reset, 0))+time.Second, 0), "rate limit exceeded"
+			}
+		}
+		if resp.StatusCode == http.StatusTooManyRequests {
+			return backoff(attempt), "rate limited"
+		}
+	case http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return backoff(attempt), resp.Status
+	}
+	return -1, ""
+}
+
+// backoff returns the exponential backoff delay for attempt, with jitter so
+// concurrent downloads don't retry in lockstep.
+func backoff(attempt int) time.Duration {
+	delay := retryBaseDelay << attempt
+	if delay > retryMaxDelay || delay <= 0 {
+		delay = retryMaxDelay
+	}
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+// transferRetries counts the restarts of a single transfer.
+type transferRetries struct {
+	stalls        int
+	interruptions int
+}
+
+// resumeTransfer reports whether the transfer of what, such as "'tool.zip'",
+// that failed with err should be resumed, counting the restart in r. Stalled transfers are
+// resumed stallRetries times and interrupted ones as often as failed
+// requests, after the same backoff.
+func (d *Downloader) resumeTransfer(ctx context.Context, r *transferRetries, what string, err error) bool {
+	switch {
+	case ctx.Err() != nil:
+		return false
+	case errors.Is(err, errStalled) && r.stalls < stallRetries:
+		r.stalls++
+		d.warnf("transfer of %s stalled, resuming (%d/%d)", what, r.stalls, stallRetries)
+		return true
+	case errors.Is(err, errInterrupted) && r.interruptions < d.retries:
+		wait := backoff(r.interruptions)
+		r.interruptions++
+		d.warnf("%v; resuming in %s (%d/%d)", err, wait.Round(time.Second), r.interruptions, d.retries)
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return false
+		case <-timer.C:
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,146 @@
+package ghdownloader
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRetryDelay(t *testing.T) {
+	reset := strconv.FormatInt(time.Now().Add(time.Minute).Unix(), 10)
+	tests := []struct {
+		name   string
+		status int
+		header http.Header
+		err    error
+		min    time.Duration
+		max    time.Duration
+	}{
+		{name: "network error", err: errors.New("connection reset"), min: retryBaseDelay / 2, max: retryBaseDelay},
+		{name: "bad gateway", status: http.StatusBadGateway, min: retryBaseDelay / 2, max: retryBaseDelay},
+		{name: "retry after", status: http.StatusForbidden, header: http.Header{"Retry-After": {"5"}}, min: 5 * time.Second, max: 5 * time.Second},
+		{name: "rate limit reset", status: http.StatusForbidden, header: http.Header{"X-Ratelimit-Remaining": {"0"}, "X-Ratelimit-Reset": {reset}}, min: 50 * time.Second, max: 61 * time.Second},
+		{name: "too many requests", status: http.StatusTooManyRequests, min: retryBaseDelay / 2, max: retryBaseDelay},
+		{name: "forbidden", status: http.StatusForbidden, min: -1, max: -1},
+		{name: "not found", status: http.StatusNotFound, min: -1, max: -1},
+	}
+	for _, tt := range tests {
+		var resp *http.Response
+		if tt.err == nil {
+			resp = &http.Response{StatusCode: tt.status, Status: http.StatusText(tt.status), Header: tt.header}
+		}
+		wait, _ := retryDelay(resp, tt.err, 0)
+		if wait < tt.min || wait > tt.max {
+			t.Errorf("%s: waits %s, want %s to %s", tt.name, wait, tt.min, tt.max)
+		}
+	}
+}
+
+func TestBackoffIsCapped(t *testing.T) {
+	for attempt := 0; attempt < 70; attempt++ {
+		if wait := backoff(attempt); wait <= 0 || wait > retryMaxDelay {
+			t.Fatalf("backoff(%d) = %s", attempt, wait)
+		}
+	}
+}
+
+func TestRetryTransport(t *testing.T) {
+	tests := []struct {
+		name     string
+		retries  int
+		statuses []int
+		wantErr  bool
+		requests int
+	}{
+		{name: "rate limited", retries: 3, statuses: []int{http.StatusTooManyRequests, http.StatusTooManyRequests}, requests: 3},
+		{name: "retries exhausted", retries: 1, statuses: []int{http.StatusTooManyRequests, http.StatusTooManyRequests}, wantErr: true, requests: 2},
+		{name: "retries disabled", retries: 0, statuses: []int{http.StatusTooManyRequests}, wantErr: true, requests: 1},
+		{name: "not retried", retries: 3, statuses: []int{http.StatusUnauthorized}, wantErr: true, requests: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := newFakeGitHub(t)
+			g.addRelease("owner/tool", "v1.0.0", map[string]string{"tool": "bin"})
+			failures := tt.statuses
+			g.hook = func(w http.ResponseWriter, r *http.Request) bool {
+				if !strings.HasSuffix(r.URL.Path, "/releases/latest") || len(failures) == 0 {
+					return false
+				}
+				w.Header().Set("Retry-After", "0")
+				w.WriteHeader(failures[0])
+				failures = failures[1:]
+				return true
+			}
+			d := g.downloader(t)
+			d.SetRetries(tt.retries)
+
+			_, err := d.DownloadLatestReleases([]string{"owner/tool"})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("DownloadLatestReleases: %v, want error %v", err, tt.wantErr)
+			}
+			var requests int
+			for _, path := range g.served() {
+				if strings.HasSuffix(path, "/releases/latest") {
+					requests++
+				}
+			}
+			if requests != tt.requests {
+				t.Errorf("sent %d requests for the latest release, want %d", requests, tt.requests)
+			}
+		})
+	}
+}
+
+func TestFetchAssetResumesInterruptedTransfer(t *testing.T) {
+	data := strings.Repeat("0123456789", 1000)
+	g := newFakeGitHub(t)
+	g.addRelease("owner/tool", "v1.0.0", map[string]string{"tool": data})
+	var ranges []string
+	g.hook = func(w http.ResponseWriter, r *http.Request) bool {
+		if !strings.Contains(r.URL.Path, "/releases/assets/") {
+			return false
+		}
+		ranges = append(ranges, r.Header.Get("Range"))
+		if len(ranges) > 1 {
+			return false
+		}
+		// Break the connection halfway through the first transfer.
+		w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+		w.Write([]byte(data[:len(data)/2]))
+		w.(http.Flusher).Flush()
+		panic(http.ErrAbortHandler)
+	}
+	d := g.downloader(t)
+	d.SetRetries(1)
+
+	paths, err := d.DownloadLatestReleases([]string{"owner/tool"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := os.ReadFile(paths[0]); err != nil || string(got) != data {
+		t.Fatalf("downloaded %d bytes, %v, want the whole asset", len(got), err)
+	}
+	if len(ranges) != 2 || ranges[1] != "bytes="+strconv.Itoa(len(data)/2)+"-" {
+		t.Errorf("requested ranges %q, want a resume from the middle", ranges)
+	}
+
+	// Without retries the partial download is kept for the next run.
+	g.addRelease("owner/tool", "v2.0.0", map[string]string{"tool": data})
+	ranges = nil
+	d.SetRetries(0)
+	if _, err := d.DownloadLatestReleases([]string{"owner/tool"}); err != nil {
+		t.Fatal(err)
+	}
+	if results := d.Results(); len(results) != 1 || !errors.Is(results[0].Err, errInterrupted) {
+		t.Fatalf("results = %+v, want the interrupted download to fail without retries", results)
+	}
+	partial := filepath.Join(d.destDir, "tool-v2.0.0", "tool"+partialSuffix)
+	if info, err := os.Stat(partial); err != nil || info.Size() != int64(len(data)/2) {
+		t.Errorf("partial download: %v, %v", info, err)
+	}
+}
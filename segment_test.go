@@ -0,0 +1,53 @@
+package ghdownloader
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFetchAssetSegmented(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789"), 1000)
+	errRejected := errors.New("rejected")
+	tests := []struct {
+		name   string
+		verify func(path string) error
+		want   error
+	}{
+		{"no verification", nil, nil},
+		{"accepted", func(path string) error {
+			if got, _ := os.ReadFile(path); !bytes.Equal(got, data) {
+				return errors.New("wrong content")
+			}
+			return nil
+		}, nil},
+		{"rejected", func(string) error { return errRejected }, errRejected},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := newTestDownloader(t)
+			d.SetSegmentedDownloads(3, 1)
+			p := &fakeProvider{}
+			d.SetProvider(p)
+			asset := p.newFakeAsset("tool", data)
+			filePath := filepath.Join(d.destDir, "tool")
+
+			err := d.fetchAsset(context.Background(), "owner/tool", asset, filePath, tt.verify)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("err = %v, want %v", err, tt.want)
+			}
+			if tt.want != nil {
+				if names := listDir(t, d.destDir); len(names) != 0 {
+					t.Errorf("left %q in the destination directory", names)
+				}
+				return
+			}
+			if got, _ := os.ReadFile(filePath); !bytes.Equal(got, data) {
+				t.Errorf("downloaded %d bytes, want %d", len(got), len(data))
+			}
+		})
+	}
+}
@@ -0,0 +1,335 @@
+package ghdownloader
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/google/go-github/v68/github"
+)
+
+// semverPattern matches a full or partial semantic version such as "1",
+// "v1.4", "1.4.x" or "2.0.0-rc.1+build.5".
+var semverPattern = regexp.MustCompile(`^[vV]?(\d+|[xX*])(?:\.(\d+|[xX*]))?(?:\.(\d+|[xX*]))?(?:-([0-9A-Za-z.-]+))?(?:\+[0-9A-Za-z.-]+)?$`)
+
+// semver is a parsed semantic version.
+type semver struct {
+	major, minor, patch int
+	pre                 []string
+}
+
+// parseSemver parses a full or partial version and returns it with missing or
+// wildcard parts as zero, along with how many leading parts were given.
+func parseSemver(s string) (semver, int, bool) {
+	m := semverPattern.FindStringSubmatch(s)
+	if m == nil {
+		return semver{}, 0, false
+	}
+	var v semver
+	parts := []*int{&v.major, &v.minor, &v.patch}
+	given := 0
+	for i, part := range m[1:4] {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			break
+		}
+		*parts[i] = n
+		given++
+	}
+	if m[4] != "" {
+		// A pre-release only makes sense on a complete version.
+		if given < 3 {
+			return semver{}, 0, false
+		}
+		v.pre = strings.Split(m[4], ".")
+	}
+	return v, given, true
+}
+
+// parseTagVersion parses a release tag as a complete version, allowing a
+// prefix such as "tool-" or "cmd/" before it.
+func parseTagVersion(tag string) (semver, bool) {
+	for i := 0; i < len(tag); i++ {
+		if i > 0 && !strings.ContainsRune("-_/@", rune(tag[i-1])) {
+			continue
+		}
+		if v, given, ok := parseSemver(tag[i:]); ok && given == 3 {
+			return v, true
+		}
+	}
+	return semver{}, false
+}
+
+// compare returns -1, 0 or 1 as v is lower than, equal to or higher than w,
+// following semver precedence (pre-releases sort before their release).
+func (v semver) compare(w semver) int {
+	for _, pair := range [][2]int{{v.major, w.major}, {v.minor, w.minor}, {v.patch, w.patch}} {
+		if pair[0] != pair[1] {
+			return compareInts(pair[0], pair[1])
+		}
+	}
+	switch {
+	case len(v.pre) == 0 && len(w.pre) == 0:
+		return 0
+	case len(v.pre) == 0:
+		return 1
+	case len(w.pre) == 0:
+		return -1
+	}
+	for i := 0; i < len(v.pre) && i < len(w.pre); i++ {
+		a, aErr := strconv.Atoi(v.pre[i])
+		b, bErr := strconv.Atoi(w.pre[i])
+		switch {
+		case aErr == nil && bErr == nil:
+			if a != b {
+				return compareInts(a, b)
+			}
+		case aErr == nil:
+			return -1
+		case bErr == nil:
+			return 1
+		case v.pre[i] != w.pre[i]:
+			return strings.Compare(v.pre[i], w.pre[i])
+		}
+	}
+	return compareInts(len(v.pre), len(w.pre))
+}
+
+func compareInts(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// bump returns the lowest version above every version starting with the
+// first given parts of v, e.g. 1.5.0 for "1.4".
+func (v semver) bump(given int) semver {
+	switch given {
+	case 1:
+		return semver{major: v.major + 1}
+	case 2:
+		return semver{major: v.major, minor: v.minor + 1}
+	}
+	return semver{major: v.major, minor: v.minor, patch: v.patch + 1}
+}
+
+// comparator is a single comparison such as ">= 1.4.0".
+type comparator struct {
+	op string
+	v  semver
+}
+
+func (c comparator) matches(v semver) bool {
+	cmp := v.compare(c.v)
+	switch c.op {
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case "!=":
+		return cmp != 0
+	}
+	return cmp == 0
+}
+
+// versionConstraint is a list of alternatives separated by "||", each of
+// which is a list of comparators that must all hold.
+type versionConstraint [][]comparator
+
+// constraintOps are the operators a constraint term may start with, longest
+// first so that ">=" isn't read as ">".
+var constraintOps = []string{">=", "<=", "!=", ">", "<", "=", "^", "~"}
+
+// isVersionConstraint reports whether a requested tag is a version constraint
+// such as "^1.4", ">=2.0 <3.0" or "1.x" rather than a literal tag.
+func isVersionConstraint(tag string) bool {
+	lower := strings.ToLower(tag)
+	return strings.ContainsAny(tag, "^~<>=*|, ") || lower == "x" || strings.HasSuffix(lower, ".x")
+}
+
+// parseVersionConstraint parses constraints such as "^1.4", "~1.4.2",
+// ">=2.0 <3.0", "1.x || 2.x" or "*". Terms may be separated by spaces or
+// commas.
+func parseVersionConstraint(s string) (versionConstraint, error) {
+	var constraint versionConstraint
+	for _, alternative := range strings.Split(s, "||") {
+		var comparators []comparator
+		fields := strings.Fields(strings.ReplaceAll(alternative, ",", " "))
+		for i := 0; i < len(fields); i++ {
+			term := fields[i]
+			// Allow a space between an operator and its version.
+			if isConstraintOp(term) && i+1 < len(fields) {
+				i++
+				term += fields[i]
+			}
+			terms, err := parseConstraintTerm(term)
+			if err != nil {
+				return nil, err
+			}
+			comparators = append(comparators, terms...)
+		}
+		if len(fields) == 0 {
+			return nil, fmt.Errorf("empty constraint")
+		}
+		constraint = append(constraint, comparators)
+	}
+	return constraint, nil
+}
+
+func isConstraintOp(s string) bool {
+	for _, op := range constraintOps {
+		if s == op {
+			return true
+		}
+	}
+	return false
+}
+
+// parseConstraintTerm expands a single term into comparators on complete
+// versions. Partial versions cover every version they are a prefix of.
+func parseConstraintTerm(term string) ([]comparator, error) {
+	op := ""
+	for _, candidate := range constraintOps {
+		if strings.HasPrefix(term, candidate) {
+			op = candidate
+			break
+		}
+	}
+	v, given, ok := parseSemver(strings.TrimPrefix(term, op))
+	if !ok {
+		return nil, fmt.Errorf("invalid version '%s'", term)
+	}
+	if given == 0 {
+		// "*" and friends match everything.
+		return nil, nil
+	}
+
+	switch op {
+	case "", "=":
+		if given == 3 {
+			return []comparator{{"=", v}}, nil
+		}
+		return []comparator{{">=", v}, {"<", v.bump(given)}}, nil
+	case "^":
+		upper := v.bump(1)
+		switch {
+		case v.major > 0 || given == 1:
+		case v.minor > 0 || given == 2:
+			upper = v.bump(2)
+		default:
+			upper = v.bump(3)
+		}
+		return []comparator{{">=", v}, {"<", upper}}, nil
+	case "~":
+		return []comparator{{">=", v}, {"<", v.bump(min(given, 2))}}, nil
+	case ">":
+		if given < 3 {
+			return []comparator{{">=", v.bump(given)}}, nil
+		}
+	case "<=":
+		if given < 3 {
+			return []comparator{{"<", v.bump(given)}}, nil
+		}
+	}
+	return []comparator{{op, v}}, nil
+}
+
+// matches reports whether v satisfies the constraint. Pre-release versions
+// only match if allowPre is set or an alternative names a pre-release itself.
+func (c versionConstraint) matches(v semver, allowPre bool) bool {
+	for _, comparators := range c {
+		ok := allowPre || len(v.pre) == 0
+		for _, comp := range comparators {
+			ok = ok || len(comp.v.pre) > 0
+		}
+		for _, comp := range comparators {
+			ok = ok && comp.matches(v)
+		}
+		if ok {
+			return true
+		}
+	}
+	return false
+}
+
+// mentionsPrerelease reports whether any term of the constraint names a
+// pre-release version.
+func (c versionConstraint) mentionsPrerelease() bool {
+	for _, comparators := range c {
+		for _, comp := range comparators {
+			if len(comp.v.pre) > 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ResolveVersion returns the tag of the highest release of owner/repo whose
+// version satisfies constraint, e.g. "^1.4", "~1.4.2", ">=2.0 <3.0" or
+// "1.x || 2.x". Tags may carry a prefix such as "v" or "tool-". Drafts are
+// ignored, as are pre-releases unless SetIncludePrereleases is enabled or the
+// constraint names a pre-release.
+func (d *Downloader) ResolveVersion(owner, repo, constraint string) (string, error) {
+	return d.ResolveVersionContext(context.Background(), owner, repo, constraint)
+}
+
+// ResolveVersionContext is like ResolveVersion but honors cancellation of ctx.
+func (d *Downloader) ResolveVersionContext(ctx context.Context, owner, repo, constraint string) (string, error) {
+	release, err := d.resolveRelease(d.rateLimitContext(ctx), owner, repo, constraint)
+	if err != nil {
+		return "", d.redactError(err)
+	}
+	return release.GetTagName(), nil
+}
+
+// resolveRelease returns the highest release of owner/repo matching the
+// version constraint.
+func (d *Downloader) resolveRelease(ctx context.Context, owner, repo, constraint string) (*github.RepositoryRelease, error) {
+	c, err := parseVersionConstraint(constraint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid version constraint '%s': %v", constraint, err)
+	}
+	allowPre := d.includePrereleases || c.mentionsPrerelease()
+
+	var best *github.RepositoryRelease
+	var bestVersion semver
+	p := d.provider(owner, repo)
+	for page := 1; ; {
+		releases, next, err := p.ListReleases(ctx, owner, repo, page, 100)
+		if err != nil {
+			return nil, fmt.Errorf("error listing releases: %w", err)
+		}
+		for _, release := range releases {
+			if release.GetDraft() || release.GetPrerelease() && !allowPre {
+				continue
+			}
+			v, ok := parseTagVersion(release.GetTagName())
+			if !ok || !c.matches(v, allowPre) {
+				continue
+			}
+			if best == nil || v.compare(bestVersion) > 0 {
+				best, bestVersion = release, v
+			}
+		}
+		if next == 0 {
+			break
+		}
+		page = next
+	}
+	if best == nil {
+		return nil, fmt.Errorf("no release matches version constraint '%s'", constraint)
+	}
+	d.debugf("Resolved '%s' for %s/%s to release '%s'", constraint, owner, repo, best.GetTagName())
+	return best, nil
+}
@@ -0,0 +1,182 @@
+package ghdownloader
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-github/v68/github"
+)
+
+func TestParseTagVersion(t *testing.T) {
+	tests := []struct {
+		tag  string
+		want string
+		ok   bool
+	}{
+		{tag: "v1.2.3", want: "1.2.3", ok: true},
+		{tag: "1.2.3", want: "1.2.3", ok: true},
+		{tag: "V2.0.0", want: "2.0.0", ok: true},
+		{tag: "v2.0.0-rc.1", want: "2.0.0-rc.1", ok: true},
+		{tag: "v2.0.0+build.5", want: "2.0.0", ok: true},
+		{tag: "tool-v1.4.0", want: "1.4.0", ok: true},
+		{tag: "cmd/tool/v1.4.0", want: "1.4.0", ok: true},
+		{tag: "tool@1.4.0", want: "1.4.0", ok: true},
+		{tag: "tool_1.4.0", want: "1.4.0", ok: true},
+		{tag: "v1.4", ok: false},
+		{tag: "v1", ok: false},
+		{tag: "nightly", ok: false},
+		{tag: "x1.2.3", ok: false},
+		{tag: "v1.2.3.4", ok: false},
+		{tag: "1.x.0", ok: false},
+	}
+	for _, tt := range tests {
+		v, ok := parseTagVersion(tt.tag)
+		if ok != tt.ok {
+			t.Errorf("parseTagVersion(%q) ok = %v, want %v", tt.tag, ok, tt.ok)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		want, _, _ := parseSemver(tt.want)
+		if v.compare(want) != 0 {
+			t.Errorf("parseTagVersion(%q) = %+v, want %s", tt.tag, v, tt.want)
+		}
+	}
+}
+
+func TestSemverCompare(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.2.3", "1.2.3", 0},
+		{"1.2.3", "1.2.4", -1},
+		{"1.10.0", "1.9.0", 1},
+		{"2.0.0", "10.0.0", -1},
+		{"1.0.0-rc.1", "1.0.0", -1},
+		{"1.0.0-alpha", "1.0.0-alpha.1", -1},
+		{"1.0.0-alpha.1", "1.0.0-alpha.beta", -1},
+		{"1.0.0-beta.2", "1.0.0-beta.11", -1},
+		{"1.0.0-rc.1", "1.0.0-beta.11", 1},
+	}
+	for _, tt := range tests {
+		a, _, _ := parseSemver(tt.a)
+		b, _, _ := parseSemver(tt.b)
+		if got := a.compare(b); got != tt.want {
+			t.Errorf("compare(%s, %s) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+		if got := b.compare(a); got != -tt.want {
+			t.Errorf("compare(%s, %s) = %d, want %d", tt.b, tt.a, got, -tt.want)
+		}
+	}
+}
+
+func TestVersionConstraint(t *testing.T) {
+	tests := []struct {
+		constraint string
+		allowPre   bool
+		match      []string
+		noMatch    []string
+	}{
+		{constraint: "^1.4", match: []string{"1.4.0", "1.9.9"}, noMatch: []string{"1.3.9", "2.0.0", "1.5.0-rc.1"}},
+		{constraint: "^0.4.2", match: []string{"0.4.2", "0.4.9"}, noMatch: []string{"0.5.0", "0.4.1"}},
+		{constraint: "^0.0.3", match: []string{"0.0.3"}, noMatch: []string{"0.0.4"}},
+		{constraint: "~1.4.2", match: []string{"1.4.2", "1.4.9"}, noMatch: []string{"1.5.0", "1.4.1"}},
+		{constraint: "~1", match: []string{"1.0.0", "1.9.0"}, noMatch: []string{"2.0.0"}},
+		{constraint: ">=2.0 <3.0", match: []string{"2.0.0", "2.9.9"}, noMatch: []string{"1.9.9", "3.0.0"}},
+		{constraint: ">= 2.0, < 3.0", match: []string{"2.5.0"}, noMatch: []string{"3.0.0"}},
+		{constraint: ">1.4", match: []string{"1.5.0"}, noMatch: []string{"1.4.9"}},
+		{constraint: "<=1.4", match: []string{"1.4.9"}, noMatch: []string{"1.5.0"}},
+		{constraint: "!=1.4.0", match: []string{"1.4.1"}, noMatch: []string{"1.4.0"}},
+		{constraint: "1.x || 3.x", match: []string{"1.2.0", "3.0.0"}, noMatch: []string{"2.0.0"}},
+		{constraint: "1.4", match: []string{"1.4.0", "1.4.7"}, noMatch: []string{"1.5.0"}},
+		{constraint: "=1.4.0", match: []string{"1.4.0"}, noMatch: []string{"1.4.1"}},
+		{constraint: "*", match: []string{"0.0.1", "9.9.9"}, noMatch: []string{"2.0.0-rc.1"}},
+		{constraint: "*", allowPre: true, match: []string{"2.0.0-rc.1"}},
+		{constraint: ">=2.0.0-rc.1", match: []string{"2.0.0-rc.2", "2.0.0"}, noMatch: []string{"2.0.0-beta.1"}},
+	}
+	for _, tt := range tests {
+		c, err := parseVersionConstraint(tt.constraint)
+		if err != nil {
+			t.Errorf("parseVersionConstraint(%q): %v", tt.constraint, err)
+			continue
+		}
+		for _, s := range tt.match {
+			v, _, _ := parseSemver(s)
+			if !c.matches(v, tt.allowPre) {
+				t.Errorf("%q doesn't match %s", tt.constraint, s)
+			}
+		}
+		for _, s := range tt.noMatch {
+			v, _, _ := parseSemver(s)
+			if c.matches(v, tt.allowPre) {
+				t.Errorf("%q matches %s", tt.constraint, s)
+			}
+		}
+	}
+}
+
+func TestParseVersionConstraintInvalid(t *testing.T) {
+	for _, s := range []string{"", "^", ">=abc", "1.2.3.4", "1.x ||", "^1.4-rc.1"} {
+		if _, err := parseVersionConstraint(s); err == nil {
+			t.Errorf("parseVersionConstraint(%q) succeeded", s)
+		}
+	}
+}
+
+func TestIsVersionConstraint(t *testing.T) {
+	tests := []struct {
+		tag  string
+		want bool
+	}{
+		{"^1.4", true},
+		{"~1.4.2", true},
+		{">=2.0 <3.0", true},
+		{"1.x", true},
+		{"x", true},
+		{"*", true},
+		{"1.x || 2.x", true},
+		{"v1.4.0", false},
+		{"nightly", false},
+		{"tool-v1.4.0", false},
+	}
+	for _, tt := range tests {
+		if got := isVersionConstraint(tt.tag); got != tt.want {
+			t.Errorf("isVersionConstraint(%q) = %v, want %v", tt.tag, got, tt.want)
+		}
+	}
+}
+
+func TestResolveVersion(t *testing.T) {
+	d := newTestDownloader(t)
+	p := &fakeProvider{releases: map[string]*github.RepositoryRelease{}}
+	for _, tag := range []string{"v2.0.0-rc.1", "v1.10.0", "v1.9.0", "v1.4.0", "nightly"} {
+		p.list = append(p.list, &github.RepositoryRelease{TagName: github.String(tag)})
+	}
+	p.list = append(p.list, &github.RepositoryRelease{TagName: github.String("v1.11.0"), Draft: github.Bool(true)})
+	d.SetProvider(p)
+
+	tests := []struct {
+		constraint string
+		want       string
+	}{
+		{"^1.4", "v1.10.0"},
+		{"~1.9", "v1.9.0"},
+		{"<1.9", "v1.4.0"},
+		{">=2.0.0-rc.1", "v2.0.0-rc.1"},
+	}
+	for _, tt := range tests {
+		got, err := d.ResolveVersionContext(context.Background(), "owner", "tool", tt.constraint)
+		if err != nil {
+			t.Errorf("ResolveVersion(%q): %v", tt.constraint, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ResolveVersion(%q) = %s, want %s", tt.constraint, got, tt.want)
+		}
+	}
+	if _, err := d.ResolveVersionContext(context.Background(), "owner", "tool", "^3"); err == nil {
+		t.Error("expected an error when no release matches")
+	}
+}
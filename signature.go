@@ -0,0 +1,251 @@
+package ghdownloader
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/go-github/v68/github"
+)
+
+// DefaultCosignIssuer is the OIDC issuer of keyless cosign signatures made
+// in GitHub Actions workflows.
+const DefaultCosignIssuer = "https://token.actions.githubusercontent.com"
+
+// signatureSuffixes are the extensions of signature material published for
+// an asset, e.g. "tool.tar.gz.sig" and "tool.tar.gz.pem".
+var signatureSuffixes = []string{".sig", ".asc", ".pem", ".cert", ".crt", ".bundle", ".sigstore", ".sigstore.json"}
+
+// SetVerifySignatures enables verifying each downloaded asset with cosign
+// before it is reported as downloaded. If cosignKeyOrIdentity is the path of
+// an existing file or a KMS URI (e.g. "awskms://..."), it is the public key
+// signatures are checked against; otherwise it is the certificate identity
+// of keyless signatures, such as the URL of the release workflow. An
+// identity starting with "^" is a regular expression. An empty value
+// disables cosign verification.
+//
+// Assets are verified with their own "<asset>.sig" (plus "<asset>.pem" for
+// keyless signatures) or "<asset>.bundle", or failing that, through a signed
+// checksum file covering them. Assets without signature material, and ones
+// that fail verification, are deleted and reported as failed.
+func (d *Downloader) SetVerifySignatures(cosignKeyOrIdentity string) {
+	d.cosignKey, d.cosignIdentity = "", ""
+	if _, err := os.Stat(cosignKeyOrIdentity); err == nil || isKMSURI(cosignKeyOrIdentity) {
+		d.cosignKey = cosignKeyOrIdentity
+	} else {
+		d.cosignIdentity = cosignKeyOrIdentity
+	}
+}
+
+// SetCosignIssuer sets the OIDC issuer keyless signatures must come from,
+// DefaultCosignIssuer unless set. An issuer starting with "^" is a regular
+// expression.
+func (d *Downloader) SetCosignIssuer(issuer string) {
+	d.cosignIssuer = issuer
+}
+
+// SetGPGKey enables verifying each downloaded asset against its
+// "<asset>.asc" or "<asset>.sig" GPG signature with the public key in the
+// file at keyPath, in addition to any cosign verification. An empty path
+// disables GPG verification.
+func (d *Downloader) SetGPGKey(keyPath string) {
+	d.gpgKey = keyPath
+}
+
+// isKMSURI reports whether key refers to a key in a KMS rather than a file.
+func isKMSURI(key string) bool {
+	scheme, _, ok := strings.Cut(key, "://")
+	return ok && scheme != "http" && scheme != "https"
+}
+
+// verifiesSignatures reports whether signature verification is enabled.
+func (d *Downloader) verifiesSignatures() bool {
+	return d.cosignKey != "" || d.cosignIdentity != "" || d.gpgKey != ""
+}
+
+// isSignatureAsset reports whether name looks like signature material.
+func isSignatureAsset(name string) bool {
+	return hasAnySuffix(strings.ToLower(name), signatureSuffixes)
+}
+
+// verifySignature checks the file at filePath, downloaded as the asset named
+// name, against the signature material among the release assets.
+func (d *Downloader) verifySignature(ctx context.Context, userRepo, filePath, name string, assets []*github.ReleaseAsset) error {
+	tmpDir, err := os.MkdirTemp("", "ghdownloader-sig-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	byName := make(map[string]*github.ReleaseAsset, len(assets))
+	for _, asset := range assets {
+		byName[asset.GetName()] = asset
+	}
+	fetch := func(asset *github.ReleaseAsset) (string, error) {
+		path := filepath.Join(tmpDir, asset.GetName())
+		if err := d.fetchAsset(ctx, userRepo, asset, path, nil); err != nil {
+			return "", fmt.Errorf("failed to download '%s': %v", asset.GetName(), err)
+		}
+		return path, nil
+	}
+
+	err = d.verifyBlob(ctx, tmpDir, filePath, name, byName, fetch)
+	if err == nil {
+		d.infof("Verified signature of '%s'", name)
+		return nil
+	}
+	if !isMissingSignature(err) {
+		return err
+	}
+
+	// Many projects only sign their checksum file; a signed checksum that
+	// matches the asset vouches for it just as well.
+	for _, asset := range assets {
+		if !isChecksumAsset(asset.GetName()) || isSignatureAsset(asset.GetName()) {
+			continue
+		}
+		sumsPath, fetchErr := fetch(asset)
+		if fetchErr != nil {
+			return fetchErr
+		}
+		data, readErr := os.ReadFile(sumsPath)
+		if readErr != nil {
+			return readErr
+		}
+		expected := parseChecksums(data)[name]
+		if expected == "" {
+			continue
+		}
+		if sumErr := d.verifyBlob(ctx, tmpDir, sumsPath, asset.GetName(), byName, fetch); sumErr != nil {
+			return sumErr
+		}
+		if sumErr := verifyDigest(filePath, expected); sumErr != nil {
+			return sumErr
+		}
+		d.infof("Verified '%s' against signed checksum file '%s'", name, asset.GetName())
+		return nil
+	}
+	return err
+}
+
+// missingSignatureError reports that no signature material was published.
+type missingSignatureError struct {
+	kind, name string
+}
+
+func (e *missingSignatureError) Error() string {
+	return fmt.Sprintf("no %s signature published for '%s'", e.kind, e.name)
+}
+
+func isMissingSignature(err error) bool {
+	_, ok := err.(*missingSignatureError)
+	return ok
+}
+
+// verifyBlob runs every enabled verifier on the file at filePath, published
+// as the asset named name, using the signature assets published next to it.
+func (d *Downloader) verifyBlob(ctx context.Context, tmpDir, filePath, name string, byName map[string]*github.ReleaseAsset, fetch func(*github.ReleaseAsset) (string, error)) error {
+	// find returns the first of the suffixed signature assets published for name.
+	find := func(suffixes ...string) (string, error) {
+		for _, suffix := range suffixes {
+			if asset, ok := byName[name+suffix]; ok {
+				return fetch(asset)
+			}
+		}
+		return "", nil
+	}
+
+	if d.cosignKey != "" || d.cosignIdentity != "" {
+		args := []string{"verify-blob"}
+		if d.cosignKey != "" {
+			args = append(args, "--key", d.cosignKey)
+		} else {
+			issuer := d.cosignIssuer
+			if issuer == "" {
+				issuer = DefaultCosignIssuer
+			}
+			args = append(args, identityFlag("--certificate-identity", d.cosignIdentity)...)
+			args = append(args, identityFlag("--certificate-oidc-issuer", issuer)...)
+		}
+
+		bundle, err := find(".bundle", ".sigstore.json", ".sigstore")
+		if err != nil {
+			return err
+		}
+		if bundle != "" {
+			args = append(args, "--bundle", bundle)
+		} else {
+			sig, err := find(".sig")
+			if err != nil {
+				return err
+			}
+			if sig == "" {
+				return &missingSignatureError{"cosign", name}
+			}
+			args = append(args, "--signature", sig)
+			if d.cosignKey == "" {
+				cert, err := find(".pem", ".cert", ".crt")
+				if err != nil {
+					return err
+				}
+				if cert == "" {
+					return &missingSignatureError{"cosign certificate for", name}
+				}
+				args = append(args, "--certificate", cert)
+			}
+		}
+		if err := runVerifier(ctx, nil, "cosign", append(args, filePath)...); err != nil {
+			return fmt.Errorf("cosign verification of '%s' failed: %v", name, err)
+		}
+	}
+
+	if d.gpgKey != "" {
+		sig, err := find(".asc", ".sig", ".gpg")
+		if err != nil {
+			return err
+		}
+		if sig == "" {
+			return &missingSignatureError{"GPG", name}
+		}
+		// Use a throwaway keyring so only the given key is trusted.
+		home := filepath.Join(tmpDir, "gnupg")
+		if err := os.Mkdir(home, 0700); err != nil && !os.IsExist(err) {
+			return err
+		}
+		env := []string{"GNUPGHOME=" + home}
+		if err := runVerifier(ctx, env, "gpg", "--batch", "--quiet", "--import", d.gpgKey); err != nil {
+			return fmt.Errorf("failed to import GPG key '%s': %v", d.gpgKey, err)
+		}
+		if err := runVerifier(ctx, env, "gpg", "--batch", "--verify", sig, filePath); err != nil {
+			return fmt.Errorf("GPG verification of '%s' failed: %v", name, err)
+		}
+	}
+	return nil
+}
+
+// identityFlag returns flag with value, using the flag's "-regexp" variant
+// when the value starts with "^".
+func identityFlag(flag, value string) []string {
+	if strings.HasPrefix(value, "^") {
+		return []string{flag + "-regexp", value}
+	}
+	return []string{flag, value}
+}
+
+// runVerifier runs a verification tool with extra environment variables,
+// including its output in the error if it fails.
+func runVerifier(ctx context.Context, env []string, name string, args ...string) error {
+	if _, err := exec.LookPath(name); err != nil {
+		return fmt.Errorf("%s is required for signature verification: %v", name, err)
+	}
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Env = append(os.Environ(), env...)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%v: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
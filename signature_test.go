@@ -0,0 +1,164 @@
+package ghdownloader
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// fakeCosign puts a cosign on PATH that accepts signatures and bundles
+// containing "good", and returns the file its arguments are logged to.
+func fakeCosign(t *testing.T) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake cosign is a shell script")
+	}
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "args")
+	script := `#!/bin/sh
+echo "$@" >> '` + logPath + `'
+while [ $# -gt 1 ]; do
+	case "$1" in --signature|--bundle) sig="$2"; shift ;; esac
+	shift
+done
+grep -q good "$sig"
+`
+	if err := os.WriteFile(filepath.Join(dir, "cosign"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	return logPath
+}
+
+// toolResult returns the result of the asset named tool.
+func toolResult(t *testing.T, d *Downloader) DownloadResult {
+	t.Helper()
+	for _, r := range d.Results() {
+		if r.AssetName == "tool" {
+			return r
+		}
+	}
+	t.Fatalf("no result for tool in %+v", d.Results())
+	return DownloadResult{}
+}
+
+func TestVerifySignaturesCosign(t *testing.T) {
+	sum := sha256.Sum256([]byte("bin"))
+	sums := hex.EncodeToString(sum[:]) + "  tool\n"
+	tests := []struct {
+		name    string
+		assets  map[string]string
+		wantErr string
+	}{
+		{name: "keyless signature", assets: map[string]string{"tool": "bin", "tool.sig": "good", "tool.pem": "cert"}},
+		{name: "bundle", assets: map[string]string{"tool": "bin", "tool.bundle": "good"}},
+		{name: "signed checksum file", assets: map[string]string{"tool": "bin", "checksums.txt": sums, "checksums.txt.sig": "good", "checksums.txt.pem": "cert"}},
+		{name: "bad signature", assets: map[string]string{"tool": "bin", "tool.sig": "bad", "tool.pem": "cert"}, wantErr: "cosign verification of 'tool' failed"},
+		{name: "no certificate", assets: map[string]string{"tool": "bin", "tool.sig": "good"}, wantErr: "no cosign certificate for signature"},
+		{name: "unsigned", assets: map[string]string{"tool": "bin"}, wantErr: "no cosign signature"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logPath := fakeCosign(t)
+			g := newFakeGitHub(t)
+			g.addRelease("owner/tool", "v1.0.0", tt.assets)
+			d := g.downloader(t)
+			d.SetVerifySignatures("https://github.com/owner/tool/.github/workflows/release.yml@refs/tags/v1.0.0")
+			if _, err := d.DownloadLatestReleases([]string{"owner/tool"}); err != nil {
+				t.Fatal(err)
+			}
+			r := toolResult(t, d)
+			_, statErr := os.Stat(filepath.Join(d.destDir, "tool-v1.0.0", "tool"))
+			if tt.wantErr == "" {
+				if r.Err != nil || statErr != nil {
+					t.Errorf("verification failed: %v, %v", r.Err, statErr)
+				}
+				args, _ := os.ReadFile(logPath)
+				if !strings.Contains(string(args), "--certificate-oidc-issuer "+DefaultCosignIssuer) {
+					t.Errorf("cosign ran with %q, want the default issuer", args)
+				}
+				return
+			}
+			if r.Err == nil || !strings.Contains(r.Err.Error(), tt.wantErr) {
+				t.Errorf("result error = %v, want one containing %q", r.Err, tt.wantErr)
+			}
+			if statErr == nil {
+				t.Error("an asset failing verification was left in place")
+			}
+		})
+	}
+}
+
+func TestVerifySignaturesCosignKey(t *testing.T) {
+	logPath := fakeCosign(t)
+	keyPath := filepath.Join(t.TempDir(), "cosign.pub")
+	if err := os.WriteFile(keyPath, []byte("key"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	g := newFakeGitHub(t)
+	g.addRelease("owner/tool", "v1.0.0", map[string]string{"tool": "bin", "tool.sig": "good"})
+	d := g.downloader(t)
+	d.SetVerifySignatures(keyPath)
+	if _, err := d.DownloadLatestReleases([]string{"owner/tool"}); err != nil {
+		t.Fatal(err)
+	}
+	if r := toolResult(t, d); r.Err != nil {
+		t.Fatal(r.Err)
+	}
+	args, _ := os.ReadFile(logPath)
+	if !strings.Contains(string(args), "--key "+keyPath) || strings.Contains(string(args), "--certificate") {
+		t.Errorf("cosign ran with %q, want only the key", args)
+	}
+}
+
+func TestVerifySignaturesGPG(t *testing.T) {
+	if _, err := exec.LookPath("gpg"); err != nil {
+		t.Skip("gpg is not installed")
+	}
+	home := t.TempDir()
+	t.Cleanup(func() { exec.Command("gpgconf", "--homedir", home, "--kill", "gpg-agent").Run() })
+	gpg := func(args ...string) []byte {
+		t.Helper()
+		cmd := exec.Command("gpg", append([]string{"--batch", "--quiet", "--homedir", home}, args...)...)
+		out, err := cmd.Output()
+		if err != nil {
+			t.Skipf("gpg %s: %v", args[0], err)
+		}
+		return out
+	}
+	gpg("--passphrase", "", "--quick-gen-key", "ghdownloader test <test@example.com>", "ed25519", "sign", "never")
+	keyPath := filepath.Join(t.TempDir(), "key.asc")
+	if err := os.WriteFile(keyPath, gpg("--armor", "--export"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	binPath := filepath.Join(t.TempDir(), "tool")
+	if err := os.WriteFile(binPath, []byte("bin"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	gpg("--armor", "--detach-sign", binPath)
+	sig, err := os.ReadFile(binPath + ".asc")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		data string
+		ok   bool
+	}{{"bin", true}, {"evil", false}} {
+		g := newFakeGitHub(t)
+		g.addRelease("owner/tool", "v1.0.0", map[string]string{"tool": tt.data, "tool.asc": string(sig)})
+		d := g.downloader(t)
+		d.SetGPGKey(keyPath)
+		if _, err := d.DownloadLatestReleases([]string{"owner/tool"}); err != nil {
+			t.Fatal(err)
+		}
+		if r := toolResult(t, d); (r.Err == nil) != tt.ok {
+			t.Errorf("%q: verification error %v, want success %v", tt.data, r.Err, tt.ok)
+		}
+	}
+}
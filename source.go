@@ -0,0 +1,47 @@
+package ghdownloader
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/google/go-github/v68/github"
+)
+
+// SetDownloadSource also downloads the source tarball GitHub generates for
+// each release, saved as "<repo>-<tag>-src.tar.gz". Releases without any
+// assets are then downloaded instead of reported as errors.
+func (d *Downloader) SetDownloadSource(download bool) {
+	d.downloadSource = download
+}
+
+// sourceName is the file name the source tarball of a release is saved as.
+func sourceName(repo, tag string) string {
+	return fmt.Sprintf("%s-%s-src.tar.gz", repo, tag)
+}
+
+// downloadSourceTarball downloads the source tarball of release into dir,
+// returning its path and whether it was already there.
+func (d *Downloader) downloadSourceTarball(ctx context.Context, userRepo, repo string, release *github.RepositoryRelease, dir string, forceDownload bool) (string, bool, error) {
+	if release.GetTarballURL() == "" {
+		return "", false, fmt.Errorf("release '%s' has no source tarball", release.GetTagName())
+	}
+	name := sourceName(repo, release.GetTagName())
+	filePath := filepath.Join(dir, name)
+	if !forceDownload {
+		if _, err := os.Stat(filePath); err == nil {
+			d.infof("File '%s' already exists. Skipping download.", filePath)
+			return filePath, true, nil
+		}
+	}
+
+	// The tarball endpoint redirects to the archive just like asset
+	// endpoints do, so it downloads the same way.
+	tarball := &github.ReleaseAsset{Name: &name, URL: release.TarballURL}
+	if err := d.fetchAsset(ctx, userRepo, tarball, filePath, nil); err != nil {
+		return "", false, err
+	}
+	d.infof("Downloaded source of '%s' to '%s'", release.GetTagName(), filePath)
+	return filePath, false, nil
+}
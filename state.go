@@ -0,0 +1,73 @@
+package ghdownloader
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// stateFileName is the file in destDir where the downloader records what it
+// has synced between runs.
+const stateFileName = ".ghdownloader-state.json"
+
+// syncState is the persisted state of the download directory.
+type syncState struct {
+	// Mirrors records, per "owner/repo", the newest release mirrored so far.
+	Mirrors map[string]mirrorState `json:"mirrors,omitempty"`
+	// Managed records, per "owner/repo", the packages installed by Sync.
+	Managed map[string]managedState `json:"managed,omitempty"`
+}
+
+// mirrorState records the newest release mirrored for a repository.
+type mirrorState struct {
+	LastReleaseID int64     `json:"last_release_id"`
+	LastTag       string    `json:"last_tag"`
+	LastCreatedAt time.Time `json:"last_created_at"`
+	SyncedAt      time.Time `json:"synced_at"`
+}
+
+// updateState loads the state file, applies fn to it and writes it back.
+// Updates are serialized so concurrent repositories don't clobber each other.
+func (d *Downloader) updateState(fn func(*syncState)) error {
+	d.stateMu.Lock()
+	defer d.stateMu.Unlock()
+
+	state, err := d.loadState()
+	if err != nil {
+		return err
+	}
+	fn(state)
+
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	statePath := filepath.Join(d.destDir, stateFileName)
+	tmpPath := statePath + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write state file: %v", err)
+	}
+	if err := os.Rename(tmpPath, statePath); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to write state file: %v", err)
+	}
+	return nil
+}
+
+// loadState reads the state file, returning an empty state if there is none.
+func (d *Downloader) loadState() (*syncState, error) {
+	state := &syncState{}
+	data, err := os.ReadFile(filepath.Join(d.destDir, stateFileName))
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state file: %v", err)
+	}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("failed to parse state file: %v", err)
+	}
+	return state, nil
+}
@@ -0,0 +1,169 @@
+package ghdownloader
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// SetBlueGreen enables blue/green installs: each release is downloaded into a
+// staging directory, checked with the smoke test (if any), then promoted by
+// atomically pointing the "current" link (see SetUpdateCurrentLink) at it.
+// The previously live version stays in place behind a "previous" link next
+// to it for Rollback.
+func (d *Downloader) SetBlueGreen(enabled bool) {
+	d.blueGreen = enabled
+}
+
+// SetSmokeTest sets a command run against a staged release before it is
+// promoted in blue/green mode. It runs inside the staging directory with
+// GHDOWNLOADER_DIR set to that directory; a non-zero exit aborts promotion.
+func (d *Downloader) SetSmokeTest(command ...string) {
+	d.smokeTest = command
+}
+
+// Rollback swaps the "current" and "previous" links of userRepo ("owner/repo"),
+// making the previously live version current again. The path template and
+// destination subdirectory must be those the links were created with.
+func (d *Downloader) Rollback(userRepo string) error {
+	owner, repo, err := parseUserRepo(userRepo)
+	if err != nil {
+		return fmt.Errorf("invalid user/repo format '%s': %v", userRepo, err)
+	}
+	key := strings.ToLower(userRepo)
+	currentPath, err := d.currentLinkPath(key, owner, repo)
+	if err != nil {
+		return err
+	}
+	previousPath, err := d.previousLinkPath(key, owner, repo)
+	if err != nil {
+		return err
+	}
+
+	current, err := readLinkTarget(currentPath)
+	if err != nil {
+		return fmt.Errorf("no current version of '%s' to roll back: %v", repo, err)
+	}
+	previous, err := readLinkTarget(previousPath)
+	if err != nil {
+		return fmt.Errorf("no previous version of '%s' to roll back to: %v", repo, err)
+	}
+
+	if err := replaceLink(previous, currentPath); err != nil {
+		return fmt.Errorf("failed to update '%s': %v", currentPath, err)
+	}
+	if err := replaceLink(current, previousPath); err != nil {
+		return fmt.Errorf("failed to update '%s': %v", previousPath, err)
+	}
+	d.infof("Rolled back '%s' to '%s'", currentPath, previous)
+	return nil
+}
+
+// previousLinkPath returns the path of the "previous" link of owner/repo,
+// requested as key.
+func (d *Downloader) previousLinkPath(key, owner, repo string) (string, error) {
+	return d.linkPath(key, owner, repo, "previous")
+}
+
+// stagingDir returns the staging directory used for versionDir.
+func stagingDir(versionDir string) string {
+	return versionDir + ".staging"
+}
+
+// liveFiles returns the files of versionDir if it is the live version of
+// owner/repo.
+func (d *Downloader) liveFiles(key, owner, repo, versionDir string) ([]string, bool) {
+	linkPath, err := d.currentLinkPath(key, owner, repo)
+	if err != nil {
+		return nil, false
+	}
+	target, err := readLinkTarget(linkPath)
+	if err != nil || target != filepath.Clean(versionDir) {
+		return nil, false
+	}
+	entries, err := os.ReadDir(versionDir)
+	if err != nil {
+		return nil, false
+	}
+	var files []string
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			files = append(files, filepath.Join(versionDir, entry.Name()))
+		}
+	}
+	return files, true
+}
+
+// promote smoke-tests the staged release and swaps it in as the live version,
+// keeping the version it replaces as "previous".
+func (d *Downloader) promote(ctx context.Context, key, owner, repo, staging, versionDir string) error {
+	if err := d.runSmokeTest(ctx, staging); err != nil {
+		os.RemoveAll(staging)
+		return fmt.Errorf("smoke test failed, not promoting '%s': %v", versionDir, err)
+	}
+
+	// If the version being replaced is live, it becomes "previous" and must
+	// survive; otherwise any stale copy can simply be replaced.
+	currentPath, err := d.currentLinkPath(key, owner, repo)
+	if err != nil {
+		return err
+	}
+	previousPath, err := d.previousLinkPath(key, owner, repo)
+	if err != nil {
+		return err
+	}
+	previous, err := readLinkTarget(currentPath)
+	hasPrevious := err == nil && previous != filepath.Clean(versionDir)
+	if err := os.RemoveAll(versionDir); err != nil {
+		return fmt.Errorf("failed to replace '%s': %v", versionDir, err)
+	}
+	if err := os.Rename(staging, versionDir); err != nil {
+		return fmt.Errorf("failed to move '%s' into place: %v", staging, err)
+	}
+
+	if err := d.updateCurrent(key, owner, repo, versionDir); err != nil {
+		return err
+	}
+	if hasPrevious {
+		if err := replaceLink(previous, previousPath); err != nil {
+			return fmt.Errorf("failed to update '%s': %v", previousPath, err)
+		}
+	}
+	return nil
+}
+
+// runSmokeTest runs the configured smoke test inside dir.
+func (d *Downloader) runSmokeTest(ctx context.Context, dir string) error {
+	if len(d.smokeTest) == 0 {
+		return nil
+	}
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.CommandContext(ctx, d.smokeTest[0], d.smokeTest[1:]...)
+	cmd.Dir = absDir
+	cmd.Env = append(os.Environ(), "GHDOWNLOADER_DIR="+absDir)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%v: %s", err, out)
+	}
+	return nil
+}
+
+// readLinkTarget returns the cleaned path a link in destDir points at,
+// resolving relative targets against the link's directory.
+func readLinkTarget(linkPath string) (string, error) {
+	target, err := os.Readlink(linkPath)
+	if err != nil {
+		return "", err
+	}
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(filepath.Dir(linkPath), target)
+	}
+	return filepath.Clean(target), nil
+}
@@ -0,0 +1,423 @@
+package ghdownloader
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Manifest declares the packages that should be present in the download
+// directory, in the spirit of a Brewfile.
+type Manifest struct {
+	// Dest is the download directory. It is only used by callers that
+	// construct the Downloader from the manifest.
+	Dest     string            `yaml:"dest,omitempty"`
+	Packages []ManifestPackage `yaml:"packages"`
+}
+
+// ManifestPackage declares one repository and how to pick its asset.
+type ManifestPackage struct {
+	// Repo is "owner/repo", or the URL of a GitLab or Gitea repository as
+	// accepted by RegisterRepoURL.
+	Repo string `yaml:"repo"`
+	// Version is the release tag to install, or a version constraint such
+	// as "^1.4", "~1.4.2" or ">=2.0 <3.0" selecting the highest matching
+	// release (see ResolveVersion); empty or "latest" tracks the latest
+	// release.
+	Version string `yaml:"version,omitempty"`
+	// Platforms limits the package to the given "os" or "os/arch" entries
+	// (e.g. "linux", "darwin/arm64"). It applies everywhere if empty.
+	Platforms []string `yaml:"platforms,omitempty"`
+	Match     string   `yaml:"match,omitempty"`
+	// Install is a directory such as "~/bin" to install the package's
+	// executables into, as links to the version directory if Link is set.
+	// As renames the executable; the release must then contain just one.
+	Install    string `yaml:"install,omitempty"`
+	As         string `yaml:"as,omitempty"`
+	Link       bool   `yaml:"link,omitempty"`
+	AssetHints `yaml:",inline"`
+}
+
+// SyncAction describes what Sync did (or would do) with a package.
+type SyncAction string
+
+const (
+	SyncInstalled SyncAction = "installed"
+	SyncUpdated   SyncAction = "updated"
+	SyncUnchanged SyncAction = "unchanged"
+	SyncPruned    SyncAction = "pruned"
+	SyncSkipped   SyncAction = "skipped"
+	SyncFailed    SyncAction = "failed"
+)
+
+// SyncChange reports the outcome of Sync for one repository. From and To are
+// the previously installed and desired tags, and Dir is the version
+// directory the package was installed to.
+type SyncChange struct {
+	Repo   string
+	Action SyncAction
+	From   string
+	To     string
+	Dir    string
+	Err    error
+}
+
+// managedState records a package installed by Sync. Installed maps the
+// executables installed from the package to the files they came from.
+type managedState struct {
+	Tag       string            `json:"tag"`
+	Dir       string            `json:"dir"`
+	Installed map[string]string `json:"installed,omitempty"`
+}
+
+// LoadManifest reads a manifest from a YAML (or JSON) file.
+func LoadManifest(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest '%s': %v", path, err)
+	}
+	var manifest Manifest
+	if err := yaml.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest '%s': %v", path, err)
+	}
+	for _, pkg := range manifest.Packages {
+		if err := validateRepo(pkg.Repo); err != nil {
+			return nil, fmt.Errorf("invalid repo '%s' in manifest '%s': %v", pkg.Repo, path, err)
+		}
+		if (pkg.As != "" || pkg.Link) && pkg.Install == "" {
+			return nil, fmt.Errorf("'as' and 'link' of repo '%s' in manifest '%s' require 'install'", pkg.Repo, path)
+		}
+	}
+	return &manifest, nil
+}
+
+// Sync converges destDir to manifest: packages that are missing or at a
+// different version are downloaded, and packages previously installed by
+// Sync but no longer in the manifest are removed. If apply is false nothing
+// is changed and the returned changes describe the drift instead.
+func (d *Downloader) Sync(manifest *Manifest, apply bool) ([]SyncChange, error) {
+	return d.SyncContext(context.Background(), manifest, apply)
+}
+
+// SyncContext is like Sync but honors cancellation of ctx. A cancelled sync
+// prunes nothing, so an interrupted run never removes installed packages.
+func (d *Downloader) SyncContext(ctx context.Context, manifest *Manifest, apply bool) ([]SyncChange, error) {
+	ctx = d.rateLimitContext(ctx)
+
+	if err := os.MkdirAll(d.destDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create destination directory: %v", err)
+	}
+	state, err := d.loadState()
+	if err != nil {
+		return nil, err
+	}
+
+	// Packages given by URL are synced under their "owner/repo".
+	packages := make([]ManifestPackage, len(manifest.Packages))
+	for i, pkg := range manifest.Packages {
+		if strings.Contains(pkg.Repo, "://") {
+			spec, err := d.RegisterRepoURL(pkg.Repo)
+			if err != nil {
+				return nil, err
+			}
+			var tag string
+			pkg.Repo, tag, _ = strings.Cut(spec, "@")
+			if pkg.Version == "" {
+				pkg.Version = tag
+			}
+		}
+		packages[i] = pkg
+	}
+
+	// Register per-package asset selection before any download starts.
+	changes := make([]SyncChange, len(packages))
+	wanted := make(map[string]bool)
+	for i, pkg := range packages {
+		key := strings.ToLower(pkg.Repo)
+		changes[i] = SyncChange{Repo: pkg.Repo, From: state.Managed[key].Tag}
+		if goos, goarch := d.platform(); !platformMatches(pkg.Platforms, goos, goarch) {
+			changes[i].Action = SyncSkipped
+			continue
+		}
+		wanted[key] = true
+		d.mu.Lock()
+		delete(d.installed, key)
+		d.mu.Unlock()
+
+		if pkg.Match != "" {
+			d.SetRepoMatchFilter(pkg.Repo, pkg.Match)
+		}
+		if pkg.Asset != "" {
+			hints := pkg.AssetHints
+			d.SetRepoAssetHints(pkg.Repo, &hints)
+		}
+		if pkg.Install != "" {
+			d.SetRepoInstallDir(pkg.Repo, expandHome(pkg.Install), pkg.Link)
+			if pkg.As != "" {
+				d.SetInstallName(pkg.Repo, pkg.As)
+			}
+		}
+	}
+
+	var wg sync.WaitGroup
+	sem := newSemaphore(d.maxConcurrentRepos)
+	for i, pkg := range packages {
+		if changes[i].Action == SyncSkipped {
+			continue
+		}
+		wg.Add(1)
+		go func(change *SyncChange, pkg ManifestPackage) {
+			defer wg.Done()
+			sem.acquire()
+			defer sem.release()
+			d.syncPackage(ctx, change, pkg, state.Managed[strings.ToLower(pkg.Repo)], apply)
+		}(&changes[i], pkg)
+	}
+	wg.Wait()
+
+	// Anything Sync installed earlier that is no longer wanted gets pruned.
+	var prunes []SyncChange
+	for key, managed := range state.Managed {
+		if wanted[key] || ctx.Err() != nil {
+			continue
+		}
+		change := SyncChange{Repo: key, Action: SyncPruned, From: managed.Tag}
+		if apply {
+			d.removeInstalled(managed, nil)
+			if err := d.removeManagedDir(managed.Dir); err != nil {
+				change.Action, change.Err = SyncFailed, err
+			} else {
+				d.infof("Removed '%s'", managed.Dir)
+			}
+		}
+		prunes = append(prunes, change)
+	}
+	sort.Slice(prunes, func(i, j int) bool { return prunes[i].Repo < prunes[j].Repo })
+	changes = append(changes, prunes...)
+
+	var repoErrs []*RepoError
+	for _, change := range changes {
+		if change.Err != nil {
+			owner, repo, _ := parseUserRepo(change.Repo)
+			repoErrs = append(repoErrs, d.repoError(owner, repo, change.Err))
+		}
+	}
+	var errs []error
+	if err := joinRepoErrors("sync", repoErrs); err != nil {
+		errs = append(errs, err)
+	}
+
+	if apply {
+		installed := make(map[string]map[string]string)
+		d.mu.Lock()
+		for key, files := range d.installed {
+			installed[key] = files
+		}
+		d.mu.Unlock()
+		err := d.updateState(func(s *syncState) {
+			if s.Managed == nil {
+				s.Managed = make(map[string]managedState)
+			}
+			for _, change := range changes {
+				key := strings.ToLower(change.Repo)
+				switch {
+				case change.Action == SyncPruned:
+					delete(s.Managed, key)
+				case change.Err == nil && change.Dir != "":
+					s.Managed[key] = managedState{Tag: change.To, Dir: change.Dir, Installed: installed[key]}
+				}
+			}
+		})
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return changes, errors.Join(errs...)
+}
+
+// syncPackage brings a single package to its desired version, or only works
+// out the desired version if apply is false.
+func (d *Downloader) syncPackage(ctx context.Context, change *SyncChange, pkg ManifestPackage, managed managedState, apply bool) {
+	owner, repo, _ := parseUserRepo(pkg.Repo)
+	tag := pkg.Version
+	if tag == "latest" {
+		tag = ""
+	}
+
+	if !apply {
+		release, err := d.fetchRelease(ctx, owner, repo, tag)
+		if err != nil {
+			change.Action, change.Err = SyncFailed, d.redactError(err)
+			return
+		}
+		change.To = release.GetTagName()
+		change.Action = syncAction(managed, change.To)
+		return
+	}
+
+	newTag, dir, err := d.downloadRelease(ctx, owner, repo, tag)
+	if err != nil {
+		change.Action, change.Err = SyncFailed, d.redactError(err)
+		return
+	}
+	if dir == "" {
+		// Skipped by a version probe or the archived policy.
+		change.Action = SyncSkipped
+		return
+	}
+	change.To, change.Dir = newTag, dir
+	change.Action = syncAction(managed, newTag)
+
+	// Executables installed earlier that weren't installed again, e.g.
+	// because the install directory changed, go away. Releases that were
+	// already in place may not have been installed again at all.
+	key := strings.ToLower(pkg.Repo)
+	d.mu.Lock()
+	installed, ok := d.installed[key]
+	if !ok && d.installTargetFor(key).dir != "" && managed.Dir == dir {
+		installed = managed.Installed
+		d.installed[key] = installed
+	}
+	d.mu.Unlock()
+	d.removeInstalled(managed, installed)
+
+	// The manifest declares a single version, so the old one goes away.
+	if change.Action == SyncUpdated && managed.Dir != "" && managed.Dir != dir {
+		if err := d.removeManagedDir(managed.Dir); err != nil {
+			d.warnf("%v", err)
+		} else {
+			d.infof("Removed '%s'", managed.Dir)
+		}
+	}
+}
+
+// removeManagedDir removes dir, recorded in the state file as the directory
+// of a package installed by Sync. The state file may have been edited, so
+// directories outside destDir are refused.
+func (d *Downloader) removeManagedDir(dir string) error {
+	if !d.insideDestDir(dir) {
+		return fmt.Errorf("refusing to remove '%s': it is outside the destination directory", dir)
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("failed to remove '%s': %v", dir, err)
+	}
+	return nil
+}
+
+// removeInstalled removes the executables installed from the package
+// described by managed, except those in keep. Files that no longer are
+// links to, or unchanged copies of, files in the package's directory were
+// replaced by someone else and are left alone.
+func (d *Downloader) removeInstalled(managed managedState, keep map[string]string) {
+	for target, src := range managed.Installed {
+		if _, ok := keep[target]; ok {
+			continue
+		}
+		if rel, err := filepath.Rel(managed.Dir, src); err != nil || !filepath.IsLocal(rel) || !d.insideDestDir(src) {
+			continue
+		}
+		if !installedFrom(target, src) {
+			d.warnf("Leaving '%s' in place: it was changed since it was installed", target)
+			continue
+		}
+		if err := os.Remove(target); err != nil {
+			d.warnf("Failed to remove '%s': %v", target, err)
+			continue
+		}
+		d.infof("Removed '%s'", target)
+	}
+}
+
+// installedFrom reports whether target is a link to src or a copy of it.
+func installedFrom(target, src string) bool {
+	info, err := os.Lstat(target)
+	if err != nil {
+		return false
+	}
+	if info.Mode()&os.ModeSymlink != 0 {
+		dest, err := os.Readlink(target)
+		if err != nil {
+			return false
+		}
+		if !filepath.IsAbs(dest) {
+			dest = filepath.Join(filepath.Dir(target), dest)
+		}
+		abs, err := filepath.Abs(src)
+		return err == nil && filepath.Clean(dest) == abs
+	}
+	if !info.Mode().IsRegular() {
+		return false
+	}
+	want, err := os.ReadFile(src)
+	if err != nil {
+		return false
+	}
+	got, err := os.ReadFile(target)
+	return err == nil && bytes.Equal(got, want)
+}
+
+// expandHome expands a leading "~/" in path to the home directory.
+func expandHome(path string) string {
+	if home, err := os.UserHomeDir(); err == nil && strings.HasPrefix(path, "~/") {
+		return filepath.Join(home, path[2:])
+	}
+	return path
+}
+
+// insideDestDir reports whether path lies strictly within destDir, after
+// resolving symbolic links in destDir and in the parent of path.
+func (d *Downloader) insideDestDir(path string) bool {
+	dest, err := filepath.Abs(d.destDir)
+	if err != nil {
+		return false
+	}
+	if real, err := filepath.EvalSymlinks(dest); err == nil {
+		dest = real
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+	if real, err := filepath.EvalSymlinks(filepath.Dir(abs)); err == nil {
+		abs = filepath.Join(real, filepath.Base(abs))
+	}
+	rel, err := filepath.Rel(dest, abs)
+	return err == nil && rel != "." && filepath.IsLocal(rel)
+}
+
+// syncAction classifies the move from the managed state to tag.
+func syncAction(managed managedState, tag string) SyncAction {
+	switch {
+	case managed.Tag == "":
+		return SyncInstalled
+	case managed.Tag != tag:
+		return SyncUpdated
+	}
+	if _, err := os.Stat(managed.Dir); err != nil {
+		return SyncInstalled
+	}
+	return SyncUnchanged
+}
+
+// platformMatches reports whether goos/goarch is one of platforms, given as
+// "os" or "os/arch". An empty list matches every platform.
+func platformMatches(platforms []string, goos, goarch string) bool {
+	if len(platforms) == 0 {
+		return true
+	}
+	for _, p := range platforms {
+		pOS, pArch, _ := strings.Cut(p, "/")
+		if strings.EqualFold(pOS, goos) && (pArch == "" || strings.EqualFold(pArch, goarch)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,225 @@
+package ghdownloader
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-github/v68/github"
+)
+
+func TestSyncPruneStaysInDestDir(t *testing.T) {
+	d := newTestDownloader(t)
+	outside := t.TempDir()
+	inside := filepath.Join(d.destDir, "tool-v1.0.0")
+	makeDirs(t, d.destDir, "tool-v1.0.0")
+	err := d.updateState(func(s *syncState) {
+		s.Managed = map[string]managedState{
+			"owner/tool":   {Tag: "v1.0.0", Dir: inside},
+			"owner/evil":   {Tag: "v1.0.0", Dir: outside},
+			"owner/parent": {Tag: "v1.0.0", Dir: filepath.Join(d.destDir, "..")},
+			"owner/dest":   {Tag: "v1.0.0", Dir: d.destDir},
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	changes, err := d.Sync(&Manifest{}, true)
+	if err == nil {
+		t.Error("expected an error for directories outside the destination directory")
+	}
+	actions := make(map[string]SyncAction)
+	for _, change := range changes {
+		actions[change.Repo] = change.Action
+	}
+	want := map[string]SyncAction{
+		"owner/tool": SyncPruned, "owner/evil": SyncFailed, "owner/parent": SyncFailed, "owner/dest": SyncFailed,
+	}
+	for repo, action := range want {
+		if actions[repo] != action {
+			t.Errorf("%s: %s, want %s", repo, actions[repo], action)
+		}
+	}
+	if _, err := os.Stat(inside); !os.IsNotExist(err) {
+		t.Errorf("%s wasn't removed", inside)
+	}
+	for _, dir := range []string{outside, d.destDir} {
+		if _, err := os.Stat(dir); err != nil {
+			t.Errorf("%s was removed", dir)
+		}
+	}
+}
+
+func TestInsideDestDir(t *testing.T) {
+	d := newTestDownloader(t)
+	link := filepath.Join(d.destDir, "link")
+	if err := os.Symlink(t.TempDir(), link); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{filepath.Join(d.destDir, "tool-v1.0.0"), true},
+		{filepath.Join(d.destDir, "owner", "tool", "v1.0.0"), true},
+		{link, true},
+		{filepath.Join(link, "tool-v1.0.0"), false},
+		{d.destDir, false},
+		{filepath.Join(d.destDir, "..", "other"), false},
+		{"/", false},
+	}
+	for _, tt := range tests {
+		if got := d.insideDestDir(tt.path); got != tt.want {
+			t.Errorf("insideDestDir(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestSyncInstall(t *testing.T) {
+	tests := []struct {
+		name       string
+		link       bool
+		edit       bool
+		wantPruned bool
+	}{
+		{name: "copy", wantPruned: true},
+		{name: "link", link: true, wantPruned: true},
+		{name: "edited copy", edit: true, wantPruned: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := newTestDownloader(t)
+			p := &fakeProvider{}
+			p.releases = map[string]*github.RepositoryRelease{
+				"owner/tool": {
+					TagName: github.String("v1.0.0"),
+					Assets:  []*github.ReleaseAsset{p.newFakeAsset("tool-linux-amd64", []byte("#!/bin/sh\n"))},
+				},
+			}
+			d.SetProvider(p)
+			installDir := t.TempDir()
+			manifest := &Manifest{Packages: []ManifestPackage{
+				{Repo: "owner/tool", Install: installDir, As: "mytool", Link: tt.link},
+			}}
+
+			if _, err := d.Sync(manifest, true); err != nil {
+				t.Fatal(err)
+			}
+			target := filepath.Join(installDir, "mytool")
+			info, err := os.Lstat(target)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if isLink := info.Mode()&os.ModeSymlink != 0; isLink != tt.link {
+				t.Errorf("installed as a link: %v, want %v", isLink, tt.link)
+			}
+			state, err := d.loadState()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, ok := state.Managed["owner/tool"].Installed[target]; !ok {
+				t.Errorf("state doesn't record '%s': %v", target, state.Managed["owner/tool"].Installed)
+			}
+
+			// Syncing again keeps the installed executable.
+			if _, err := d.Sync(manifest, true); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := os.Lstat(target); err != nil {
+				t.Fatalf("installed executable removed by an unchanged sync: %v", err)
+			}
+
+			if tt.edit {
+				if err := os.WriteFile(target, []byte("mine"), 0755); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if _, err := d.Sync(&Manifest{}, true); err != nil {
+				t.Fatal(err)
+			}
+			_, err = os.Lstat(target)
+			if pruned := os.IsNotExist(err); pruned != tt.wantPruned {
+				t.Errorf("installed executable pruned: %v, want %v", pruned, tt.wantPruned)
+			}
+		})
+	}
+}
+
+func TestSyncVersionConstraint(t *testing.T) {
+	d := newTestDownloader(t)
+	p := &fakeProvider{}
+	for _, tag := range []string{"v2.0.0", "v1.10.0", "v1.2.1", "v1.2.0"} {
+		p.list = append(p.list, &github.RepositoryRelease{
+			TagName: github.String(tag),
+			Assets:  []*github.ReleaseAsset{p.newFakeAsset("tool", []byte("bin"))},
+		})
+	}
+	d.SetProvider(p)
+
+	tests := []struct {
+		version    string
+		wantAction SyncAction
+		wantTag    string
+	}{
+		{"^1.2", SyncInstalled, "v1.10.0"},
+		{"~1.2", SyncUpdated, "v1.2.1"},
+		{"1.2.x", SyncUnchanged, "v1.2.1"},
+		{">=2.0 <3.0", SyncUpdated, "v2.0.0"},
+	}
+	for _, tt := range tests {
+		manifest := &Manifest{Packages: []ManifestPackage{{Repo: "owner/tool", Version: tt.version}}}
+		drift, err := d.Sync(manifest, false)
+		if err != nil {
+			t.Fatalf("%s: check: %v", tt.version, err)
+		}
+		if drift[0].To != tt.wantTag {
+			t.Errorf("%s: check resolved %s, want %s", tt.version, drift[0].To, tt.wantTag)
+		}
+
+		changes, err := d.Sync(manifest, true)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.version, err)
+		}
+		if changes[0].Action != tt.wantAction || changes[0].To != tt.wantTag {
+			t.Errorf("%s: %s %s, want %s %s", tt.version, changes[0].Action, changes[0].To, tt.wantAction, tt.wantTag)
+		}
+	}
+	if got := listDir(t, d.destDir); len(got) != 2 || got[1] != "tool-v2.0.0" {
+		t.Errorf("destination holds %v, want only the state file and tool-v2.0.0", got)
+	}
+}
+
+func TestSyncRepoURL(t *testing.T) {
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/repos/owner/tool/releases/latest":
+			fmt.Fprintf(w, `{"id": 1, "tag_name": "v1.0.0", "assets": [{"id": 2, "name": "tool", "browser_download_url": "%s/tool"}]}`, srv.URL)
+		case "/tool":
+			fmt.Fprint(w, "bin")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	d := newTestDownloader(t)
+	manifest := &Manifest{Packages: []ManifestPackage{{Repo: srv.URL + "/owner/tool"}}}
+	changes, err := d.Sync(manifest, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changes[0].Repo != "owner/tool" || changes[0].Action != SyncInstalled || changes[0].To != "v1.0.0" {
+		t.Errorf("change = %+v, want owner/tool installed at v1.0.0", changes[0])
+	}
+	if manifest.Packages[0].Repo != srv.URL+"/owner/tool" {
+		t.Errorf("Sync rewrote the manifest to %s", manifest.Packages[0].Repo)
+	}
+	if data, err := os.ReadFile(filepath.Join(d.destDir, "tool-v1.0.0", "tool")); err != nil || string(data) != "bin" {
+		t.Errorf("installed %q, %v", data, err)
+	}
+}
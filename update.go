@@ -0,0 +1,148 @@
+package ghdownloader
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// UpdateStatus reports whether a repository has a newer release than the
+// version already downloaded.
+type UpdateStatus struct {
+	// Repo is the repository as requested, without any "@tag".
+	Repo string
+	// Current is the tag of the version on disk, or "" if there is none.
+	Current string
+	// Latest is the tag of the latest release (within the version
+	// constraint, if one was requested).
+	Latest          string
+	UpdateAvailable bool
+	Err             error
+}
+
+// CheckForUpdates compares the latest release of each of userRepos with the
+// version already downloaded: the target of its "<repo>-current" link, the
+// newest version directory, or the version recorded by Sync or
+// MirrorReleases. Repos may carry a version constraint ("owner/repo@^1.4")
+// to only consider releases within it. Nothing is downloaded.
+func (d *Downloader) CheckForUpdates(userRepos []string) ([]UpdateStatus, error) {
+	return d.CheckForUpdatesContext(context.Background(), userRepos)
+}
+
+// CheckForUpdatesContext is like CheckForUpdates but honors cancellation of
+// ctx.
+func (d *Downloader) CheckForUpdatesContext(ctx context.Context, userRepos []string) ([]UpdateStatus, error) {
+	ctx = d.rateLimitContext(ctx)
+
+	statuses := make([]UpdateStatus, len(userRepos))
+	errs := make([]*RepoError, len(userRepos))
+	sem := newSemaphore(d.maxConcurrentRepos)
+	var wg sync.WaitGroup
+	for i, userRepo := range userRepos {
+		owner, repo, tag, err := parseRepoSpec(userRepo)
+		if err != nil {
+			return nil, fmt.Errorf("invalid user/repo format '%s': %v", userRepo, err)
+		}
+
+		wg.Add(1)
+		go func(i int, owner, repo, tag string) {
+			defer wg.Done()
+			sem.acquire()
+			defer sem.release()
+			status := &statuses[i]
+			status.Repo = owner + "/" + repo
+			if err := d.checkForUpdate(ctx, status, owner, repo, tag); err != nil {
+				errs[i] = d.repoError(owner, repo, err)
+				status.Err = errs[i].Err
+			}
+		}(i, owner, repo, tag)
+	}
+	wg.Wait()
+
+	var failed []*RepoError
+	for _, err := range errs {
+		if err != nil {
+			failed = append(failed, err)
+		}
+	}
+	return statuses, joinRepoErrors("check", failed)
+}
+
+// checkForUpdate fills in status for owner/repo, considering the releases
+// matching tag.
+func (d *Downloader) checkForUpdate(ctx context.Context, status *UpdateStatus, owner, repo, tag string) error {
+	key := strings.ToLower(owner + "/" + repo)
+	owner, repo, _, err := d.resolveRepo(ctx, owner, repo)
+	if err != nil {
+		return err
+	}
+	release, err := d.fetchRelease(ctx, owner, repo, tag)
+	if err != nil {
+		return err
+	}
+	current, err := d.downloadedTag(key, owner, repo)
+	if err != nil {
+		return err
+	}
+
+	status.Current, status.Latest = current, release.GetTagName()
+	status.UpdateAvailable = isNewerTag(status.Latest, status.Current)
+	return nil
+}
+
+// isNewerTag reports whether latest is newer than current. Tags that aren't
+// versions are only compared for equality.
+func isNewerTag(latest, current string) bool {
+	if current == "" {
+		return true
+	}
+	latestVersion, ok1 := parseTagVersion(latest)
+	currentVersion, ok2 := parseTagVersion(current)
+	if ok1 && ok2 {
+		return latestVersion.compare(currentVersion) > 0
+	}
+	return strings.TrimPrefix(latest, "v") != strings.TrimPrefix(current, "v")
+}
+
+// downloadedTag returns the tag of the version of owner/repo on disk: the
+// target of its "current" link, the highest version directory, or the tag
+// recorded in the state file. It returns "" if nothing was downloaded yet.
+func (d *Downloader) downloadedTag(key, owner, repo string) (string, error) {
+	if _, tagPattern, ok := d.versionPattern(key, owner, repo); ok {
+		if linkPath, err := d.currentLinkPath(key, owner, repo); err == nil {
+			target, err := readLinkTarget(linkPath)
+			if m := tagPattern.FindStringSubmatch(target); err == nil && m != nil {
+				return m[1], nil
+			}
+		}
+	}
+
+	dirs, err := d.versionDirs(key, owner, repo)
+	if err != nil {
+		return "", err
+	}
+	var newest string
+	var newestVersion semver
+	for _, dir := range dirs {
+		v, ok := parseTagVersion(dir.tag)
+		if !ok {
+			continue
+		}
+		if newest == "" || v.compare(newestVersion) > 0 {
+			newest, newestVersion = dir.tag, v
+		}
+	}
+	if newest != "" {
+		return newest, nil
+	}
+
+	state, err := d.loadState()
+	if err != nil {
+		return "", err
+	}
+	if managed, ok := state.Managed[key]; ok {
+		return managed.Tag, nil
+	}
+	return state.Mirrors[key].LastTag, nil
+}
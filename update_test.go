@@ -0,0 +1,88 @@
+package ghdownloader
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestDownloadedTag(t *testing.T) {
+	tests := []struct {
+		name    string
+		dirs    []string
+		current string
+		repo    string
+		want    string
+	}{
+		{
+			name: "highest version",
+			dirs: []string{"foo-v1.2.0", "foo-v1.10.0", "foo-v1.9.0"},
+			repo: "foo",
+			want: "v1.10.0",
+		},
+		{
+			name:    "current link",
+			dirs:    []string{"foo-v1.2.0", "foo-v1.10.0"},
+			current: "foo-v1.2.0",
+			repo:    "foo",
+			want:    "v1.2.0",
+		},
+		{
+			name: "repository sharing a name prefix",
+			dirs: []string{"foo-v1.0.0", "foo-bar-v2.0.0"},
+			repo: "foo",
+			want: "v1.0.0",
+		},
+		{
+			name: "only a repository sharing a name prefix",
+			dirs: []string{"foo-bar-v2.0.0"},
+			repo: "foo",
+			want: "",
+		},
+		{
+			name: "longer repository name",
+			dirs: []string{"foo-v3.0.0", "foo-bar-v2.0.0"},
+			repo: "foo-bar",
+			want: "v2.0.0",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := newTestDownloader(t)
+			makeDirs(t, d.destDir, tt.dirs...)
+			key := "owner/" + tt.repo
+			if tt.current != "" {
+				if err := d.updateCurrent(key, "owner", tt.repo, filepath.Join(d.destDir, tt.current)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			got, err := d.downloadedTag(key, "owner", tt.repo)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("downloadedTag = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsNewerTag(t *testing.T) {
+	tests := []struct {
+		latest, current string
+		want            bool
+	}{
+		{"v1.2.0", "", true},
+		{"v1.10.0", "v1.9.0", true},
+		{"v1.9.0", "v1.10.0", false},
+		{"v1.2.0", "1.2.0", false},
+		{"v1.2.0", "v1.2.0-rc.1", true},
+		{"nightly-2", "nightly-1", true},
+		{"nightly", "v1.2.0", true},
+		{"nightly", "nightly", false},
+	}
+	for _, tt := range tests {
+		if got := isNewerTag(tt.latest, tt.current); got != tt.want {
+			t.Errorf("isNewerTag(%q, %q) = %v, want %v", tt.latest, tt.current, got, tt.want)
+		}
+	}
+}
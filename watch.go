@@ -0,0 +1,81 @@
+package ghdownloader
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// WatchEvent reports a new release downloaded by Watch.
+type WatchEvent struct {
+	// Repo is the repository as requested, without any "@tag".
+	Repo string
+	// Previous is the tag that was downloaded before, or "" if there was
+	// none.
+	Previous string
+	Tag      string
+	// Results holds one result per downloaded asset.
+	Results []DownloadResult
+	// Err is set if the release failed to download; it is tried again on
+	// the next poll.
+	Err error
+}
+
+// Watch checks userRepos for new releases every interval until ctx is
+// cancelled, downloading each one that is newer than the version on disk
+// (see CheckForUpdates) and passing the outcome to callback, which may be
+// nil. The first check happens immediately, so repos that were never
+// downloaded are fetched right away. Failed checks are logged and retried on
+// the next poll. Watch returns ctx.Err() once ctx is cancelled; the
+// downloader must not be used concurrently while it runs.
+func (d *Downloader) Watch(ctx context.Context, userRepos []string, interval time.Duration, callback func(WatchEvent)) error {
+	if interval <= 0 {
+		return fmt.Errorf("invalid watch interval %s: must be positive", interval)
+	}
+	for _, userRepo := range userRepos {
+		if _, _, _, err := parseRepoSpec(userRepo); err != nil {
+			return fmt.Errorf("invalid user/repo format '%s': %v", userRepo, err)
+		}
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		d.pollForUpdates(ctx, userRepos, callback)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// pollForUpdates downloads the new releases of userRepos once.
+func (d *Downloader) pollForUpdates(ctx context.Context, userRepos []string, callback func(WatchEvent)) {
+	statuses, _ := d.CheckForUpdatesContext(ctx, userRepos)
+	for i, status := range statuses {
+		if ctx.Err() != nil {
+			return
+		}
+		if status.Err != nil {
+			d.warnf("Failed to check %s for updates: %v", status.Repo, status.Err)
+			continue
+		}
+		if !status.UpdateAvailable {
+			d.debugf("%s is up to date at '%s'", status.Repo, status.Current)
+			continue
+		}
+
+		d.infof("New release of %s: '%s'", status.Repo, status.Latest)
+		event := WatchEvent{Repo: status.Repo, Previous: status.Current, Tag: status.Latest}
+		event.Results, event.Err = d.withRun(ctx, func(ctx context.Context) error {
+			return d.downloadLatestReleases(ctx, []string{userRepos[i]})
+		})
+		if event.Err != nil {
+			d.warnf("Failed to download '%s' of %s: %v", status.Latest, status.Repo, event.Err)
+		}
+		if callback != nil {
+			callback(event)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package ghdownloader
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestWatch(t *testing.T) {
+	g := newFakeGitHub(t)
+	g.addRelease("owner/tool", "v1.0.0", map[string]string{"tool": "v1"})
+	d := g.downloader(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events := make(chan WatchEvent)
+	done := make(chan error)
+	go func() {
+		done <- d.Watch(ctx, []string{"owner/tool"}, 10*time.Millisecond, func(event WatchEvent) {
+			events <- event
+		})
+	}()
+
+	next := func() WatchEvent {
+		t.Helper()
+		select {
+		case event := <-events:
+			if event.Err != nil {
+				t.Fatalf("%s: %v", event.Tag, event.Err)
+			}
+			return event
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for a release")
+		}
+		return WatchEvent{}
+	}
+
+	// The first check downloads the latest release right away.
+	event := next()
+	if event.Repo != "owner/tool" || event.Previous != "" || event.Tag != "v1.0.0" || len(event.Results) != 1 {
+		t.Errorf("first event = %+v, want v1.0.0", event)
+	}
+
+	g.addRelease("owner/tool", "v2.0.0", map[string]string{"tool": "v2"})
+	event = next()
+	if event.Previous != "v1.0.0" || event.Tag != "v2.0.0" {
+		t.Errorf("second event = %+v, want v1.0.0 to v2.0.0", event)
+	}
+	if data, err := os.ReadFile(event.Results[0].Path); err != nil || string(data) != "v2" {
+		t.Errorf("downloaded %q, %v", data, err)
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Watch returned %v, want context.Canceled", err)
+		}
+	case event := <-events:
+		t.Errorf("unexpected event %+v", event)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Watch didn't return after cancellation")
+	}
+}
+
+func TestWatchRejectsInvalidArguments(t *testing.T) {
+	d := newTestDownloader(t)
+	if err := d.Watch(context.Background(), []string{"owner/tool"}, 0, nil); err == nil {
+		t.Error("expected an error for a zero interval")
+	}
+	if err := d.Watch(context.Background(), []string{"tool"}, time.Second, nil); err == nil {
+		t.Error("expected an error for a repository without an owner")
+	}
+}
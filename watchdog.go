@@ -0,0 +1,74 @@
+package ghdownloader
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync/atomic"
+	"time"
+)
+
+// stallRetries is how many times a stalled transfer is restarted before
+// giving up on the asset.
+const stallRetries = 3
+
+// errStalled is returned when a transfer is aborted by the stall watchdog.
+var errStalled = errors.New("transfer stalled")
+
+// SetStallWatchdog aborts and retries asset transfers whose throughput stays
+// below minBytesPerSec for a whole window (e.g. 10KB/s for 60s). A zero
+// window disables the watchdog.
+func (d *Downloader) SetStallWatchdog(minBytesPerSec int64, window time.Duration) {
+	d.stallMinRate = minBytesPerSec
+	d.stallWindow = window
+}
+
+// countingReader counts the bytes read through it.
+type countingReader struct {
+	r io.Reader
+	n atomic.Int64
+	// err is the last error reading from r other than io.EOF, telling a
+	// broken connection apart from a failure to write the data.
+	err error
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n.Add(int64(n))
+	if err != nil && err != io.EOF {
+		c.err = err
+	}
+	return n, err
+}
+
+// watchStall cancels the transfer whenever fewer than minBytesPerSec*window
+// bytes are read through counter within a window. It returns a function that
+// stops the watchdog and reports whether it fired.
+func watchStall(cancel context.CancelFunc, counter *countingReader, minBytesPerSec int64, window time.Duration) func() bool {
+	var stalled atomic.Bool
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(window)
+		defer ticker.Stop()
+		minBytes := int64(float64(minBytesPerSec) * window.Seconds())
+		last := counter.n.Load()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				current := counter.n.Load()
+				if current-last < minBytes {
+					stalled.Store(true)
+					cancel()
+					return
+				}
+				last = current
+			}
+		}
+	}()
+	return func() bool {
+		close(done)
+		return stalled.Load()
+	}
+}